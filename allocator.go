@@ -0,0 +1,53 @@
+package api2go
+
+import "reflect"
+
+// objectAllocator creates the objects of a resource and the pointers that are needed to unmarshal
+// into them, so that the handlers do not depend on the type of the objects
+type objectAllocator interface {
+	// create passes a pointer to a new object to init and returns the object, which is a struct
+	// or a pointer depending on how the resource was registered
+	create(init func(ptr interface{}) error) (interface{}, error)
+	// edit passes a pointer to a copy of obj to change and returns the changed object in the
+	// same form as obj, pointers are changed in place
+	edit(obj interface{}, change func(ptr interface{}) error) (interface{}, error)
+}
+
+// reflectAllocator allocates the objects of resources that were registered with AddResource
+type reflectAllocator struct {
+	resourceType reflect.Type
+}
+
+func (a reflectAllocator) create(init func(ptr interface{}) error) (interface{}, error) {
+	// Ok this is weird again, but reflect.New produces a pointer, so we need the pure type without pointer,
+	// otherwise we would have a pointer pointer type that we don't want.
+	resourceType := a.resourceType
+	if resourceType.Kind() == reflect.Ptr {
+		resourceType = resourceType.Elem()
+	}
+	newObj := reflect.New(resourceType).Interface()
+
+	if err := init(newObj); err != nil {
+		return nil, err
+	}
+
+	if a.resourceType.Kind() == reflect.Struct {
+		// we have to dereference the pointer if user wants to use non pointer values
+		return reflect.ValueOf(newObj).Elem().Interface(), nil
+	}
+
+	return newObj, nil
+}
+
+func (a reflectAllocator) edit(obj interface{}, change func(ptr interface{}) error) (interface{}, error) {
+	if reflect.TypeOf(obj).Kind() != reflect.Struct {
+		return obj, change(obj)
+	}
+
+	ptr := getPointerToStruct(obj)
+	if err := change(ptr); err != nil {
+		return nil, err
+	}
+
+	return reflect.ValueOf(ptr).Elem().Interface(), nil
+}
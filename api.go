@@ -1,9 +1,11 @@
 package api2go
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -179,7 +181,9 @@ type resource struct {
 	resourceType reflect.Type
 	source       interface{}
 	name         string
+	references   []jsonapi.Reference
 	api          *API
+	objects      objectAllocator
 }
 
 // middlewareChain executes the middleeware chain setup
@@ -194,9 +198,9 @@ func (api *API) allocateDefaultContext() APIContexter {
 	return &APIContext{}
 }
 
-func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interface{}) *resource {
+func (api *API) addResource(prototype interface{}, source interface{}, objects objectAllocator) *resource {
 	resourceType := reflect.TypeOf(prototype)
-	if resourceType.Kind() != reflect.Struct && resourceType.Kind() != reflect.Ptr {
+	if resourceType == nil || (resourceType.Kind() != reflect.Struct && resourceType.Kind() != reflect.Ptr) {
 		panic("pass an empty resource struct or a struct pointer to AddResource!")
 	}
 
@@ -211,6 +215,17 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 		name = resourceType.Elem().Name()
 	}
 
+	// structs with jsonapi struct tags are used through an adapter that implements the interfaces
+	if err := jsonapi.ValidateTags(prototype); err != nil {
+		panic(err)
+	}
+
+	prototype = jsonapi.Adapt(prototype)
+	ptrPrototype = jsonapi.Adapt(ptrPrototype)
+	if _, ok := prototype.(jsonapi.MarshalIdentifier); !ok {
+		panic("the resource passed to AddResource must implement jsonapi.MarshalIdentifier or use jsonapi struct tags!")
+	}
+
 	// check if EntityNamer interface is implemented and use that as name
 	entityName, ok := prototype.(jsonapi.EntityNamer)
 	if ok {
@@ -219,11 +234,20 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 		name = jsonapi.Jsonify(jsonapi.Pluralize(name))
 	}
 
+	if objects == nil {
+		objects = reflectAllocator{resourceType: resourceType}
+	}
+
 	res := resource{
 		resourceType: resourceType,
 		name:         name,
 		source:       source,
 		api:          api,
+		objects:      objects,
+	}
+
+	if casted, ok := prototype.(jsonapi.MarshalReferences); ok {
+		res.references = casted.GetReferences()
 	}
 
 	requestInfo := func(r *http.Request, api *API) *information {
@@ -253,7 +277,7 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 		api.contextPool.Put(c)
 	})
 
-	api.router.Handle("GET", baseURL, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	api.handle("GET", baseURL, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 		info := requestInfo(r, api)
 		c := api.contextPool.Get().(APIContexter)
 		c.Reset()
@@ -276,7 +300,7 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 			api.contextPool.Put(c)
 		})
 
-		api.router.Handle("GET", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		api.handle("GET", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 			info := requestInfo(r, api)
 			c := api.contextPool.Get().(APIContexter)
 			c.Reset()
@@ -294,7 +318,7 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 	if ok {
 		relations := casted.GetReferences()
 		for _, relation := range relations {
-			api.router.Handle("GET", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
+			api.handle("GET", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
 				return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 					info := requestInfo(r, api)
 					c := api.contextPool.Get().(APIContexter)
@@ -308,7 +332,7 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 				}
 			}(relation))
 
-			api.router.Handle("GET", baseURL+"/:id/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
+			api.handle("GET", baseURL+"/:id/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
 				return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 					info := requestInfo(r, api)
 					c := api.contextPool.Get().(APIContexter)
@@ -322,12 +346,13 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 				}
 			}(relation))
 
-			api.router.Handle("PATCH", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
+			api.handle("PATCH", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
 				return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+					info := requestInfo(r, api)
 					c := api.contextPool.Get().(APIContexter)
 					c.Reset()
 					api.middlewareChain(c, w, r)
-					err := res.handleReplaceRelation(c, w, r, params, relation)
+					err := res.handleReplaceRelation(c, w, r, params, *info, relation)
 					api.contextPool.Put(c)
 					if err != nil {
 						api.handleError(err, w, r)
@@ -335,39 +360,52 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 				}
 			}(relation))
 
-			if _, ok := ptrPrototype.(jsonapi.EditToManyRelations); ok && relation.Name == jsonapi.Pluralize(relation.Name) {
+			if relation.Name == jsonapi.Pluralize(relation.Name) {
 				// generate additional routes to manipulate to-many relationships
-				api.router.Handle("POST", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-					return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-						c := api.contextPool.Get().(APIContexter)
-						c.Reset()
-						api.middlewareChain(c, w, r)
-						err := res.handleAddToManyRelation(c, w, r, params, relation)
-						api.contextPool.Put(c)
-						if err != nil {
-							api.handleError(err, w, r)
+				_, editable := ptrPrototype.(jsonapi.EditToManyRelations)
+				if _, withType := ptrPrototype.(jsonapi.EditToManyRelationsWithType); withType {
+					editable = true
+				}
+				_, adder := source.(RelationshipAdder)
+				_, remover := source.(RelationshipRemover)
+
+				if editable || adder {
+					api.handle("POST", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
+						return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+							info := requestInfo(r, api)
+							c := api.contextPool.Get().(APIContexter)
+							c.Reset()
+							api.middlewareChain(c, w, r)
+							err := res.handleAddToManyRelation(c, w, r, params, *info, relation)
+							api.contextPool.Put(c)
+							if err != nil {
+								api.handleError(err, w, r)
+							}
 						}
-					}
-				}(relation))
-
-				api.router.Handle("DELETE", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-					return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-						c := api.contextPool.Get().(APIContexter)
-						c.Reset()
-						api.middlewareChain(c, w, r)
-						err := res.handleDeleteToManyRelation(c, w, r, params, relation)
-						api.contextPool.Put(c)
-						if err != nil {
-							api.handleError(err, w, r)
+					}(relation))
+				}
+
+				if editable || remover {
+					api.handle("DELETE", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
+						return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+							info := requestInfo(r, api)
+							c := api.contextPool.Get().(APIContexter)
+							c.Reset()
+							api.middlewareChain(c, w, r)
+							err := res.handleDeleteToManyRelation(c, w, r, params, *info, relation)
+							api.contextPool.Put(c)
+							if err != nil {
+								api.handleError(err, w, r)
+							}
 						}
-					}
-				}(relation))
+					}(relation))
+				}
 			}
 		}
 	}
 
 	if _, ok := source.(ResourceCreator); ok {
-		api.router.Handle("POST", baseURL, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		api.handle("POST", baseURL, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 			info := requestInfo(r, api)
 			c := api.contextPool.Get().(APIContexter)
 			c.Reset()
@@ -381,7 +419,7 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 	}
 
 	if _, ok := source.(ResourceDeleter); ok {
-		api.router.Handle("DELETE", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		api.handle("DELETE", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 			c := api.contextPool.Get().(APIContexter)
 			c.Reset()
 			api.middlewareChain(c, w, r)
@@ -394,7 +432,7 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 	}
 
 	if _, ok := source.(ResourceUpdater); ok {
-		api.router.Handle("PATCH", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		api.handle("PATCH", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
 			info := requestInfo(r, api)
 			c := api.contextPool.Get().(APIContexter)
 			c.Reset()
@@ -407,6 +445,20 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 		})
 	}
 
+	if _, ok := source.(ResourceReplacer); ok {
+		api.handle("PUT", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+			info := requestInfo(r, api)
+			c := api.contextPool.Get().(APIContexter)
+			c.Reset()
+			api.middlewareChain(c, w, r)
+			err := res.handleReplace(c, w, r, params, *info)
+			api.contextPool.Put(c)
+			if err != nil {
+				api.handleError(err, w, r)
+			}
+		})
+	}
+
 	api.resources = append(api.resources, res)
 
 	return &res
@@ -424,6 +476,10 @@ func getAllowedMethods(source interface{}, collection bool) []string {
 		result = append(result, http.MethodPatch)
 	}
 
+	if _, ok := source.(ResourceReplacer); ok && !collection {
+		result = append(result, http.MethodPut)
+	}
+
 	if _, ok := source.(ResourceDeleter); ok && !collection {
 		result = append(result, http.MethodDelete)
 	}
@@ -450,19 +506,21 @@ func buildRequest(c APIContexter, r *http.Request) Request {
 	req.QueryParams = params
 	req.Header = r.Header
 	req.Context = c
+	negotiated := getNegotiatedParams(r)
+	req.extensions = negotiated.extensions
+	req.profiles = negotiated.profiles
 	return req
 }
 
 func (res *resource) marshalResponse(resp interface{}, w http.ResponseWriter, status int, r *http.Request) error {
-	filtered, err := filterSparseFields(resp, r)
-	if err != nil {
+	if err := res.api.checkDocumentFields(resp, r); err != nil {
 		return err
 	}
-	result, err := json.Marshal(filtered)
+	result, err := res.api.jsonCodec().Marshal(resp)
 	if err != nil {
 		return err
 	}
-	writeResult(w, result, status, res.api.ContentType)
+	writeResult(w, result, status, res.api.contentType(r))
 	return nil
 }
 
@@ -481,7 +539,18 @@ func (res *resource) handleIndex(c APIContexter, w http.ResponseWriter, r *http.
 				return err
 			}
 
-			return res.respondWithPagination(response, info, http.StatusOK, paginationLinks, w, r)
+			return res.respondWithPagination(c, response, info, http.StatusOK, paginationLinks, w, r)
+		}
+	}
+
+	if source, ok := res.source.(FindMany); ok {
+		if ids := filteredIDs(r.URL.Query()); len(ids) > 0 {
+			response, err := source.FindMany(ids, buildRequest(c, r))
+			if err != nil {
+				return err
+			}
+
+			return res.respondWith(c, response, info, http.StatusOK, w, r)
 		}
 	}
 
@@ -495,7 +564,42 @@ func (res *resource) handleIndex(c APIContexter, w http.ResponseWriter, r *http.
 		return err
 	}
 
-	return res.respondWith(response, info, http.StatusOK, w, r)
+	return res.respondWith(c, response, info, http.StatusOK, w, r)
+}
+
+// filteredIDs returns the ids of filter[id] if it is the only filter and no sort order is requested,
+// because FindMany can neither apply other filters nor sort the result
+func filteredIDs(query url.Values) []string {
+	if isSortedOrFiltered(query, "filter[id]") {
+		return nil
+	}
+
+	ids := []string{}
+	for _, value := range query["filter[id]"] {
+		for _, id := range strings.Split(value, ",") {
+			if id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return appendMissingStrings(nil, ids)
+}
+
+// isSortedOrFiltered checks if the query contains a sort or a filter other than the allowed ones,
+// these queries must be answered with FindAll because FindMany only looks up ids
+func isSortedOrFiltered(query url.Values, allowed ...string) bool {
+	if _, ok := query["sort"]; ok {
+		return true
+	}
+
+	for key := range query {
+		if strings.HasPrefix(key, "filter[") && !containsString(allowed, key) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (res *resource) handleRead(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
@@ -513,7 +617,7 @@ func (res *resource) handleRead(c APIContexter, w http.ResponseWriter, r *http.R
 		return err
 	}
 
-	return res.respondWith(response, info, http.StatusOK, w, r)
+	return res.respondWith(c, response, info, http.StatusOK, w, r)
 }
 
 func (res *resource) handleReadRelation(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information, relation jsonapi.Reference) error {
@@ -525,35 +629,187 @@ func (res *resource) handleReadRelation(c APIContexter, w http.ResponseWriter, r
 
 	id := params["id"]
 
+	if paginated, ok := res.source.(PaginatedFindRelationship); ok && isToManyReference(relation) {
+		pagination := newPaginationQueryParams(r)
+		if pagination.isValid() {
+			return res.handleReadPaginatedRelation(c, paginated, pagination, w, r, id, info, relation)
+		}
+	}
+
 	obj, err := source.FindOne(id, buildRequest(c, r))
 	if err != nil {
 		return err
 	}
 
-	document, err := jsonapi.MarshalToStruct(obj.Result(), info)
+	return res.respondWithRelationship(obj, info, relation, w, r)
+}
+
+// respondWithRelationship writes the linkage, links and meta of a relationship of the resource in obj
+func (res *resource) respondWithRelationship(obj Responder, info information, relation jsonapi.Reference, w http.ResponseWriter, r *http.Request) error {
+	document, err := jsonapi.MarshalToStructWithOptions(obj.Result(), info, res.api.options)
 	if err != nil {
 		return err
 	}
 
+	if document.Data == nil || document.Data.DataObject == nil {
+		return fmt.Errorf("Expected one object of resource %s", res.name)
+	}
+
 	rel, ok := document.Data.DataObject.Relationships[relation.Name]
 	if !ok {
 		return NewHTTPError(nil, fmt.Sprintf("There is no relation with the name %s", relation.Name), http.StatusNotFound)
 	}
 
+	// the meta of the relationship takes precedence over the response metadata
 	meta := obj.Metadata()
 	if len(meta) > 0 {
-		rel.Meta = meta
+		merged := make(map[string]interface{}, len(meta)+len(rel.Meta))
+		for k, v := range meta {
+			merged[k] = v
+		}
+
+		for k, v := range rel.Meta {
+			merged[k] = v
+		}
+
+		rel.Meta = merged
 	}
 
 	return res.marshalResponse(rel, w, http.StatusOK, r)
 }
 
+// resolves the id of a to-one relationship with the referenced ids of the parent and
+// returns the result of FindOne of the referenced resource or null for empty relationships
+func (res *resource) handleLinkedToOne(c APIContexter, linkedResource resource, w http.ResponseWriter, r *http.Request, id string, linked jsonapi.Reference, info information) error {
+	parentSource, ok := res.source.(ResourceGetter)
+	if !ok {
+		return fmt.Errorf("Resource %s does not implement the ResourceGetter interface", res.name)
+	}
+
+	parent, err := parentSource.FindOne(id, buildRequest(c, r))
+	if err != nil {
+		return err
+	}
+
+	relationer, ok := jsonapi.Adapt(parent.Result()).(jsonapi.MarshalLinkedRelations)
+	if !ok {
+		return fmt.Errorf("Resource %s does not implement the MarshalLinkedRelations interface", res.name)
+	}
+
+	for _, referenceID := range relationer.GetReferencedIDs() {
+		if referenceID.Name != linked.Name {
+			continue
+		}
+
+		source, ok := linkedResource.source.(ResourceGetter)
+		if !ok {
+			return NewHTTPError(nil, "Resource does not implement the ResourceGetter interface", http.StatusNotFound)
+		}
+
+		obj, err := source.FindOne(referenceID.ID, buildRequest(c, r))
+		if err != nil {
+			return err
+		}
+
+		return res.respondWith(c, obj, info, http.StatusOK, w, r)
+	}
+
+	return res.respondWith(c, &Response{}, info, http.StatusOK, w, r)
+}
+
+// resolves the ids of a to-many relationship with the referenced ids of the parent and returns the
+// result of FindMany of the referenced resource
+func (res *resource) handleLinkedToMany(c APIContexter, linkedResource resource, w http.ResponseWriter, r *http.Request, id string, linked jsonapi.Reference, info information) error {
+	parentSource, ok := res.source.(ResourceGetter)
+	if !ok {
+		return fmt.Errorf("Resource %s does not implement the ResourceGetter interface", res.name)
+	}
+
+	parent, err := parentSource.FindOne(id, buildRequest(c, r))
+	if err != nil {
+		return err
+	}
+
+	relationer, ok := jsonapi.Adapt(parent.Result()).(jsonapi.MarshalLinkedRelations)
+	if !ok {
+		return fmt.Errorf("Resource %s does not implement the MarshalLinkedRelations interface", res.name)
+	}
+
+	ids := []string{}
+	for _, referenceID := range relationer.GetReferencedIDs() {
+		if referenceID.Name == linked.Name {
+			ids = append(ids, referenceID.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return res.respondWith(c, &Response{Res: []interface{}{}}, info, http.StatusOK, w, r)
+	}
+
+	obj, err := linkedResource.source.(FindMany).FindMany(appendMissingStrings(nil, ids), buildRequest(c, r))
+	if err != nil {
+		return err
+	}
+
+	return res.respondWith(c, obj, info, http.StatusOK, w, r)
+}
+
+// returns the requested page of the IDs of a to-many relationship along with pagination links
+func (res *resource) handleReadPaginatedRelation(c APIContexter, source PaginatedFindRelationship, pagination paginationQueryParams, w http.ResponseWriter, r *http.Request, id string, info information, relation jsonapi.Reference) error {
+	count, referenceIDs, err := source.PaginatedFindRelationship(id, relation.Name, buildRequest(c, r))
+	if err != nil {
+		return err
+	}
+
+	links, err := pagination.getLinks(r, count, info)
+	if err != nil {
+		return err
+	}
+
+	selfURL := fmt.Sprintf("%s%s", info.GetBaseURL(), r.URL.Path)
+	links["self"] = jsonapi.Link{Href: selfURL}
+	links["related"] = jsonapi.Link{Href: strings.TrimSuffix(selfURL, "/relationships/"+relation.Name) + "/" + relation.Name}
+
+	data := []jsonapi.RelationshipData{}
+	for _, referenceID := range referenceIDs {
+		referenceType := referenceID.Type
+		if referenceType == "" {
+			referenceType = relation.Type
+		}
+
+		data = append(data, jsonapi.RelationshipData{Type: referenceType, ID: referenceID.ID})
+	}
+
+	rel := jsonapi.Relationship{
+		Links: links,
+		Data:  &jsonapi.RelationshipDataContainer{DataArray: data},
+	}
+
+	return res.marshalResponse(rel, w, http.StatusOK, r)
+}
+
+// isToManyReference checks if a reference is a to-many relationship, guessing it from the
+// pluralization of the name for the default relationship type
+func isToManyReference(relation jsonapi.Reference) bool {
+	if relation.Relationship == jsonapi.DefaultRelationship {
+		return jsonapi.Pluralize(relation.Name) == relation.Name
+	}
+
+	return relation.Relationship == jsonapi.ToManyRelationship
+}
+
 // try to find the referenced resource and call the findAll Method with referencing resource id as param
 func (res *resource) handleLinked(c APIContexter, api *API, w http.ResponseWriter, r *http.Request, params map[string]string, linked jsonapi.Reference, info information) error {
 	id := params["id"]
 	for _, resource := range api.resources {
 		if resource.name == linked.Type {
+			if !isToManyReference(linked) {
+				return res.handleLinkedToOne(c, resource, w, r, id, linked, info)
+			}
+
 			request := buildRequest(c, r)
+			request.Relation = &Relation{ParentType: res.name, ParentID: id, Name: linked.Name}
+			// deprecated, only kept for resources that do not use request.Relation yet
 			request.QueryParams[res.name+"ID"] = []string{id}
 			request.QueryParams[res.name+"Name"] = []string{linked.Name}
 
@@ -572,10 +828,14 @@ func (res *resource) handleLinked(c APIContexter, api *API, w http.ResponseWrite
 						return err
 					}
 
-					return res.respondWithPagination(response, info, http.StatusOK, paginationLinks, w, r)
+					return res.respondWithPagination(c, response, info, http.StatusOK, paginationLinks, w, r)
 				}
 			}
 
+			if _, ok := resource.source.(FindMany); ok && !isSortedOrFiltered(r.URL.Query()) {
+				return res.handleLinkedToMany(c, resource, w, r, id, linked, info)
+			}
+
 			source, ok := resource.source.(FindAll)
 			if !ok {
 				return NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
@@ -585,7 +845,7 @@ func (res *resource) handleLinked(c APIContexter, api *API, w http.ResponseWrite
 			if err != nil {
 				return err
 			}
-			return res.respondWith(obj, info, http.StatusOK, w, r)
+			return res.respondWith(c, obj, info, http.StatusOK, w, r)
 		}
 	}
 
@@ -609,38 +869,22 @@ func (res *resource) handleCreate(c APIContexter, w http.ResponseWriter, r *http
 		return err
 	}
 
-	// Ok this is weird again, but reflect.New produces a pointer, so we need the pure type without pointer,
-	// otherwise we would have a pointer pointer type that we don't want.
-	resourceType := res.resourceType
-	if resourceType.Kind() == reflect.Ptr {
-		resourceType = resourceType.Elem()
-	}
-	newObj := reflect.New(resourceType).Interface()
-
-	// Call InitializeObject if available to allow implementers change the object
-	// before calling Unmarshal.
-	if initSource, ok := source.(ObjectInitializer); ok {
-		initSource.InitializeObject(newObj)
+	// the references are checked first, so that unknown relationships are not rejected by the model
+	if err := res.checkDocumentReferences(c, r, ctx); err != nil {
+		return err
 	}
 
-	err = jsonapi.Unmarshal(ctx, newObj)
+	newObj, err := res.unmarshalNewObject(ctx)
 	if err != nil {
-		return NewHTTPError(nil, err.Error(), http.StatusNotAcceptable)
+		return err
 	}
 
-	var response Responder
-
-	if res.resourceType.Kind() == reflect.Struct {
-		// we have to dereference the pointer if user wants to use non pointer values
-		response, err = source.Create(reflect.ValueOf(newObj).Elem().Interface(), buildRequest(c, r))
-	} else {
-		response, err = source.Create(newObj, buildRequest(c, r))
-	}
+	response, err := source.Create(newObj, buildRequest(c, r))
 	if err != nil {
 		return err
 	}
 
-	result, ok := response.Result().(jsonapi.MarshalIdentifier)
+	result, ok := jsonapi.Adapt(response.Result()).(jsonapi.MarshalIdentifier)
 
 	if !ok {
 		return fmt.Errorf("Expected one newly created object by resource %s", res.name)
@@ -655,7 +899,7 @@ func (res *resource) handleCreate(c APIContexter, w http.ResponseWriter, r *http
 	// handle 200 status codes
 	switch response.StatusCode() {
 	case http.StatusCreated:
-		return res.respondWith(response, info, http.StatusCreated, w, r)
+		return res.respondWith(c, response, info, http.StatusCreated, w, r)
 	case http.StatusNoContent:
 		w.WriteHeader(response.StatusCode())
 		return nil
@@ -685,26 +929,100 @@ func (res *resource) handleUpdate(c APIContexter, w http.ResponseWriter, r *http
 		return err
 	}
 
-	// we have to make the Result to a pointer to unmarshal into it
-	updatingObj := reflect.ValueOf(obj.Result())
-	if updatingObj.Kind() == reflect.Struct {
-		updatingObjPtr := reflect.New(reflect.TypeOf(obj.Result()))
-		updatingObjPtr.Elem().Set(updatingObj)
-		err = jsonapi.Unmarshal(ctx, updatingObjPtr.Interface())
-		updatingObj = updatingObjPtr.Elem()
-	} else {
-		err = jsonapi.Unmarshal(ctx, updatingObj.Interface())
+	if err := res.checkDocumentReferences(c, r, ctx); err != nil {
+		return err
 	}
+
+	// we need a pointer to the Result to unmarshal into it
+	updatingObj, err := res.objects.edit(obj.Result(), func(ptr interface{}) error {
+		return jsonapi.UnmarshalWithOptions(ctx, ptr, res.api.options)
+	})
 	if err != nil {
 		return NewHTTPError(nil, err.Error(), http.StatusNotAcceptable)
 	}
 
-	response, err := source.Update(updatingObj.Interface(), buildRequest(c, r))
+	response, err := source.Update(updatingObj, buildRequest(c, r))
+
+	if err != nil {
+		return err
+	}
+
+	return res.respondWithUpdated(c, response, source, id, "Update", info, w, r)
+}
+
+func (res *resource) handleReplace(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
+	source, ok := res.source.(ResourceReplacer)
+
+	if !ok {
+		return fmt.Errorf("Resource %s does not implement the ResourceReplacer interface", res.name)
+	}
+
+	id := params["id"]
+
+	ctx, err := unmarshalRequest(r)
+	if err != nil {
+		return err
+	}
+
+	if err := res.checkDocumentReferences(c, r, ctx); err != nil {
+		return err
+	}
+
+	// the replacement starts from scratch instead of the stored object
+	replacingObj, err := res.unmarshalNewObject(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the url identifies the replaced object, the document may only repeat its id
+	replacingObj, err = res.objects.edit(replacingObj, func(ptr interface{}) error {
+		if identifier, ok := jsonapi.Adapt(ptr).(jsonapi.MarshalIdentifier); ok {
+			if documentID := identifier.GetID(); documentID != "" && documentID != id {
+				message := fmt.Sprintf("The id %s of the document does not match the id %s of the url", documentID, id)
+				return NewHTTPError(nil, message, http.StatusConflict)
+			}
+		}
+
+		identifier, ok := jsonapi.Adapt(ptr).(jsonapi.UnmarshalIdentifier)
+		if !ok {
+			return fmt.Errorf("Resource %s does not implement the UnmarshalIdentifier interface", res.name)
+		}
+
+		return identifier.SetID(id)
+	})
+	if err != nil {
+		return err
+	}
 
+	response, err := source.Replace(replacingObj, buildRequest(c, r))
 	if err != nil {
 		return err
 	}
 
+	return res.respondWithUpdated(c, response, source, id, "Replace", info, w, r)
+}
+
+// unmarshalNewObject unmarshals the request body into a new instance of the resource type.
+// The returned object is a pointer or a struct, depending on how the resource was registered.
+func (res *resource) unmarshalNewObject(body []byte) (interface{}, error) {
+	newObj, err := res.objects.create(func(ptr interface{}) error {
+		// Call InitializeObject if available to allow implementers change the object
+		// before calling Unmarshal.
+		if initSource, ok := res.source.(ObjectInitializer); ok {
+			initSource.InitializeObject(ptr)
+		}
+
+		return jsonapi.UnmarshalWithOptions(body, ptr, res.api.options)
+	})
+	if err != nil {
+		return nil, NewHTTPError(nil, err.Error(), http.StatusNotAcceptable)
+	}
+
+	return newObj, nil
+}
+
+// respondWithUpdated handles the status codes of Update and Replace in the same way
+func (res *resource) respondWithUpdated(c APIContexter, response Responder, source ResourceGetter, id, method string, info information, w http.ResponseWriter, r *http.Request) error {
 	switch response.StatusCode() {
 	case http.StatusOK:
 		updated := response.Result()
@@ -721,7 +1039,7 @@ func (res *resource) handleUpdate(c APIContexter, w http.ResponseWriter, r *http
 			response = internalResponse
 		}
 
-		return res.respondWith(response, info, http.StatusOK, w, r)
+		return res.respondWith(c, response, info, http.StatusOK, w, r)
 	case http.StatusAccepted:
 		w.WriteHeader(http.StatusAccepted)
 		return nil
@@ -729,221 +1047,331 @@ func (res *resource) handleUpdate(c APIContexter, w http.ResponseWriter, r *http
 		w.WriteHeader(http.StatusNoContent)
 		return nil
 	default:
-		return fmt.Errorf("invalid status code %d from resource %s for method Update", response.StatusCode(), res.name)
+		return fmt.Errorf("invalid status code %d from resource %s for method %s", response.StatusCode(), res.name, method)
 	}
 }
 
-func (res *resource) handleReplaceRelation(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, relation jsonapi.Reference) error {
+func (res *resource) handleReplaceRelation(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information, relation jsonapi.Reference) error {
+	id := params["id"]
+
+	if replacer, ok := res.source.(RelationshipReplacer); ok {
+		data, err := res.unmarshalRelationshipData(r)
+		if err != nil {
+			return err
+		}
+
+		ids, err := getReplacedRelationshipIDs(data, relation)
+		if err != nil {
+			return err
+		}
+
+		if err := res.checkRelationshipReferences(c, r, relation, data, true); err != nil {
+			return err
+		}
+
+		response, err := replacer.ReplaceRelationship(id, relation.Name, ids, buildRequest(c, r))
+		if err != nil {
+			return err
+		}
+
+		return res.respondWithUpdatedRelationship(c, response, id, "ReplaceRelationship", info, relation, w, r)
+	}
+
 	source, ok := res.source.(ResourceUpdater)
 
 	if !ok {
 		return fmt.Errorf("Resource %s does not implement the ResourceUpdater interface", res.name)
 	}
 
-	var (
-		err     error
-		editObj interface{}
-	)
-
-	id := params["id"]
-
 	response, err := source.FindOne(id, buildRequest(c, r))
 	if err != nil {
 		return err
 	}
 
-	body, err := unmarshalRequest(r)
+	data, err := res.unmarshalRelationshipData(r)
 	if err != nil {
 		return err
 	}
 
-	inc := map[string]interface{}{}
-	err = json.Unmarshal(body, &inc)
+	editObj, err := res.objects.edit(response.Result(), func(ptr interface{}) error {
+		return processRelationshipsData(data, relation.Name, jsonapi.Adapt(ptr))
+	})
 	if err != nil {
 		return err
 	}
-	data, ok := inc["data"]
-	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
-	}
 
-	resType := reflect.TypeOf(response.Result()).Kind()
-	if resType == reflect.Struct {
-		editObj = getPointerToStruct(response.Result())
-	} else {
-		editObj = response.Result()
+	if err := res.checkRelationshipReferences(c, r, relation, data, true); err != nil {
+		return err
 	}
 
-	err = processRelationshipsData(data, relation.Name, editObj)
+	updated, err := source.Update(editObj, buildRequest(c, r))
 	if err != nil {
 		return err
 	}
 
-	if resType == reflect.Struct {
-		_, err = source.Update(reflect.ValueOf(editObj).Elem().Interface(), buildRequest(c, r))
-	} else {
-		_, err = source.Update(editObj, buildRequest(c, r))
-	}
-
-	w.WriteHeader(http.StatusNoContent)
-	return err
+	return res.respondWithUpdatedRelationship(c, updated, id, "Update", info, relation, w, r)
 }
 
-func (res *resource) handleAddToManyRelation(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, relation jsonapi.Reference) error {
+func (res *resource) handleAddToManyRelation(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information, relation jsonapi.Reference) error {
+	id := params["id"]
+
+	if adder, ok := res.source.(RelationshipAdder); ok {
+		newIDs, err := res.unmarshalToManyRelationshipIDs(c, r, relation, true)
+		if err != nil {
+			return err
+		}
+
+		response, err := adder.AddToRelationship(id, relation.Name, newIDs, buildRequest(c, r))
+		if err != nil {
+			return err
+		}
+
+		return res.respondWithUpdatedRelationship(c, response, id, "AddToRelationship", info, relation, w, r)
+	}
+
 	source, ok := res.source.(ResourceUpdater)
 
 	if !ok {
 		return fmt.Errorf("Resource %s does not implement the ResourceUpdater interface", res.name)
 	}
 
-	var (
-		err     error
-		editObj interface{}
-	)
-
-	id := params["id"]
-
 	response, err := source.FindOne(id, buildRequest(c, r))
 	if err != nil {
 		return err
 	}
 
-	body, err := unmarshalRequest(r)
+	newIDs, err := res.unmarshalToManyRelationshipIDs(c, r, relation, true)
 	if err != nil {
 		return err
 	}
-	inc := map[string]interface{}{}
-	err = json.Unmarshal(body, &inc)
+
+	editObj, err := res.objects.edit(response.Result(), func(ptr interface{}) error {
+		return editToManyIDs(jsonapi.Adapt(ptr), relation.Name, newIDs, true)
+	})
 	if err != nil {
 		return err
 	}
 
-	data, ok := inc["data"]
-	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
+	updated, err := source.Update(editObj, buildRequest(c, r))
+	if err != nil {
+		return err
 	}
 
-	newRels, ok := data.([]interface{})
-	if !ok {
-		return fmt.Errorf("Data must be an array with \"id\" and \"type\" field to add new to-many relationships")
-	}
+	return res.respondWithUpdatedRelationship(c, updated, id, "Update", info, relation, w, r)
+}
 
-	newIDs := []string{}
+func (res *resource) handleDeleteToManyRelation(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information, relation jsonapi.Reference) error {
+	id := params["id"]
 
-	for _, newRel := range newRels {
-		casted, ok := newRel.(map[string]interface{})
-		if !ok {
-			return errors.New("entry in data object invalid")
+	if remover, ok := res.source.(RelationshipRemover); ok {
+		obsoleteIDs, err := res.unmarshalToManyRelationshipIDs(c, r, relation, false)
+		if err != nil {
+			return err
 		}
-		newID, ok := casted["id"].(string)
-		if !ok {
-			return errors.New("no id field found inside data object")
+
+		response, err := remover.RemoveFromRelationship(id, relation.Name, obsoleteIDs, buildRequest(c, r))
+		if err != nil {
+			return err
 		}
 
-		newIDs = append(newIDs, newID)
+		return res.respondWithUpdatedRelationship(c, response, id, "RemoveFromRelationship", info, relation, w, r)
 	}
 
-	resType := reflect.TypeOf(response.Result()).Kind()
-	if resType == reflect.Struct {
-		editObj = getPointerToStruct(response.Result())
-	} else {
-		editObj = response.Result()
-	}
+	source, ok := res.source.(ResourceUpdater)
 
-	targetObj, ok := editObj.(jsonapi.EditToManyRelations)
 	if !ok {
-		return errors.New("target struct must implement jsonapi.EditToManyRelations")
+		return fmt.Errorf("Resource %s does not implement the ResourceUpdater interface", res.name)
 	}
-	targetObj.AddToManyIDs(relation.Name, newIDs)
 
-	if resType == reflect.Struct {
-		_, err = source.Update(reflect.ValueOf(targetObj).Elem().Interface(), buildRequest(c, r))
-	} else {
-		_, err = source.Update(targetObj, buildRequest(c, r))
+	response, err := source.FindOne(id, buildRequest(c, r))
+	if err != nil {
+		return err
+	}
+
+	obsoleteIDs, err := res.unmarshalToManyRelationshipIDs(c, r, relation, false)
+	if err != nil {
+		return err
+	}
+
+	editObj, err := res.objects.edit(response.Result(), func(ptr interface{}) error {
+		return editToManyIDs(jsonapi.Adapt(ptr), relation.Name, obsoleteIDs, false)
+	})
+	if err != nil {
+		return err
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	updated, err := source.Update(editObj, buildRequest(c, r))
+	if err != nil {
+		return err
+	}
 
-	return err
+	return res.respondWithUpdatedRelationship(c, updated, id, "Update", info, relation, w, r)
 }
 
-func (res *resource) handleDeleteToManyRelation(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, relation jsonapi.Reference) error {
-	source, ok := res.source.(ResourceUpdater)
+// editToManyIDs adds or deletes ids of a to-many relationship of target, the types of the identifiers are
+// only passed on if target implements jsonapi.EditToManyRelationsWithType. Errors of the edit methods are
+// ignored, the object is passed to Update anyway.
+func editToManyIDs(target interface{}, name string, IDs []jsonapi.ReferenceID, add bool) error {
+	if withType, ok := target.(jsonapi.EditToManyRelationsWithType); ok {
+		if add {
+			withType.AddToManyIDsWithType(name, IDs)
+		} else {
+			withType.DeleteToManyIDsWithType(name, IDs)
+		}
 
+		return nil
+	}
+
+	editor, ok := target.(jsonapi.EditToManyRelations)
 	if !ok {
-		return fmt.Errorf("Resource %s does not implement the ResourceUpdater interface", res.name)
+		return errors.New("target struct must implement jsonapi.EditToManyRelations")
 	}
 
-	var (
-		err     error
-		editObj interface{}
-	)
+	plainIDs := make([]string, 0, len(IDs))
+	for _, ID := range IDs {
+		plainIDs = append(plainIDs, ID.ID)
+	}
 
-	id := params["id"]
+	if add {
+		editor.AddToManyIDs(name, plainIDs)
+	} else {
+		editor.DeleteToManyIDs(name, plainIDs)
+	}
 
-	response, err := source.FindOne(id, buildRequest(c, r))
-	if err != nil {
-		return err
+	return nil
+}
+
+// respondWithUpdatedRelationship writes the response of a relationship mutation according to the status code
+// of the resource, the relationship of the FindOne result is returned for 200 if the resource returned no object
+func (res *resource) respondWithUpdatedRelationship(c APIContexter, response Responder, id, method string, info information, relation jsonapi.Reference, w http.ResponseWriter, r *http.Request) error {
+	switch response.StatusCode() {
+	case http.StatusOK:
+		if response.Result() == nil {
+			source, ok := res.source.(ResourceGetter)
+			if !ok {
+				return fmt.Errorf("Resource %s does not implement the ResourceGetter interface", res.name)
+			}
+
+			internalResponse, err := source.FindOne(id, buildRequest(c, r))
+			if err != nil {
+				return err
+			}
+
+			response = internalResponse
+		}
+
+		return res.respondWithRelationship(response, info, relation, w, r)
+	case http.StatusAccepted:
+		w.WriteHeader(http.StatusAccepted)
+		return nil
+	case http.StatusNoContent:
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	default:
+		return fmt.Errorf("invalid status code %d from resource %s for method %s", response.StatusCode(), res.name, method)
 	}
+}
 
+// unmarshalRelationshipData returns the content of the "data" member of a relationship request
+func (res *resource) unmarshalRelationshipData(r *http.Request) (interface{}, error) {
 	body, err := unmarshalRequest(r)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	inc := map[string]interface{}{}
-	err = json.Unmarshal(body, &inc)
+	err = res.api.jsonCodec().Unmarshal(body, &inc)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	data, ok := inc["data"]
 	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
+		return nil, errors.New("Invalid object. Need a \"data\" object")
+	}
+
+	return data, nil
+}
+
+// unmarshalToManyRelationshipIDs returns the identifiers of a request to add or remove to-many relationships,
+// the existence of the referenced resources is only checked if checkExistence is true
+func (res *resource) unmarshalToManyRelationshipIDs(c APIContexter, r *http.Request, relation jsonapi.Reference, checkExistence bool) ([]jsonapi.ReferenceID, error) {
+	data, err := res.unmarshalRelationshipData(r)
+	if err != nil {
+		return nil, err
 	}
 
 	newRels, ok := data.([]interface{})
 	if !ok {
-		return fmt.Errorf("Data must be an array with \"id\" and \"type\" field to add new to-many relationships")
+		return nil, fmt.Errorf("Data must be an array with \"id\" and \"type\" field to add new to-many relationships")
 	}
 
-	obsoleteIDs := []string{}
+	ids := []jsonapi.ReferenceID{}
 
 	for _, newRel := range newRels {
 		casted, ok := newRel.(map[string]interface{})
 		if !ok {
-			return errors.New("entry in data object invalid")
+			return nil, errors.New("entry in data object invalid")
 		}
-		obsoleteID, ok := casted["id"].(string)
+		id, ok := casted["id"].(string)
 		if !ok {
-			return errors.New("no id field found inside data object")
+			return nil, errors.New("no id field found inside data object")
 		}
+		idType, _ := casted["type"].(string)
 
-		obsoleteIDs = append(obsoleteIDs, obsoleteID)
+		ids = append(ids, jsonapi.ReferenceID{ID: id, Type: idType, Name: relation.Name, Relationship: jsonapi.ToManyRelationship})
 	}
 
-	resType := reflect.TypeOf(response.Result()).Kind()
-	if resType == reflect.Struct {
-		editObj = getPointerToStruct(response.Result())
-	} else {
-		editObj = response.Result()
+	if err := res.checkRelationshipReferences(c, r, relation, data, checkExistence); err != nil {
+		return nil, err
 	}
 
-	targetObj, ok := editObj.(jsonapi.EditToManyRelations)
-	if !ok {
-		return errors.New("target struct must implement jsonapi.EditToManyRelations")
+	return ids, nil
+}
+
+// getReplacedRelationshipIDs returns the new identifiers of a relationship, to-one relationships have
+// exactly one identifier or none if they are cleared
+func getReplacedRelationshipIDs(data interface{}, relation jsonapi.Reference) ([]jsonapi.ReferenceID, error) {
+	if isToManyReference(relation) {
+		hasMany, ok := data.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid data array, must be an array of objects with \"id\" and \"type\" field for %s", relation.Name)
+		}
+
+		ids := []jsonapi.ReferenceID{}
+		for _, entry := range hasMany {
+			casted, ok := entry.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("entry in data array must be an object for %s", relation.Name)
+			}
+			id, ok := casted["id"].(string)
+			if !ok {
+				return nil, fmt.Errorf("all data objects must have a field id for %s", relation.Name)
+			}
+			idType, _ := casted["type"].(string)
+
+			ids = append(ids, jsonapi.ReferenceID{ID: id, Type: idType, Name: relation.Name, Relationship: jsonapi.ToManyRelationship})
+		}
+
+		return ids, nil
 	}
-	targetObj.DeleteToManyIDs(relation.Name, obsoleteIDs)
 
-	if resType == reflect.Struct {
-		_, err = source.Update(reflect.ValueOf(targetObj).Elem().Interface(), buildRequest(c, r))
-	} else {
-		_, err = source.Update(targetObj, buildRequest(c, r))
+	if data == nil {
+		return []jsonapi.ReferenceID{}, nil
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	hasOne, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid data object, must be an object with \"id\" and \"type\" field or null for %s", relation.Name)
+	}
+
+	id, ok := hasOne["id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("data object must have a field id for %s", relation.Name)
+	}
+	idType, _ := hasOne["type"].(string)
 
-	return err
+	return []jsonapi.ReferenceID{{ID: id, Type: idType, Name: relation.Name, Relationship: jsonapi.ToOneRelationship}}, nil
 }
 
 // returns a pointer to an interface{} struct
@@ -991,42 +1419,182 @@ func writeResult(w http.ResponseWriter, data []byte, status int, contentType str
 	w.Write(data)
 }
 
-func (res *resource) respondWith(obj Responder, info information, status int, w http.ResponseWriter, r *http.Request) error {
-	data, err := jsonapi.MarshalToStruct(obj.Result(), info)
+func (res *resource) respondWith(c APIContexter, obj Responder, info information, status int, w http.ResponseWriter, r *http.Request) error {
+	var links jsonapi.Links
+	if objWithLinks, ok := obj.(LinksResponder); ok {
+		baseURL := strings.Trim(info.GetBaseURL(), "/")
+		requestURL := fmt.Sprintf("%s%s", baseURL, r.URL.Path)
+		links = objWithLinks.Links(r, requestURL)
+	}
+
+	return res.respondWithPagination(c, obj, info, status, links, w, r)
+}
+
+func (res *resource) respondWithPagination(c APIContexter, obj Responder, info information, status int, links jsonapi.Links, w http.ResponseWriter, r *http.Request) error {
+	if stream, ok := obj.(StreamResponder); ok {
+		return res.respondWithStream(stream, info, status, links, w, r)
+	}
+
+	data, err := jsonapi.MarshalToStructWithOptions(obj.Result(), info, res.api.marshalOptions(r))
 	if err != nil {
 		return err
 	}
 
+	if len(links) > 0 {
+		data.Links = links
+	}
+
 	meta := obj.Metadata()
 	if len(meta) > 0 {
 		data.Meta = meta
 	}
 
-	if objWithLinks, ok := obj.(LinksResponder); ok {
-		baseURL := strings.Trim(info.GetBaseURL(), "/")
-		requestURL := fmt.Sprintf("%s%s", baseURL, r.URL.Path)
-		links := objWithLinks.Links(r, requestURL)
-		if len(links) > 0 {
-			data.Links = links
-		}
+	if err := newBatchLoader(res.api, c, r).includeResources(data, info); err != nil {
+		return err
 	}
 
+	res.api.decorateDocument(data, r)
+
 	return res.marshalResponse(data, w, status, r)
 }
 
-func (res *resource) respondWithPagination(obj Responder, info information, status int, links jsonapi.Links, w http.ResponseWriter, r *http.Request) error {
-	data, err := jsonapi.MarshalToStruct(obj.Result(), info)
+// respondWithStream writes the records of a StreamResponder one by one
+func (res *resource) respondWithStream(obj StreamResponder, info information, status int, links jsonapi.Links, w http.ResponseWriter, r *http.Request) error {
+	records := obj.Records()
+	if closer, ok := records.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// included resources are only loaded for complete documents, so they cannot be streamed
+	if len(parseIncludeTree(r)) > 0 {
+		return NewHTTPError(nil, "include is not supported for streamed responses", http.StatusBadRequest)
+	}
+
+	// the first record is read before anything is written, so that errors of the iterator and invalid sparse
+	// fieldsets of its type and included structs can still be answered with an error document
+	first, err := records.Next()
 	if err != nil {
 		return err
 	}
 
-	data.Links = links
+	options := res.api.marshalOptions(r)
+	if first != nil {
+		if _, err := jsonapi.MarshalToStructWithOptions(first, info, options); err != nil {
+			return err
+		}
+	}
+
+	stream := &jsonapi.Stream{Records: &peekedIterator{first: first, rest: records}, Options: options}
+	if len(links) > 0 {
+		stream.Document.Links = links
+	}
+
 	meta := obj.Metadata()
 	if len(meta) > 0 {
-		data.Meta = meta
+		stream.Document.Meta = meta
 	}
 
-	return res.marshalResponse(data, w, status, r)
+	res.api.decorateDocument(&stream.Document, r)
+
+	fields := options.Fields
+	if len(fields) > 0 {
+		if err := res.api.checkSparseFields(fields); err != nil {
+			return err
+		}
+
+		stream.Filter = func(data *jsonapi.Data) error {
+			if wrongFields := res.api.wrongFields(fields, data); len(wrongFields) > 0 {
+				return newSparseFieldsError(wrongFields)
+			}
+
+			return nil
+		}
+	}
+
+	w.Header().Set("Content-Type", res.api.contentType(r))
+	w.WriteHeader(status)
+
+	buffered := bufio.NewWriter(w)
+	if err := jsonapi.MarshalTo(buffered, stream, info); err != nil {
+		// the status code was already sent, so the error can only be logged
+		log.Printf("streaming the response of %s failed: %s", res.name, err)
+		buffered.Flush()
+		return nil
+	}
+
+	return buffered.Flush()
+}
+
+// peekedIterator returns the already read first record before the remaining records of rest
+type peekedIterator struct {
+	first jsonapi.MarshalIdentifier
+	rest  jsonapi.Iterator
+	read  bool
+}
+
+func (p *peekedIterator) Next() (jsonapi.MarshalIdentifier, error) {
+	if !p.read {
+		p.read = true
+		return p.first, nil
+	}
+
+	if p.first == nil {
+		return nil, nil
+	}
+
+	return p.rest.Next()
+}
+
+func (api *API) decorateDocument(document *jsonapi.Document, r *http.Request) {
+	document.JSONAPI = api.getJSONAPIObject(r)
+	document.Profiles = profileLinks(r)
+
+	if api.describedBy != nil {
+		if document.Links == nil {
+			document.Links = make(jsonapi.Links)
+		}
+
+		if _, ok := document.Links["describedby"]; !ok {
+			document.Links["describedby"] = *api.describedBy
+		}
+	}
+}
+
+// getJSONAPIObject returns a copy of the configured jsonapi object with all applied extensions and profiles
+func (api *API) getJSONAPIObject(r *http.Request) *jsonapi.JSONAPI {
+	if api.jsonapiObject == nil {
+		return nil
+	}
+
+	object := *api.jsonapiObject
+	negotiated := getNegotiatedParams(r)
+	object.Ext = appendMissingStrings(object.Ext, negotiated.extensions)
+	object.Profile = appendMissingStrings(object.Profile, negotiated.profiles)
+
+	return &object
+}
+
+// appendMissingStrings returns a new slice with all values of source and the values of add that are not in source
+func appendMissingStrings(source, add []string) []string {
+	if len(add) == 0 {
+		return source
+	}
+
+	result := make([]string, len(source), len(source)+len(add))
+	copy(result, source)
+	known := map[string]bool{}
+	for _, value := range source {
+		known[value] = true
+	}
+
+	for _, value := range add {
+		if !known[value] {
+			known[value] = true
+			result = append(result, value)
+		}
+	}
+
+	return result
 }
 
 func unmarshalRequest(r *http.Request) ([]byte, error) {
@@ -1038,63 +1606,85 @@ func unmarshalRequest(r *http.Request) ([]byte, error) {
 	return data, nil
 }
 
-func filterSparseFields(resp interface{}, r *http.Request) (interface{}, error) {
+// checkDocumentFields checks the requested fields of all types in the document, the attributes were
+// already filtered while marshalling
+func (api *API) checkDocumentFields(resp interface{}, r *http.Request) error {
 	query := r.URL.Query()
-	queryParams := parseQueryFields(&query)
-	if len(queryParams) < 1 {
-		return resp, nil
+	fields := parseQueryFields(&query)
+	if len(fields) < 1 {
+		return nil
 	}
 
-	if document, ok := resp.(*jsonapi.Document); ok {
-		wrongFields := map[string][]string{}
+	document, ok := resp.(*jsonapi.Document)
+	if !ok || document.Data == nil {
+		return nil
+	}
 
-		// single entry in data
-		data := document.Data.DataObject
-		if data != nil {
-			errors := replaceAttributes(&queryParams, data)
-			for t, v := range errors {
-				wrongFields[t] = v
-			}
+	entries := []*jsonapi.Data{}
+	if document.Data.DataObject != nil {
+		entries = append(entries, document.Data.DataObject)
+	}
+	for index := range document.Data.DataArray {
+		entries = append(entries, &document.Data.DataArray[index])
+	}
+	for index := range document.Included {
+		entries = append(entries, &document.Included[index])
+	}
+
+	wrongFields := map[string][]string{}
+	for _, entry := range entries {
+		for t, v := range api.wrongFields(fields, entry) {
+			wrongFields[t] = v
 		}
+	}
 
-		// data can be a slice too
-		datas := document.Data.DataArray
-		for index, data := range datas {
-			errors := replaceAttributes(&queryParams, &data)
-			for t, v := range errors {
-				wrongFields[t] = v
-			}
-			datas[index] = data
+	if len(wrongFields) > 0 {
+		return newSparseFieldsError(wrongFields)
+	}
+
+	return nil
+}
+
+// checkSparseFields checks the requested fields of all types whose attributes are known
+func (api *API) checkSparseFields(fields map[string][]string) error {
+	wrongFields := map[string][]string{}
+	for fieldType, requested := range fields {
+		knownFields, ok := jsonapi.AttributeNamesWithOptions(fieldType, api.options)
+		if !ok {
+			continue
 		}
 
-		// included slice
-		for index, include := range document.Included {
-			errors := replaceAttributes(&queryParams, &include)
-			for t, v := range errors {
-				wrongFields[t] = v
+		for _, field := range requested {
+			if !containsString(knownFields, field) {
+				wrongFields[fieldType] = append(wrongFields[fieldType], field)
 			}
-			document.Included[index] = include
 		}
+	}
 
-		if len(wrongFields) > 0 {
-			httpError := NewHTTPError(nil, "Some requested fields were invalid", http.StatusBadRequest)
-			for k, v := range wrongFields {
-				for _, field := range v {
-					httpError.Errors = append(httpError.Errors, Error{
-						Status: "Bad Request",
-						Code:   codeInvalidQueryFields,
-						Title:  fmt.Sprintf(`Field "%s" does not exist for type "%s"`, field, k),
-						Detail: "Please make sure you do only request existing fields",
-						Source: &ErrorSource{
-							Parameter: fmt.Sprintf("fields[%s]", k),
-						},
-					})
-				}
-			}
-			return nil, httpError
+	if len(wrongFields) > 0 {
+		return newSparseFieldsError(wrongFields)
+	}
+
+	return nil
+}
+
+func newSparseFieldsError(wrongFields map[string][]string) HTTPError {
+	httpError := NewHTTPError(nil, "Some requested fields were invalid", http.StatusBadRequest)
+	for k, v := range wrongFields {
+		for _, field := range v {
+			httpError.Errors = append(httpError.Errors, Error{
+				Status: "Bad Request",
+				Code:   codeInvalidQueryFields,
+				Title:  fmt.Sprintf(`Field "%s" does not exist for type "%s"`, field, k),
+				Detail: "Please make sure you do only request existing fields",
+				Source: &ErrorSource{
+					Parameter: fmt.Sprintf("fields[%s]", k),
+				},
+			})
 		}
 	}
-	return resp, nil
+
+	return httpError
 }
 
 func parseQueryFields(query *url.Values) (result map[string][]string) {
@@ -1110,51 +1700,72 @@ func parseQueryFields(query *url.Values) (result map[string][]string) {
 	return
 }
 
-func filterAttributes(attributes map[string]interface{}, fields []string) (filteredAttributes map[string]interface{}, wrongFields []string) {
-	wrongFields = []string{}
-	filteredAttributes = map[string]interface{}{}
+// wrongFields returns the requested fields that do not exist for the type of the entry. The attribute names of
+// types with a custom json encoding are unknown, so their fields must be part of the attributes of the entry.
+func (api *API) wrongFields(fields map[string][]string, entry *jsonapi.Data) map[string][]string {
+	requested := fields[entry.Type]
+	if len(requested) == 0 {
+		return nil
+	}
 
-	for _, field := range fields {
-		if attribute, ok := attributes[field]; ok {
-			filteredAttributes[field] = attribute
-		} else {
-			wrongFields = append(wrongFields, field)
+	knownFields, ok := jsonapi.AttributeNamesWithOptions(entry.Type, api.options)
+	if !ok {
+		// attribute values are not decoded
+		attributes := map[string]json.RawMessage{}
+		_ = api.jsonCodec().Unmarshal(entry.Attributes, &attributes)
+		for name := range attributes {
+			knownFields = append(knownFields, name)
 		}
 	}
 
-	return
-}
-
-func replaceAttributes(query *map[string][]string, entry *jsonapi.Data) map[string][]string {
-	fieldType := entry.Type
-	attributes := map[string]interface{}{}
-	_ = json.Unmarshal(entry.Attributes, &attributes)
-	fields := (*query)[fieldType]
-	if len(fields) > 0 {
-		var wrongFields []string
-		attributes, wrongFields = filterAttributes(attributes, fields)
-		if len(wrongFields) > 0 {
-			return map[string][]string{
-				fieldType: wrongFields,
-			}
+	wrongFields := []string{}
+	for _, field := range requested {
+		if !containsString(knownFields, field) {
+			wrongFields = append(wrongFields, field)
 		}
-		bytes, _ := json.Marshal(attributes)
-		entry.Attributes = bytes
 	}
 
-	return nil
+	if len(wrongFields) == 0 {
+		return nil
+	}
+
+	return map[string][]string{entry.Type: wrongFields}
+}
+
+// marshalOptions returns the options of the api together with the sparse fieldsets of the request
+func (api *API) marshalOptions(r *http.Request) jsonapi.Options {
+	options := api.options
+	query := r.URL.Query()
+	options.Fields = parseQueryFields(&query)
+	return options
 }
 
 func (api *API) handleError(err error, w http.ResponseWriter, r *http.Request) {
 	log.Println(err)
-	if e, ok := err.(HTTPError); ok {
-		writeResult(w, []byte(marshalHTTPError(e)), e.status, api.ContentType)
-		return
+	e, ok := err.(HTTPError)
+	if !ok {
+		e = NewHTTPError(err, err.Error(), http.StatusInternalServerError)
+	}
 
+	document := errorDocument{
+		JSONAPI:   api.getJSONAPIObject(r),
+		HTTPError: e,
 	}
 
-	e := NewHTTPError(err, err.Error(), http.StatusInternalServerError)
-	writeResult(w, []byte(marshalHTTPError(e)), http.StatusInternalServerError, api.ContentType)
+	if api.describedBy != nil {
+		document.Links = jsonapi.Links{"describedby": *api.describedBy}
+	}
+
+	writeResult(w, []byte(marshalErrorDocument(document, api.jsonCodec())), e.status, api.contentType(r))
+}
+
+// jsonCodec returns the JSONCodec of the api or the one of the jsonapi package if none is set
+func (api *API) jsonCodec() jsonapi.JSONCodec {
+	if api.options.Codec != nil {
+		return api.options.Codec
+	}
+
+	return jsonapi.GetJSONCodec()
 }
 
 // TODO: this can also be replaced with a struct into that we directly json.Unmarshal
@@ -1166,48 +1777,72 @@ func processRelationshipsData(data interface{}, linkName string, target interfac
 			return fmt.Errorf("data object must have a field id for %s", linkName)
 		}
 
-		target, ok := target.(jsonapi.UnmarshalToOneRelations)
-		if !ok {
-			return errors.New("target struct must implement interface UnmarshalToOneRelations")
-		}
-
-		target.SetToOneReferenceID(linkName, hasOneID)
+		hasOneType, _ := hasOne["type"].(string)
+		return setToOneReferenceID(target, jsonapi.ReferenceID{
+			ID:           hasOneID,
+			Type:         hasOneType,
+			Name:         linkName,
+			Relationship: jsonapi.ToOneRelationship,
+		})
 	} else if data == nil {
 		// this means that a to-one relationship must be deleted
-		target, ok := target.(jsonapi.UnmarshalToOneRelations)
-		if !ok {
-			return errors.New("target struct must implement interface UnmarshalToOneRelations")
-		}
+		return setToOneReferenceID(target, jsonapi.ReferenceID{Name: linkName, Relationship: jsonapi.ToOneRelationship})
+	}
 
-		target.SetToOneReferenceID(linkName, "")
-	} else {
-		hasMany, ok := data.([]interface{})
+	hasMany, ok := data.([]interface{})
+	if !ok {
+		return fmt.Errorf("invalid data object or array, must be an object with \"id\" and \"type\" field for %s", linkName)
+	}
+
+	withType, hasType := target.(jsonapi.UnmarshalToManyRelationsWithType)
+	toMany, ok := target.(jsonapi.UnmarshalToManyRelations)
+	if !hasType && !ok {
+		return errors.New("target struct must implement interface UnmarshalToManyRelations")
+	}
+
+	hasManyIDs := []string{}
+	referenceIDs := []jsonapi.ReferenceID{}
+
+	for _, entry := range hasMany {
+		data, ok := entry.(map[string]interface{})
 		if !ok {
-			return fmt.Errorf("invalid data object or array, must be an object with \"id\" and \"type\" field for %s", linkName)
+			return fmt.Errorf("entry in data array must be an object for %s", linkName)
 		}
-
-		target, ok := target.(jsonapi.UnmarshalToManyRelations)
+		dataID, ok := data["id"].(string)
 		if !ok {
-			return errors.New("target struct must implement interface UnmarshalToManyRelations")
+			return fmt.Errorf("all data objects must have a field id for %s", linkName)
 		}
+		dataType, _ := data["type"].(string)
+
+		hasManyIDs = append(hasManyIDs, dataID)
+		referenceIDs = append(referenceIDs, jsonapi.ReferenceID{
+			ID:           dataID,
+			Type:         dataType,
+			Name:         linkName,
+			Relationship: jsonapi.ToManyRelationship,
+		})
+	}
 
-		hasManyIDs := []string{}
+	if hasType {
+		return withType.SetToManyReferenceIDsWithType(linkName, referenceIDs)
+	}
 
-		for _, entry := range hasMany {
-			data, ok := entry.(map[string]interface{})
-			if !ok {
-				return fmt.Errorf("entry in data array must be an object for %s", linkName)
-			}
-			dataID, ok := data["id"].(string)
-			if !ok {
-				return fmt.Errorf("all data objects must have a field id for %s", linkName)
-			}
+	toMany.SetToManyReferenceIDs(linkName, hasManyIDs)
+	return nil
+}
 
-			hasManyIDs = append(hasManyIDs, dataID)
-		}
+// setToOneReferenceID sets a to-one relationship with type if the target supports it, an empty
+// id clears the relationship
+func setToOneReferenceID(target interface{}, referenceID jsonapi.ReferenceID) error {
+	if withType, ok := target.(jsonapi.UnmarshalToOneRelationsWithType); ok {
+		return withType.SetToOneReferenceIDWithType(referenceID.Name, referenceID)
+	}
 
-		target.SetToManyReferenceIDs(linkName, hasManyIDs)
+	toOne, ok := target.(jsonapi.UnmarshalToOneRelations)
+	if !ok {
+		return errors.New("target struct must implement interface UnmarshalToOneRelations")
 	}
 
+	toOne.SetToOneReferenceID(referenceID.Name, referenceID.ID)
 	return nil
 }
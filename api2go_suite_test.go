@@ -5,11 +5,27 @@ import (
 	. "github.com/onsi/gomega"
 	"io/ioutil"
 	"log"
+	"os"
+	"sync/atomic"
+
+	"github.com/manyminds/api2go/jsonapi"
 
 	"testing"
 )
 
 func TestApi2go(t *testing.T) {
+	// API2GO_TEST_JSON_CODEC=counting runs the suite with a custom JSONCodec
+	if os.Getenv("API2GO_TEST_JSON_CODEC") == "counting" {
+		codec := &countingCodec{}
+		jsonapi.SetJSONCodec(codec)
+		defer jsonapi.SetJSONCodec(nil)
+		defer func() {
+			if atomic.LoadInt64(&codec.calls) == 0 {
+				t.Error("expected the custom JSONCodec to be used")
+			}
+		}()
+	}
+
 	RegisterFailHandler(Fail)
 	log.SetOutput(ioutil.Discard)
 	RunSpecs(t, "Api2go Suite")
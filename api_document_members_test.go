@@ -0,0 +1,94 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Top-level jsonapi object and describedby link", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.SetJSONAPIObject(jsonapi.JSONAPI{Version: "1.1", Meta: map[string]interface{}{"server": "api2go"}})
+		api.SetDescribedBy(jsonapi.Link{Href: "https://example.com/openapi.json"})
+		api.AddResource(SomeData{}, &extensionResource{})
+		rec = httptest.NewRecorder()
+	})
+
+	get := func(url, accept string) {
+		req, err := http.NewRequest("GET", url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Accept", accept)
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("adds both to single resources", func() {
+		get("/v1/someDatas/12345", "application/vnd.api+json")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"jsonapi": {"version": "1.1", "meta": {"server": "api2go"}},
+			"links": {"describedby": "https://example.com/openapi.json"},
+			"data": {
+				"type": "someDatas",
+				"id": "12345",
+				"attributes": {
+					"data": "A Brezzn",
+					"customerId": ""
+				}
+			}
+		}`))
+	})
+
+	It("adds both next to pagination links", func() {
+		get("/v1/someDatas?page[number]=1&page[size]=1", "application/vnd.api+json")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var document map[string]interface{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+		Expect(document).To(HaveKeyWithValue("jsonapi", map[string]interface{}{
+			"version": "1.1",
+			"meta":    map[string]interface{}{"server": "api2go"},
+		}))
+		Expect(document["links"]).To(HaveKeyWithValue("describedby", "https://example.com/openapi.json"))
+		Expect(document["links"]).To(HaveKey("next"))
+	})
+
+	It("adds both to error documents", func() {
+		get("/v1/someDatas/12345", `application/vnd.api+json; ext="https://example.com/ext/unknown"`)
+		Expect(rec.Code).To(Equal(http.StatusNotAcceptable))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"jsonapi": {"version": "1.1", "meta": {"server": "api2go"}},
+			"links": {"describedby": "https://example.com/openapi.json"},
+			"errors": [{
+				"status": "406",
+				"code": "API2GO_NOT_ACCEPTABLE",
+				"title": "All JSON API media types in the Accept header contain unsupported extensions"
+			}]
+		}`))
+	})
+
+	It("lists applied extensions and profiles in the jsonapi object", func() {
+		api.RegisterExtension("https://jsonapi.org/ext/atomic")
+		api.RegisterProfile("https://example.com/profiles/timestamps")
+		get("/v1/someDatas/12345", `application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"; profile="https://example.com/profiles/timestamps"`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"jsonapi":{"version":"1.1","ext":["https://jsonapi.org/ext/atomic"],"profile":["https://example.com/profiles/timestamps"],"meta":{"server":"api2go"}}`))
+	})
+
+	It("does not add anything if nothing is configured", func() {
+		api = NewAPI("v1")
+		api.AddResource(SomeData{}, &extensionResource{})
+		get("/v1/someDatas/12345", "application/vnd.api+json")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).ToNot(ContainSubstring("jsonapi"))
+		Expect(rec.Body.String()).ToNot(ContainSubstring("links"))
+	})
+})
@@ -0,0 +1,238 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Article struct {
+	ID        string   `jsonapi:"primary,articles"`
+	Title     string   `jsonapi:"attr,title"`
+	WriterID  string   `jsonapi:"relation,writer,writers"`
+	RemarkIDs []string `jsonapi:"relation,remarks,remarks"`
+}
+
+type Writer struct {
+	ID   string `jsonapi:"primary,writers"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type Remark struct {
+	ID       string `jsonapi:"primary,remarks"`
+	Text     string `jsonapi:"attr,text"`
+	WriterID string `jsonapi:"relation,writer,writers"`
+}
+
+// lookupSource stores objects by id and records all lookups
+type lookupSource struct {
+	objects   map[string]interface{}
+	findOne   []string
+	findMany  [][]string
+	relations []*Relation
+}
+
+func (s *lookupSource) FindAll(req Request) (Responder, error) {
+	s.relations = append(s.relations, req.Relation)
+	ids := []string{}
+	for id := range s.objects {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	objects := []interface{}{}
+	for _, id := range ids {
+		objects = append(objects, s.objects[id])
+	}
+
+	return &Response{Res: objects}, nil
+}
+
+func (s *lookupSource) FindOne(ID string, req Request) (Responder, error) {
+	s.findOne = append(s.findOne, ID)
+	object, ok := s.objects[ID]
+	if !ok {
+		return nil, NewHTTPError(nil, "not found", http.StatusNotFound)
+	}
+
+	return &Response{Res: object}, nil
+}
+
+// batchLookupSource additionally implements FindMany
+type batchLookupSource struct {
+	lookupSource
+}
+
+func (s *batchLookupSource) FindMany(IDs []string, req Request) (Responder, error) {
+	s.findMany = append(s.findMany, IDs)
+	objects := []interface{}{}
+	for _, ID := range IDs {
+		if object, ok := s.objects[ID]; ok {
+			objects = append(objects, object)
+		}
+	}
+
+	return &Response{Res: objects, Meta: map[string]interface{}{"batch": true}}, nil
+}
+
+var _ = Describe("Batch loading with FindMany", func() {
+	var (
+		api      *API
+		articles *lookupSource
+		writers  *batchLookupSource
+		remarks  *lookupSource
+		rec      *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		articles = &lookupSource{objects: map[string]interface{}{
+			"1": Article{ID: "1", Title: "First", WriterID: "1", RemarkIDs: []string{"1", "2"}},
+			"2": Article{ID: "2", Title: "Second", WriterID: "1", RemarkIDs: []string{"2", "3", "4"}},
+			"3": Article{ID: "3", Title: "Third", WriterID: "2"},
+		}}
+		writers = &batchLookupSource{lookupSource{objects: map[string]interface{}{
+			"1": Writer{ID: "1", Name: "Marvin"},
+			"2": Writer{ID: "2", Name: "Arthur"},
+			"3": Writer{ID: "3", Name: "Trillian"},
+		}}}
+		remarks = &lookupSource{objects: map[string]interface{}{
+			"1": Remark{ID: "1", Text: "Nice", WriterID: "3"},
+			"2": Remark{ID: "2", Text: "Boring", WriterID: "2"},
+			"3": Remark{ID: "3", Text: "Great", WriterID: "3"},
+		}}
+		api.AddResource(Article{}, articles)
+		api.AddResource(Writer{}, writers)
+		api.AddResource(Remark{}, remarks)
+		rec = httptest.NewRecorder()
+	})
+
+	request := func(url string) {
+		req, err := http.NewRequest("GET", url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	included := func() []string {
+		var document struct {
+			Included []struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+			} `json:"included"`
+		}
+		Expect(api.jsonCodec().Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+
+		result := []string{}
+		for _, data := range document.Included {
+			result = append(result, data.Type+"/"+data.ID)
+		}
+		return result
+	}
+
+	It("loads the included resources of all primary records with one FindMany call", func() {
+		request("/v1/articles?include=writer")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(writers.findMany).To(Equal([][]string{{"1", "2"}}))
+		Expect(writers.findOne).To(BeEmpty())
+		Expect(included()).To(Equal([]string{"writers/1", "writers/2"}))
+		Expect(rec.Body.String()).To(ContainSubstring(`"attributes":{"name":"Marvin"}`))
+	})
+
+	It("looks up every id once with FindOne without FindMany", func() {
+		request("/v1/articles?include=remarks")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(remarks.findOne).To(Equal([]string{"1", "2", "3", "4"}))
+		Expect(included()).To(Equal([]string{"remarks/1", "remarks/2", "remarks/3"}))
+	})
+
+	It("resolves nested include paths level by level", func() {
+		request("/v1/articles/1?include=remarks.writer,writer")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(remarks.findOne).To(Equal([]string{"1", "2"}))
+		Expect(writers.findMany).To(Equal([][]string{{"1"}, {"3", "2"}}))
+		Expect(included()).To(Equal([]string{"remarks/1", "remarks/2", "writers/1", "writers/3", "writers/2"}))
+	})
+
+	It("does not include the primary data", func() {
+		request("/v1/writers/1?include=")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(included()).To(BeEmpty())
+
+		rec = httptest.NewRecorder()
+		request("/v1/remarks?include=writer")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(writers.findMany).To(Equal([][]string{{"3", "2"}}))
+	})
+
+	It("rejects unknown include paths", func() {
+		request("/v1/articles?include=remarks.author")
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("invalid include path remarks.author"))
+	})
+
+	It("uses FindMany for related resources of to-many relationships", func() {
+		source := &batchLookupSource{lookupSource{objects: remarks.objects}}
+		api = NewAPI("v1")
+		api.AddResource(Article{}, articles)
+		api.AddResource(Remark{}, source)
+		request("/v1/articles/2/remarks")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.findMany).To(Equal([][]string{{"2", "3", "4"}}))
+		Expect(rec.Body.String()).To(ContainSubstring(`"id":"2"`))
+		Expect(rec.Body.String()).To(ContainSubstring(`"id":"3"`))
+	})
+
+	It("uses FindAll with the relation for sorted or filtered related resources", func() {
+		source := &batchLookupSource{lookupSource{objects: remarks.objects}}
+		api = NewAPI("v1")
+		api.AddResource(Article{}, articles)
+		api.AddResource(Remark{}, source)
+		request("/v1/articles/2/remarks?sort=-text")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		rec = httptest.NewRecorder()
+		request("/v1/articles/2/remarks?filter[text]=Great")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		relation := &Relation{ParentType: "articles", ParentID: "2", Name: "remarks"}
+		Expect(source.findMany).To(BeEmpty())
+		Expect(source.relations).To(Equal([]*Relation{relation, relation}))
+	})
+
+	It("uses FindMany for filter[id]", func() {
+		request("/v1/writers?filter[id]=1,3,1")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(writers.findMany).To(Equal([][]string{{"1", "3"}}))
+		Expect(rec.Body.String()).To(ContainSubstring(`"meta":{"batch":true}`))
+		Expect(rec.Body.String()).ToNot(ContainSubstring(`"name":"Arthur"`))
+	})
+
+	It("reads all values of filter[id]", func() {
+		request("/v1/writers?filter[id]=1&filter[id]=3,2")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(writers.findMany).To(Equal([][]string{{"1", "3", "2"}}))
+	})
+
+	It("uses FindAll if filter[id] is combined with other filters or sort", func() {
+		request("/v1/writers?filter[name]=Marvin&filter[id]=1,2")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(writers.findMany).To(BeEmpty())
+		Expect(rec.Body.String()).ToNot(ContainSubstring(`"meta":{"batch":true}`))
+
+		rec = httptest.NewRecorder()
+		request("/v1/writers?filter[id]=1,2,3&sort=-name")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(writers.findMany).To(BeEmpty())
+		Expect(rec.Body.String()).ToNot(ContainSubstring(`"meta":{"batch":true}`))
+	})
+
+	It("uses FindAll for filter[id] without FindMany", func() {
+		request("/v1/remarks?filter[id]=1")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(strings.Count(rec.Body.String(), `"type":"remarks"`)).To(Equal(3))
+	})
+})
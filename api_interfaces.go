@@ -41,7 +41,7 @@ type ResourceDeleter interface {
 	Delete(id string, req Request) (Responder, error)
 }
 
-// The ResourceUpdater interface MUST be implemented in order to generate the PATCH/PUT routes
+// The ResourceUpdater interface MUST be implemented in order to generate the PATCH route
 type ResourceUpdater interface {
 	// ResourceGetter must be implemented along with ResourceUpdater so that api2go can retrieve the single resource before update
 	ResourceGetter
@@ -53,6 +53,68 @@ type ResourceUpdater interface {
 	Update(obj interface{}, req Request) (Responder, error)
 }
 
+// The ResourceReplacer interface can be optionally implemented in order to generate the PUT route.
+// In contrast to Update, the object passed to Replace is not loaded with FindOne first. It is a new
+// object that only contains the values sent by the client, so it replaces the stored resource as a whole.
+type ResourceReplacer interface {
+	// ResourceGetter must be implemented along with ResourceReplacer so that api2go can retrieve the
+	// single resource if Replace does not return it
+	ResourceGetter
+	// Replace an object
+	// Possible Responder status codes are:
+	// - 200 OK: Replace successful, however some field(s) were changed, returns updates source
+	// - 202 Accepted: Processing is delayed, return nothing
+	// - 204 No Content: Replace was successful, no fields were changed by the server, return nothing
+	Replace(obj interface{}, req Request) (Responder, error)
+}
+
+// The RelationshipReplacer interface can be optionally implemented to replace a relationship on
+// PATCH /:id/relationships/:name directly in the storage, instead of loading the resource with FindOne
+// and saving it with Update. For to-one relationships, ids contains the new identifier or is empty if
+// the relationship is cleared. The identifiers contain the type sent by the client, so that polymorphic
+// relationships can be stored.
+// Possible Responder status codes are:
+// - 200 OK: Replace successful, returns the updated resource whose relationship linkage and meta are
+//   rendered. If no resource is returned, FindOne is used to retrieve it.
+// - 202 Accepted: Processing is delayed, return nothing
+// - 204 No Content: Replace was successful, return nothing
+type RelationshipReplacer interface {
+	ReplaceRelationship(id, name string, ids []jsonapi.ReferenceID, req Request) (Responder, error)
+}
+
+// The RelationshipAdder interface can be optionally implemented to add ids to a to-many relationship
+// on POST /:id/relationships/:name directly in the storage. The same status codes as for
+// RelationshipReplacer are possible.
+type RelationshipAdder interface {
+	AddToRelationship(id, name string, ids []jsonapi.ReferenceID, req Request) (Responder, error)
+}
+
+// The RelationshipRemover interface can be optionally implemented to remove ids from a to-many
+// relationship on DELETE /:id/relationships/:name directly in the storage. The same status codes as
+// for RelationshipReplacer are possible.
+type RelationshipRemover interface {
+	RemoveFromRelationship(id, name string, ids []jsonapi.ReferenceID, req Request) (Responder, error)
+}
+
+// The ExistenceChecker interface can be optionally implemented to check the existence of referenced
+// resources with one lookup if referential integrity checks are enabled. Otherwise FindOne is called
+// for every referenced id.
+type ExistenceChecker interface {
+	// FindMissingIDs returns all of the given ids that do not exist
+	FindMissingIDs(ids []string, req Request) ([]string, error)
+}
+
+// The FindMany interface can be optionally implemented to fetch multiple records by id with one
+// lookup. It is used for the `include` query parameter, where the related ids of all primary records
+// are requested together, for the related resource endpoints of to-many relationships without sort
+// and filters and for `filter[id]=1,2,3` on the collection endpoint if it is the only filter and no
+// sort is given. Otherwise FindOne is called for every referenced id.
+// Every id is only requested once per request.
+type FindMany interface {
+	// FindMany returns the objects with the given ids, ids that do not exist are skipped
+	FindMany(ids []string, req Request) (Responder, error)
+}
+
 // Pagination represents information needed to return pagination links
 type Pagination struct {
 	Next  map[string]string
@@ -70,6 +132,15 @@ type PaginatedFindAll interface {
 	PaginatedFindAll(req Request) (totalCount uint, response Responder, err error)
 }
 
+// The PaginatedFindRelationship interface can be optionally implemented to return only a subset of
+// the IDs of a to-many relationship on GET /:id/relationships/:name. Like PaginatedFindAll, the
+// pagination query parameters must be used to limit the result and pagination URLs will
+// automatically be generated by the api. Without pagination query parameters, the relationship of
+// the FindOne result is returned.
+type PaginatedFindRelationship interface {
+	PaginatedFindRelationship(id, name string, req Request) (totalCount uint, ids []jsonapi.ReferenceID, err error)
+}
+
 // The FindAll interface can be optionally implemented to fetch all records at once.
 type FindAll interface {
 	// FindAll returns all objects
@@ -78,8 +149,8 @@ type FindAll interface {
 
 // The ObjectInitializer interface can be implemented to have the ability to change
 // a created object before Unmarshal is called. This is currently only called on
-// Create as the other actions go through FindOne or FindAll which are already
-// controlled by the implementer.
+// Create and Replace as the other actions go through FindOne or FindAll which are
+// already controlled by the implementer.
 type ObjectInitializer interface {
 	InitializeObject(interface{})
 }
@@ -123,3 +194,15 @@ type LinksResponder interface {
 	Responder
 	Links(*http.Request, string) jsonapi.Links
 }
+
+// The StreamResponder interface can be implemented by the Responder of FindAll and PaginatedFindAll to stream large
+// collections. The records are read one by one from Records instead of Result and written to the response with
+// chunked transfer encoding, so the collection never has to be held in memory. Included structs are still collected
+// and deduplicated, the include query parameter is answered with 400. The first record is read before the status code is sent, so that its errors and invalid sparse
+// fieldsets are answered with an error document. Errors that occur after the first record was written can only be
+// logged, the response is incomplete then. If the iterator implements io.Closer, it is closed once the response was
+// written or streaming was aborted.
+type StreamResponder interface {
+	Responder
+	Records() jsonapi.Iterator
+}
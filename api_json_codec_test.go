@@ -0,0 +1,156 @@
+package api2go
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// countingCodec wraps the StandardJSONCodec and counts its calls
+type countingCodec struct {
+	jsonapi.StandardJSONCodec
+	calls int64
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	atomic.AddInt64(&c.calls, 1)
+	return c.StandardJSONCodec.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	atomic.AddInt64(&c.calls, 1)
+	return c.StandardJSONCodec.Unmarshal(data, v)
+}
+
+// typesCodec wraps the StandardJSONCodec and records the types of its values
+type typesCodec struct {
+	jsonapi.StandardJSONCodec
+	mutex sync.Mutex
+	types []string
+}
+
+func (c *typesCodec) record(v interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.types = append(c.types, fmt.Sprintf("%T", v))
+}
+
+func (c *typesCodec) Marshal(v interface{}) ([]byte, error) {
+	c.record(v)
+	return c.StandardJSONCodec.Marshal(v)
+}
+
+func (c *typesCodec) Unmarshal(data []byte, v interface{}) error {
+	c.record(v)
+	return c.StandardJSONCodec.Unmarshal(data, v)
+}
+
+// failingCodec returns an error for every call
+type failingCodec struct{}
+
+func (failingCodec) Marshal(v interface{}) ([]byte, error) {
+	return nil, errors.New("marshal failed")
+}
+
+func (failingCodec) Unmarshal(data []byte, v interface{}) error {
+	return errors.New("unmarshal failed")
+}
+
+var _ = Describe("Custom JSONCodec", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}, false})
+		rec = httptest.NewRecorder()
+	})
+
+	It("encodes response documents", func() {
+		codec := &countingCodec{}
+		api.SetJSONCodec(codec)
+		req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"title":"Hello, World!"`))
+		Expect(atomic.LoadInt64(&codec.calls)).To(BeNumerically(">", 0))
+	})
+
+	It("encodes error documents", func() {
+		codec := &countingCodec{}
+		api.SetJSONCodec(codec)
+		req, err := http.NewRequest("GET", "/v1/posts/2", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{"status": "404", "title": "post not found"}]}`))
+		Expect(atomic.LoadInt64(&codec.calls)).To(Equal(int64(1)))
+	})
+
+	It("encodes documents with sparse fieldsets", func() {
+		codec := &countingCodec{}
+		api.SetJSONCodec(codec)
+		req, err := http.NewRequest("GET", "/v1/posts/1?fields[posts]=title", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"attributes":{"title":"Hello, World!"}`))
+		// the record, the selection of its attributes (decoding the members and encoding the key) and the
+		// response document
+		Expect(atomic.LoadInt64(&codec.calls)).To(Equal(int64(4)))
+	})
+
+	It("decodes relationship requests", func() {
+		api.SetJSONCodec(failingCodec{})
+		reqBody := strings.NewReader(`{"data": {"type": "users", "id": "1"}}`)
+		req, err := http.NewRequest("PATCH", "/v1/posts/1/relationships/author", reqBody)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rec.Body.String()).To(Equal("{}"))
+	})
+
+	It("uses the codec of each api for the resources", func() {
+		first := &typesCodec{}
+		api.SetJSONCodec(first)
+
+		other := NewAPI("v1")
+		other.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false})
+		second := &typesCodec{}
+		other.SetJSONCodec(second)
+
+		req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(first.types).To(ContainElement("api2go.Post"))
+		Expect(second.types).To(BeEmpty())
+
+		reqBody := strings.NewReader(`{"data": {"type": "posts", "attributes": {"title": "New Post"}}}`)
+		req, err = http.NewRequest("POST", "/v1/posts", reqBody)
+		Expect(err).ToNot(HaveOccurred())
+		rec = httptest.NewRecorder()
+		other.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(second.types).To(ContainElement("*api2go.Post"))
+		Expect(first.types).ToNot(ContainElement("*api2go.Post"))
+	})
+
+	It("falls back to the codec of the jsonapi package", func() {
+		api.SetJSONCodec(nil)
+		Expect(api.jsonCodec()).To(Equal(jsonapi.GetJSONCodec()))
+	})
+})
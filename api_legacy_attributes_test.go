@@ -0,0 +1,100 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Label struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func (l Label) GetID() string {
+	return l.ID
+}
+
+func (l *Label) SetID(ID string) error {
+	l.ID = ID
+	return nil
+}
+
+type LabelResource struct{}
+
+func (s LabelResource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: Label{ID: ID, Type: "warning", Text: "Hot"}}, nil
+}
+
+func (s LabelResource) FindAll(req Request) (Responder, error) {
+	return &Response{Res: []Label{{ID: "1", Type: "warning", Text: "Hot"}}}, nil
+}
+
+func (s LabelResource) Create(obj interface{}, req Request) (Responder, error) {
+	label := obj.(Label)
+	return &Response{Res: label, Code: http.StatusCreated}, nil
+}
+
+func (s LabelResource) Delete(ID string, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s LabelResource) Update(obj interface{}, req Request) (Responder, error) {
+	return &Response{Res: obj, Code: http.StatusOK}, nil
+}
+
+var _ = Describe("Legacy attributes", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.AddResource(Label{}, LabelResource{})
+		rec = httptest.NewRecorder()
+	})
+
+	It("removes the reserved members by default", func() {
+		req, err := http.NewRequest("GET", "/v1/labels/1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"attributes":{"text":"Hot"}`))
+	})
+
+	It("keeps the reserved members if enabled for the api", func() {
+		api.SetLegacyAttributes(true)
+		req, err := http.NewRequest("GET", "/v1/labels/1?fields[labels]=type", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"attributes":{"type":"warning"}`))
+	})
+
+	It("accepts the reserved members in requests if enabled for the api", func() {
+		api.SetLegacyAttributes(true)
+		body := `{"data": {"type": "labels", "id": "2", "attributes": {"id": "2", "type": "info", "text": "Cold"}}}`
+		req, err := http.NewRequest("POST", "/v1/labels", strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(rec.Body.String()).To(ContainSubstring(`"attributes":{"id":"2","type":"info","text":"Cold"}`))
+	})
+
+	It("does not change other apis", func() {
+		legacy := NewAPI("v1")
+		legacy.AddResource(Label{}, LabelResource{})
+		legacy.SetLegacyAttributes(true)
+
+		body := `{"data": {"type": "labels", "attributes": {"type": "info", "text": "Cold"}}}`
+		req, err := http.NewRequest("POST", "/v1/labels", strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNotAcceptable))
+	})
+})
@@ -0,0 +1,112 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type PaginatedProjectResource struct {
+	ProjectResource
+	members []string
+}
+
+func (s PaginatedProjectResource) PaginatedFindRelationship(id, name string, req Request) (uint, []jsonapi.ReferenceID, error) {
+	if id == "404" {
+		return 0, nil, NewHTTPError(nil, "not found", http.StatusNotFound)
+	}
+
+	var start, end int
+	if number, ok := req.QueryParams["page[number]"]; ok {
+		page, _ := strconv.Atoi(number[0])
+		size, _ := strconv.Atoi(req.QueryParams["page[size]"][0])
+		start = (page - 1) * size
+		end = start + size
+	} else {
+		start, _ = strconv.Atoi(req.QueryParams["page[offset]"][0])
+		limit, _ := strconv.Atoi(req.QueryParams["page[limit]"][0])
+		end = start + limit
+	}
+
+	if end > len(s.members) {
+		end = len(s.members)
+	}
+
+	result := []jsonapi.ReferenceID{}
+	for _, ID := range s.members[start:end] {
+		result = append(result, jsonapi.ReferenceID{ID: ID, Type: "users", Name: name})
+	}
+
+	return uint(len(s.members)), result, nil
+}
+
+var _ = Describe("Paginated relationships", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPIWithBaseURL("v1", "http://localhost")
+		api.AddResource(Project{}, PaginatedProjectResource{members: []string{"1", "2", "3", "4", "5"}})
+		rec = httptest.NewRecorder()
+	})
+
+	get := func(url string) {
+		req, err := http.NewRequest("GET", url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("returns a page with number and size", func() {
+		get("/v1/projects/1/relationships/members?page[number]=2&page[size]=2")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"links": {
+				"self": "http://localhost/v1/projects/1/relationships/members",
+				"related": "http://localhost/v1/projects/1/members",
+				"first": "http://localhost/v1/projects/1/relationships/members?page[number]=1&page[size]=2",
+				"prev": "http://localhost/v1/projects/1/relationships/members?page[number]=1&page[size]=2",
+				"next": "http://localhost/v1/projects/1/relationships/members?page[number]=3&page[size]=2",
+				"last": "http://localhost/v1/projects/1/relationships/members?page[number]=3&page[size]=2"
+			},
+			"data": [{"type": "users", "id": "3"}, {"type": "users", "id": "4"}]
+		}`))
+	})
+
+	It("returns a page with offset and limit", func() {
+		get("/v1/projects/1/relationships/members?page[offset]=3&page[limit]=2")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"links": {
+				"self": "http://localhost/v1/projects/1/relationships/members",
+				"related": "http://localhost/v1/projects/1/members",
+				"first": "http://localhost/v1/projects/1/relationships/members?page[limit]=2&page[offset]=0",
+				"prev": "http://localhost/v1/projects/1/relationships/members?page[limit]=2&page[offset]=1"
+			},
+			"data": [{"type": "users", "id": "4"}, {"type": "users", "id": "5"}]
+		}`))
+	})
+
+	It("returns the full relationship without pagination query params", func() {
+		get("/v1/projects/1/relationships/members")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"links": {
+				"self": "http://localhost/v1/projects/1/relationships/members",
+				"related": "http://localhost/v1/projects/1/members?page[number]=1"
+			},
+			"data": [{"type": "users", "id": "1"}, {"type": "users", "id": "2"}],
+			"meta": {"author": "Marvin", "count": 2}
+		}`))
+	})
+
+	It("returns errors of PaginatedFindRelationship", func() {
+		get("/v1/projects/404/relationships/members?page[number]=1&page[size]=2")
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,108 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Feed struct {
+	ID      string                `json:"-"`
+	Pinned  jsonapi.ReferenceID   `json:"-"`
+	Entries []jsonapi.ReferenceID `json:"-"`
+}
+
+func (f Feed) GetID() string {
+	return f.ID
+}
+
+func (f *Feed) SetID(ID string) error {
+	f.ID = ID
+	return nil
+}
+
+func (f Feed) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{Name: "pinned", Relationship: jsonapi.ToOneRelationship},
+		{Name: "entries", Relationship: jsonapi.ToManyRelationship},
+	}
+}
+
+func (f Feed) GetReferencedIDs() []jsonapi.ReferenceID {
+	result := []jsonapi.ReferenceID{}
+	if f.Pinned.ID != "" {
+		result = append(result, f.Pinned)
+	}
+
+	return append(result, f.Entries...)
+}
+
+func (f *Feed) SetToOneReferenceIDWithType(name string, ID jsonapi.ReferenceID) error {
+	f.Pinned = ID
+	return nil
+}
+
+func (f *Feed) SetToManyReferenceIDsWithType(name string, IDs []jsonapi.ReferenceID) error {
+	f.Entries = IDs
+	return nil
+}
+
+type FeedResource struct {
+	updated *Feed
+}
+
+func (s *FeedResource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: Feed{ID: ID}}, nil
+}
+
+func (s *FeedResource) Update(obj interface{}, req Request) (Responder, error) {
+	feed := obj.(Feed)
+	s.updated = &feed
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+var _ = Describe("Polymorphic relationship routes", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *FeedResource
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		source = &FeedResource{}
+		api.AddResource(Feed{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("passes the types of to-many relationships", func() {
+		doRequest("PATCH", "/v1/feeds/1/relationships/entries", `{"data": [{"type": "comments", "id": "1"}, {"type": "reviews", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.updated.Entries).To(Equal([]jsonapi.ReferenceID{
+			{ID: "1", Type: "comments", Name: "entries", Relationship: jsonapi.ToManyRelationship},
+			{ID: "1", Type: "reviews", Name: "entries", Relationship: jsonapi.ToManyRelationship},
+		}))
+	})
+
+	It("passes the type of to-one relationships", func() {
+		doRequest("PATCH", "/v1/feeds/1/relationships/pinned", `{"data": {"type": "reviews", "id": "2"}}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.updated.Pinned).To(Equal(jsonapi.ReferenceID{ID: "2", Type: "reviews", Name: "pinned", Relationship: jsonapi.ToOneRelationship}))
+	})
+
+	It("clears to-one relationships", func() {
+		doRequest("PATCH", "/v1/feeds/1/relationships/pinned", `{"data": null}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.updated.Pinned).To(Equal(jsonapi.ReferenceID{Name: "pinned", Relationship: jsonapi.ToOneRelationship}))
+	})
+})
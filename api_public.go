@@ -21,6 +21,12 @@ type API struct {
 	middlewares      []HandlerFunc
 	contextPool      sync.Pool
 	contextAllocator APIContextAllocatorFunc
+	extensions       []string
+	profiles         []string
+	jsonapiObject    *jsonapi.JSONAPI
+	describedBy      *jsonapi.Link
+	checkReferences  bool
+	options          jsonapi.Options
 }
 
 // Handler returns the http.Handler instance for the API.
@@ -42,8 +48,64 @@ func (api *API) SetContextAllocator(allocator APIContextAllocatorFunc) {
 // At least the CRUD interface must be implemented, all the other interfaces are optional.
 // `resource` should be either an empty struct instance such as `Post{}` or a pointer to
 // a struct such as `&Post{}`. The same type will be used for constructing new elements.
-func (api *API) AddResource(prototype jsonapi.MarshalIdentifier, source interface{}) {
-	api.addResource(prototype, source)
+// The struct must implement jsonapi.MarshalIdentifier or use `jsonapi` struct tags, see jsonapi.Adapt.
+func (api *API) AddResource(prototype interface{}, source interface{}) {
+	api.addResource(prototype, source, nil)
+}
+
+// RegisterExtension registers the URIs of JSON API extensions that are supported by the API.
+// Requests that use any other extension in the `ext` media type parameter are rejected with
+// 415 Unsupported Media Type or 406 Not Acceptable. Applied extensions are added to the
+// Content-Type header of the response.
+func (api *API) RegisterExtension(uris ...string) {
+	api.extensions = append(api.extensions, uris...)
+}
+
+// RegisterProfile registers the URIs of JSON API profiles that are supported by the API.
+// Requested profiles that are registered are applied: they are listed in the `profile`
+// parameter of the Content-Type header and in the top-level `links.profile` member of
+// the response. Use Request.HasProfile to check for them in your resources.
+// Requested profiles that are not registered are ignored.
+func (api *API) RegisterProfile(uris ...string) {
+	api.profiles = append(api.profiles, uris...)
+}
+
+// SetJSONAPIObject sets the top-level `jsonapi` object that is added to every document,
+// e.g. to advertise the supported version of the specification. Applied extensions and
+// profiles are added to its `ext` and `profile` members.
+func (api *API) SetJSONAPIObject(object jsonapi.JSONAPI) {
+	api.jsonapiObject = &object
+}
+
+// SetDescribedBy sets a link to a description document like a JSON Schema or an OpenAPI
+// specification. It is added as `describedby` to the top-level links of every document.
+func (api *API) SetDescribedBy(link jsonapi.Link) {
+	api.describedBy = &link
+}
+
+// EnableReferentialIntegrity enables checks of the relationships in create, update and relationship
+// requests. The type of every referenced resource must be registered and match the type of the
+// reference, every relationship must be returned by GetReferences and the referenced resource must
+// exist in its registered resource, otherwise the request is rejected with 422 or 404 before the
+// resource is called. Resources that do not implement ExistenceChecker must report a missing id in
+// FindOne with an HTTPError with status 404, all other errors are returned as they are.
+func (api *API) EnableReferentialIntegrity() {
+	api.checkReferences = true
+}
+
+// SetJSONCodec replaces the JSONCodec that is used for the documents and the attributes of the resources
+// of this API, see jsonapi.Options. Passing nil restores the codec of the jsonapi package, which can be set
+// with jsonapi.SetJSONCodec and is still used by the MarshalJSON methods of the jsonapi structs, e.g. for
+// the relationships of a record.
+func (api *API) SetJSONCodec(codec jsonapi.JSONCodec) {
+	api.options.Codec = codec
+}
+
+// SetLegacyAttributes restores the legacy behavior for the attributes of all resources of this API, see
+// jsonapi.Options. The json encoding of the structs is used unchanged, so members named `id`, `type`, the member
+// of the ID field and members with the name of a relationship are written and accepted as attributes.
+func (api *API) SetLegacyAttributes(legacy bool) {
+	api.options.LegacyAttributes = legacy
 }
 
 // UseMiddleware registers middlewares that implement the api2go.HandlerFunc
@@ -0,0 +1,108 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Project struct {
+	ID        string   `json:"-"`
+	Name      string   `json:"name"`
+	MemberIDs []string `json:"-"`
+}
+
+func (p Project) GetID() string {
+	return p.ID
+}
+
+func (p *Project) SetID(ID string) error {
+	p.ID = ID
+	return nil
+}
+
+func (p Project) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{{Type: "users", Name: "members"}}
+}
+
+func (p Project) GetReferencedIDs() []jsonapi.ReferenceID {
+	result := []jsonapi.ReferenceID{}
+	for _, ID := range p.MemberIDs {
+		result = append(result, jsonapi.ReferenceID{ID: ID, Type: "users", Name: "members"})
+	}
+
+	return result
+}
+
+func (p Project) GetRelationshipMeta(name string) map[string]interface{} {
+	return map[string]interface{}{"count": len(p.MemberIDs)}
+}
+
+func (p Project) GetRelationshipLinks(name, base string) jsonapi.Links {
+	return jsonapi.Links{"related": jsonapi.Link{Href: base + "/" + name + "?page[number]=1"}}
+}
+
+type ProjectResource struct{}
+
+func (s ProjectResource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{
+		Res:  Project{ID: ID, Name: "api2go", MemberIDs: []string{"1", "2"}},
+		Meta: map[string]interface{}{"author": "Marvin", "count": 99},
+	}, nil
+}
+
+var _ = Describe("Relationship meta and links", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPIWithBaseURL("v1", "http://localhost")
+		api.AddResource(Project{}, ProjectResource{})
+		rec = httptest.NewRecorder()
+	})
+
+	It("adds relationship meta and custom links to resources", func() {
+		req, err := http.NewRequest("GET", "/v1/projects/1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"meta": {"author": "Marvin", "count": 99},
+			"data": {
+				"type": "projects",
+				"id": "1",
+				"attributes": {"name": "api2go"},
+				"relationships": {
+					"members": {
+						"links": {
+							"self": "http://localhost/v1/projects/1/relationships/members",
+							"related": "http://localhost/v1/projects/1/members?page[number]=1"
+						},
+						"data": [{"type": "users", "id": "1"}, {"type": "users", "id": "2"}],
+						"meta": {"count": 2}
+					}
+				}
+			}
+		}`))
+	})
+
+	It("does not overwrite the relationship meta with the response metadata", func() {
+		req, err := http.NewRequest("GET", "/v1/projects/1/relationships/members", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"links": {
+				"self": "http://localhost/v1/projects/1/relationships/members",
+				"related": "http://localhost/v1/projects/1/members?page[number]=1"
+			},
+			"data": [{"type": "users", "id": "1"}, {"type": "users", "id": "2"}],
+			"meta": {"author": "Marvin", "count": 2}
+		}`))
+	})
+})
@@ -0,0 +1,373 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Board struct {
+	ID        string   `json:"-"`
+	Name      string   `json:"name"`
+	OwnerID   string   `json:"-"`
+	MemberIDs []string `json:"-"`
+}
+
+func (b Board) GetID() string {
+	return b.ID
+}
+
+func (b *Board) SetID(ID string) error {
+	b.ID = ID
+	return nil
+}
+
+func (b Board) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{Type: "users", Name: "owner"},
+		{Type: "users", Name: "members"},
+	}
+}
+
+func (b Board) GetReferencedIDs() []jsonapi.ReferenceID {
+	result := []jsonapi.ReferenceID{}
+	if b.OwnerID != "" {
+		result = append(result, jsonapi.ReferenceID{ID: b.OwnerID, Type: "users", Name: "owner"})
+	}
+
+	for _, ID := range b.MemberIDs {
+		result = append(result, jsonapi.ReferenceID{ID: ID, Type: "users", Name: "members"})
+	}
+
+	return result
+}
+
+type BoardResource struct {
+	board Board
+	calls []string
+}
+
+func (s *BoardResource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: s.board}, nil
+}
+
+// identifiers formats identifiers as type/id
+func identifiers(ids []jsonapi.ReferenceID) string {
+	formatted := []string{}
+	for _, id := range ids {
+		formatted = append(formatted, id.Type+"/"+id.ID)
+	}
+
+	return strings.Join(formatted, ",")
+}
+
+func plainIDs(ids []jsonapi.ReferenceID) []string {
+	result := []string{}
+	for _, id := range ids {
+		result = append(result, id.ID)
+	}
+
+	return result
+}
+
+func (s *BoardResource) respond(ids []string) (Responder, error) {
+	if len(ids) > 0 {
+		switch ids[0] {
+		case "delayed":
+			return &Response{Code: http.StatusAccepted}, nil
+		case "unchanged":
+			return &Response{Code: http.StatusNoContent}, nil
+		case "reload":
+			return &Response{Code: http.StatusOK}, nil
+		case "fail":
+			return &Response{}, NewHTTPError(nil, "Fail", http.StatusForbidden)
+		case "invalid":
+			return &Response{Code: http.StatusTeapot}, nil
+		}
+	}
+
+	return &Response{Res: s.board, Code: http.StatusOK, Meta: map[string]interface{}{"count": len(s.board.MemberIDs)}}, nil
+}
+
+func (s *BoardResource) ReplaceRelationship(id, name string, ids []jsonapi.ReferenceID, req Request) (Responder, error) {
+	s.calls = append(s.calls, "replace "+id+" "+name+" "+identifiers(ids))
+	if name == "owner" {
+		s.board.OwnerID = strings.Join(plainIDs(ids), "")
+	} else {
+		s.board.MemberIDs = plainIDs(ids)
+	}
+
+	return s.respond(plainIDs(ids))
+}
+
+func (s *BoardResource) AddToRelationship(id, name string, ids []jsonapi.ReferenceID, req Request) (Responder, error) {
+	s.calls = append(s.calls, "add "+id+" "+name+" "+identifiers(ids))
+	s.board.MemberIDs = append(s.board.MemberIDs, plainIDs(ids)...)
+	return s.respond(plainIDs(ids))
+}
+
+func (s *BoardResource) RemoveFromRelationship(id, name string, ids []jsonapi.ReferenceID, req Request) (Responder, error) {
+	s.calls = append(s.calls, "remove "+id+" "+name+" "+identifiers(ids))
+	members := []string{}
+	for _, member := range s.board.MemberIDs {
+		if !containsString(plainIDs(ids), member) {
+			members = append(members, member)
+		}
+	}
+	s.board.MemberIDs = members
+
+	return s.respond(plainIDs(ids))
+}
+
+type failingUpdateResource struct{}
+
+func (s failingUpdateResource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: Post{ID: ID}}, nil
+}
+
+func (s failingUpdateResource) Update(obj interface{}, req Request) (Responder, error) {
+	return &Response{}, NewHTTPError(nil, "Fail", http.StatusForbidden)
+}
+
+// statusUpdateResource returns the updated object with the configured status code
+type statusUpdateResource struct {
+	code int
+}
+
+func (s statusUpdateResource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: Post{ID: ID, Comments: []Comment{{ID: "1"}}}}, nil
+}
+
+func (s statusUpdateResource) Update(obj interface{}, req Request) (Responder, error) {
+	if s.code == http.StatusOK {
+		return &Response{Res: obj, Code: s.code}, nil
+	}
+
+	return &Response{Code: s.code}, nil
+}
+
+// Gallery has a polymorphic to-many relationship to photos and videos
+type Gallery struct {
+	ID    string                `json:"-"`
+	Items []jsonapi.ReferenceID `json:"-"`
+}
+
+func (g Gallery) GetID() string {
+	return g.ID
+}
+
+func (g *Gallery) SetID(ID string) error {
+	g.ID = ID
+	return nil
+}
+
+func (g Gallery) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{{Name: "items"}}
+}
+
+func (g Gallery) GetReferencedIDs() []jsonapi.ReferenceID {
+	return g.Items
+}
+
+func (g *Gallery) SetToManyReferenceIDsWithType(name string, IDs []jsonapi.ReferenceID) error {
+	g.Items = IDs
+	return nil
+}
+
+func (g *Gallery) AddToManyIDsWithType(name string, IDs []jsonapi.ReferenceID) error {
+	g.Items = append(g.Items, IDs...)
+	return nil
+}
+
+func (g *Gallery) DeleteToManyIDsWithType(name string, IDs []jsonapi.ReferenceID) error {
+	obsolete := map[string]bool{}
+	for _, ID := range IDs {
+		obsolete[ID.Type+"/"+ID.ID] = true
+	}
+
+	items := []jsonapi.ReferenceID{}
+	for _, item := range g.Items {
+		if !obsolete[item.Type+"/"+item.ID] {
+			items = append(items, item)
+		}
+	}
+	g.Items = items
+
+	return nil
+}
+
+type galleryResource struct {
+	gallery Gallery
+}
+
+func (s *galleryResource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: s.gallery}, nil
+}
+
+func (s *galleryResource) Update(obj interface{}, req Request) (Responder, error) {
+	s.gallery = obj.(Gallery)
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+var _ = Describe("Relationship mutation interfaces", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *BoardResource
+	)
+
+	BeforeEach(func() {
+		api = NewAPIWithBaseURL("v1", "http://localhost")
+		source = &BoardResource{board: Board{ID: "1", Name: "Kanban", OwnerID: "1", MemberIDs: []string{"1", "2"}}}
+		api.AddResource(Board{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("replaces to-many relationships and returns the updated linkage and meta", func() {
+		doRequest("PATCH", "/v1/boards/1/relationships/members", `{"data": [{"type": "users", "id": "3"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.calls).To(Equal([]string{"replace 1 members users/3"}))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"links": {
+				"self": "http://localhost/v1/boards/1/relationships/members",
+				"related": "http://localhost/v1/boards/1/members"
+			},
+			"data": [{"type": "users", "id": "3"}],
+			"meta": {"count": 1}
+		}`))
+	})
+
+	It("replaces to-one relationships", func() {
+		doRequest("PATCH", "/v1/boards/1/relationships/owner", `{"data": {"type": "users", "id": "2"}}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.calls).To(Equal([]string{"replace 1 owner users/2"}))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"links": {
+				"self": "http://localhost/v1/boards/1/relationships/owner",
+				"related": "http://localhost/v1/boards/1/owner"
+			},
+			"data": {"type": "users", "id": "2"},
+			"meta": {"count": 2}
+		}`))
+	})
+
+	It("clears to-one relationships", func() {
+		doRequest("PATCH", "/v1/boards/1/relationships/owner", `{"data": null}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.calls).To(Equal([]string{"replace 1 owner "}))
+		Expect(source.board.OwnerID).To(BeEmpty())
+	})
+
+	It("rejects arrays for to-one relationships", func() {
+		doRequest("PATCH", "/v1/boards/1/relationships/owner", `{"data": [{"type": "users", "id": "2"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(source.calls).To(BeEmpty())
+	})
+
+	It("adds to to-many relationships", func() {
+		doRequest("POST", "/v1/boards/1/relationships/members", `{"data": [{"type": "users", "id": "3"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.calls).To(Equal([]string{"add 1 members users/3"}))
+		Expect(rec.Body.String()).To(ContainSubstring(`"data":[{"type":"users","id":"1"},{"type":"users","id":"2"},{"type":"users","id":"3"}]`))
+	})
+
+	It("removes from to-many relationships", func() {
+		doRequest("DELETE", "/v1/boards/1/relationships/members", `{"data": [{"type": "users", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.calls).To(Equal([]string{"remove 1 members users/1"}))
+		Expect(rec.Body.String()).To(ContainSubstring(`"data":[{"type":"users","id":"2"}]`))
+	})
+
+	It("returns the relationship of the FindOne result if no resource is returned", func() {
+		doRequest("PATCH", "/v1/boards/1/relationships/members", `{"data": [{"type": "users", "id": "reload"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"data":[{"type":"users","id":"reload"}]`))
+	})
+
+	It("returns 202 Accepted if processing is delayed", func() {
+		doRequest("POST", "/v1/boards/1/relationships/members", `{"data": [{"type": "users", "id": "delayed"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(rec.Body.String()).To(BeEmpty())
+	})
+
+	It("returns 204 No Content", func() {
+		doRequest("DELETE", "/v1/boards/1/relationships/members", `{"data": [{"type": "users", "id": "unchanged"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(rec.Body.String()).To(BeEmpty())
+	})
+
+	It("reports errors of the resource with their status code", func() {
+		doRequest("PATCH", "/v1/boards/1/relationships/members", `{"data": [{"type": "users", "id": "fail"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{"status": "403", "title": "Fail"}]}`))
+	})
+
+	It("does not accept invalid status codes", func() {
+		doRequest("POST", "/v1/boards/1/relationships/members", `{"data": [{"type": "users", "id": "invalid"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rec.Body.String()).To(ContainSubstring("invalid status code 418 from resource boards for method AddToRelationship"))
+	})
+
+	It("reports errors of Update before writing the header", func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, failingUpdateResource{})
+		doRequest("POST", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{"status": "403", "title": "Fail"}]}`))
+	})
+
+	It("responds with the relationship of the object returned by Update", func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, statusUpdateResource{code: http.StatusOK})
+		doRequest("POST", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "2"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"data":[{"type":"comments","id":"1"},{"type":"comments","id":"2"}]`))
+
+		rec = httptest.NewRecorder()
+		doRequest("PATCH", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "3"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"data":[{"type":"comments","id":"3"}]`))
+	})
+
+	It("honours the status codes 202 and 204 of Update", func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, statusUpdateResource{code: http.StatusAccepted})
+		doRequest("DELETE", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+
+		api = NewAPI("v1")
+		api.AddResource(Post{}, statusUpdateResource{code: http.StatusNoContent})
+		rec = httptest.NewRecorder()
+		doRequest("DELETE", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+	})
+
+	It("passes the types of polymorphic identifiers to EditToManyRelationsWithType", func() {
+		source := &galleryResource{gallery: Gallery{ID: "1"}}
+		api = NewAPI("v1")
+		api.AddResource(Gallery{}, source)
+		doRequest("POST", "/v1/galleries/1/relationships/items", `{"data": [{"type": "photos", "id": "1"}, {"type": "videos", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(identifiers(source.gallery.Items)).To(Equal("photos/1,videos/1"))
+
+		rec = httptest.NewRecorder()
+		doRequest("DELETE", "/v1/galleries/1/relationships/items", `{"data": [{"type": "videos", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(identifiers(source.gallery.Items)).To(Equal("photos/1"))
+	})
+
+	It("does not register add and remove routes for to-one relationships", func() {
+		doRequest("POST", "/v1/boards/1/relationships/owner", `{"data": [{"type": "users", "id": "3"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
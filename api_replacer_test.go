@@ -0,0 +1,206 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type ReplacerResource struct {
+	stored   map[string]SomeData
+	replaced interface{}
+}
+
+func (s *ReplacerResource) InitializeObject(obj interface{}) {
+	if data, ok := obj.(*SomeData); ok {
+		data.CustomerID = "initialized"
+	}
+}
+
+func (s *ReplacerResource) FindOne(ID string, req Request) (Responder, error) {
+	if data, ok := s.stored[ID]; ok {
+		return &Response{Res: data}, nil
+	}
+
+	return &Response{}, NewHTTPError(nil, "not found", http.StatusNotFound)
+}
+
+func (s *ReplacerResource) Replace(obj interface{}, req Request) (Responder, error) {
+	s.replaced = obj
+	incoming := obj.(SomeData)
+	switch incoming.Data {
+	case "override me":
+		incoming.Data = "overridden"
+		return &Response{Res: incoming, Code: http.StatusOK}, nil
+	case "reload":
+		s.stored[incoming.ID] = incoming
+		return &Response{Code: http.StatusOK}, nil
+	case "delayed":
+		return &Response{Code: http.StatusAccepted}, nil
+	case "fail":
+		return &Response{}, NewHTTPError(nil, "Fail", http.StatusForbidden)
+	case "invalid":
+		return &Response{Code: http.StatusTeapot}, nil
+	default:
+		s.stored[incoming.ID] = incoming
+		return &Response{Code: http.StatusNoContent}, nil
+	}
+}
+
+var _ = Describe("Test resource implementing the ResourceReplacer interface", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *ReplacerResource
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		source = &ReplacerResource{stored: map[string]SomeData{
+			"12345": {ID: "12345", Data: "A Brezzn", CustomerID: "2"},
+		}}
+		api.AddResource(SomeData{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	put := func(payload SomeData) {
+		m, err := jsonapi.Marshal(payload)
+		Expect(err).ToNot(HaveOccurred())
+		req, err := http.NewRequest("PUT", "/v1/someDatas/12345", strings.NewReader(string(m)))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("replaces the object with a fresh one instead of the FindOne result", func() {
+		req, err := http.NewRequest("PUT", "/v1/someDatas/12345", strings.NewReader(`
+		{
+			"data": {
+				"type": "someDatas",
+				"id": "12345",
+				"attributes": {
+					"data": "new value"
+				}
+			}
+		}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(rec.Body.String()).To(BeEmpty())
+		Expect(source.replaced).To(Equal(SomeData{ID: "12345", Data: "new value", CustomerID: "initialized"}))
+		Expect(source.stored["12345"]).To(Equal(SomeData{ID: "12345", Data: "new value", CustomerID: "initialized"}))
+	})
+
+	It("passes a pointer for pointer resources", func() {
+		api = NewAPI("v1")
+		pointerSource := &pointerReplacerResource{}
+		api.AddResource(&SomeData{}, pointerSource)
+		put(SomeData{ID: "12345", Data: "new value"})
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(pointerSource.replaced).To(Equal(&SomeData{ID: "12345", Data: "new value"}))
+	})
+
+	It("uses the id of the url for documents without id", func() {
+		req, err := http.NewRequest("PUT", "/v1/someDatas/12345", strings.NewReader(`{"data": {"type": "someDatas", "attributes": {"data": "new value"}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.replaced).To(Equal(SomeData{ID: "12345", Data: "new value", CustomerID: "initialized"}))
+	})
+
+	It("returns 409 if the id of the document does not match the url", func() {
+		req, err := http.NewRequest("PUT", "/v1/someDatas/12345", strings.NewReader(`{"data": {"type": "someDatas", "id": "54321", "attributes": {"data": "new value"}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(source.replaced).To(BeNil())
+		Expect(source.stored).ToNot(HaveKey("54321"))
+	})
+
+	It("returns 200 ok with the object returned by Replace", func() {
+		put(SomeData{ID: "12345", Data: "override me"})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var actual SomeData
+		err := jsonapi.Unmarshal(rec.Body.Bytes(), &actual)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(actual).To(Equal(SomeData{ID: "12345", Data: "overridden"}))
+	})
+
+	It("returns 200 ok with the FindOne result if Replace returns no object", func() {
+		put(SomeData{ID: "12345", Data: "reload"})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var actual SomeData
+		err := jsonapi.Unmarshal(rec.Body.Bytes(), &actual)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(actual).To(Equal(SomeData{ID: "12345", Data: "reload"}))
+	})
+
+	It("returns 202 Accepted if replacement is delayed", func() {
+		put(SomeData{ID: "12345", Data: "delayed"})
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(rec.Body.String()).To(BeEmpty())
+	})
+
+	It("does not accept invalid return codes", func() {
+		put(SomeData{ID: "12345", Data: "invalid"})
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		var err HTTPError
+		json.Unmarshal(rec.Body.Bytes(), &err)
+		Expect(err.Errors[0]).To(Equal(Error{
+			Title:  "invalid status code 418 from resource someDatas for method Replace",
+			Status: strconv.Itoa(http.StatusInternalServerError)}))
+	})
+
+	It("handles error cases", func() {
+		put(SomeData{ID: "12345", Data: "fail"})
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		var err HTTPError
+		json.Unmarshal(rec.Body.Bytes(), &err)
+		Expect(err.Errors[0]).To(Equal(Error{Title: "Fail", Status: strconv.Itoa(http.StatusForbidden)}))
+	})
+
+	It("rejects payloads without type", func() {
+		req, err := http.NewRequest("PUT", "/v1/someDatas/12345", strings.NewReader(`{"data": {"id": "12345"}}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNotAcceptable))
+		Expect(source.replaced).To(BeNil())
+	})
+
+	It("lists PUT in the allowed methods of the element route", func() {
+		req, err := http.NewRequest("OPTIONS", "/v1/someDatas/12345", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(strings.Split(rec.Header().Get("Allow"), ",")).To(Equal([]string{
+			"OPTIONS",
+			"GET",
+			"PUT",
+		}))
+	})
+
+	It("does not register PUT for resources without ResourceReplacer", func() {
+		api = NewAPI("v1")
+		api.AddResource(SomeData{}, SomeResource{})
+		put(SomeData{ID: "12345", Data: "new value"})
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
+
+type pointerReplacerResource struct {
+	replaced interface{}
+}
+
+func (s *pointerReplacerResource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: &SomeData{ID: ID}}, nil
+}
+
+func (s *pointerReplacerResource) Replace(obj interface{}, req Request) (Responder, error) {
+	s.replaced = obj
+	return &Response{Code: http.StatusNoContent}, nil
+}
@@ -0,0 +1,187 @@
+package api2go
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Measurement struct {
+	ID    string `json:"-"`
+	Value int    `json:"value"`
+	Unit  string `json:"unit"`
+}
+
+func (m Measurement) GetID() string {
+	return m.ID
+}
+
+type measurementStream struct {
+	count  int
+	failAt int
+}
+
+func (s measurementStream) Metadata() map[string]interface{} {
+	return map[string]interface{}{"total": s.count}
+}
+
+func (s measurementStream) Result() interface{} {
+	return nil
+}
+
+func (s measurementStream) StatusCode() int {
+	return http.StatusOK
+}
+
+func (s measurementStream) Records() jsonapi.Iterator {
+	index := 0
+	return jsonapi.IteratorFunc(func() (jsonapi.MarshalIdentifier, error) {
+		index++
+		if index == s.failAt {
+			return nil, errors.New("cursor closed")
+		}
+
+		if index > s.count {
+			return nil, nil
+		}
+
+		return Measurement{ID: strconv.Itoa(index), Value: index * 10, Unit: "cm"}, nil
+	})
+}
+
+type measurementResource struct {
+	stream measurementStream
+}
+
+func (s measurementResource) FindAll(req Request) (Responder, error) {
+	return s.stream, nil
+}
+
+func (s measurementResource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: Measurement{ID: ID}}, nil
+}
+
+// channelMeasurementResource streams endless measurements from a producer goroutine
+type channelMeasurementResource struct {
+	done chan struct{}
+}
+
+func (s channelMeasurementResource) FindAll(req Request) (Responder, error) {
+	records := make(chan jsonapi.MarshalIdentifier)
+	go func() {
+		defer close(records)
+		for index := 1; ; index++ {
+			select {
+			case records <- Measurement{ID: strconv.Itoa(index), Value: index, Unit: "cm"}:
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	return channelStream{jsonapi.ChannelIterator(records, s.done)}, nil
+}
+
+func (s channelMeasurementResource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: Measurement{ID: ID}}, nil
+}
+
+type channelStream struct {
+	records jsonapi.Iterator
+}
+
+func (s channelStream) Metadata() map[string]interface{} {
+	return nil
+}
+
+func (s channelStream) Result() interface{} {
+	return nil
+}
+
+func (s channelStream) StatusCode() int {
+	return http.StatusOK
+}
+
+func (s channelStream) Records() jsonapi.Iterator {
+	return s.records
+}
+
+var _ = Describe("Streaming responses", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	get := func(stream measurementStream, url string) {
+		api = NewAPI("v1")
+		api.AddResource(Measurement{}, measurementResource{stream: stream})
+		rec = httptest.NewRecorder()
+		req, err := http.NewRequest("GET", url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("streams the records of a StreamResponder", func() {
+		get(measurementStream{count: 2}, "/v1/measurements")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(defaultContentTypHeader))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"meta": {"total": 2},
+			"data": [
+				{"type": "measurements", "id": "1", "attributes": {"value": 10, "unit": "cm"}},
+				{"type": "measurements", "id": "2", "attributes": {"value": 20, "unit": "cm"}}
+			]
+		}`))
+	})
+
+	It("applies sparse fieldsets", func() {
+		get(measurementStream{count: 1}, "/v1/measurements?fields[measurements]=unit")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"meta": {"total": 1},
+			"data": [{"type": "measurements", "id": "1", "attributes": {"unit": "cm"}}]
+		}`))
+	})
+
+	It("rejects invalid fields of the first record before streaming", func() {
+		get(measurementStream{count: 1}, "/v1/measurements?fields[measurements]=color")
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`Field \"color\" does not exist for type \"measurements\"`))
+	})
+
+	It("rejects include because included resources cannot be streamed", func() {
+		get(measurementStream{count: 1}, "/v1/measurements?include=sensor")
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{"status": "400", "title": "include is not supported for streamed responses"}]}`))
+	})
+
+	It("responds with an error document if the first record cannot be read", func() {
+		get(measurementStream{count: 5, failAt: 1}, "/v1/measurements")
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{"status": "500", "title": "cursor closed"}]}`))
+	})
+
+	It("closes channel iterators so that the producer stops", func() {
+		done := make(chan struct{})
+		api = NewAPI("v1")
+		api.AddResource(Measurement{}, channelMeasurementResource{done: done})
+		rec = httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/v1/measurements?fields[measurements]=color", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("ends the response if the iterator fails", func() {
+		get(measurementStream{count: 5, failAt: 2}, "/v1/measurements")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(HavePrefix(`{"meta":{"total":5},"data":[{"type":"measurements","id":"1"`))
+		Expect(rec.Body.String()).ToNot(HaveSuffix("]}"))
+	})
+})
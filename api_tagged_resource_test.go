@@ -0,0 +1,144 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Member struct {
+	ID      int      `jsonapi:"primary,members"`
+	Name    string   `jsonapi:"attr,name"`
+	TeamIDs []string `jsonapi:"relation,teams,teams"`
+}
+
+type Untagged struct {
+	ID string
+}
+
+type InvalidlyTagged struct {
+	ID   string `jsonapi:"primary,invalids"`
+	Name string `jsonapi:"atr,name"`
+}
+
+type memberSource struct {
+	members map[int]Member
+	nextID  int
+}
+
+func (s *memberSource) FindAll(req Request) (Responder, error) {
+	members := []Member{}
+	for _, member := range s.members {
+		members = append(members, member)
+	}
+
+	return &Response{Res: members}, nil
+}
+
+func (s *memberSource) FindOne(ID string, req Request) (Responder, error) {
+	for _, member := range s.members {
+		if ID == strconv.Itoa(member.ID) {
+			return &Response{Res: member}, nil
+		}
+	}
+
+	return nil, NewHTTPError(nil, "member not found", http.StatusNotFound)
+}
+
+func (s *memberSource) Create(obj interface{}, req Request) (Responder, error) {
+	member := obj.(Member)
+	s.nextID++
+	member.ID = s.nextID
+	s.members[member.ID] = member
+	return &Response{Res: member, Code: http.StatusCreated}, nil
+}
+
+func (s *memberSource) Delete(ID string, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *memberSource) Update(obj interface{}, req Request) (Responder, error) {
+	member := obj.(Member)
+	s.members[member.ID] = member
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+var _ = Describe("Resources with struct tags", func() {
+	var (
+		api    *API
+		source *memberSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		source = &memberSource{members: map[int]Member{
+			1: {ID: 1, Name: "Marvin", TeamIDs: []string{"1"}},
+		}, nextID: 1}
+		api.AddResource(Member{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	request := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("marshals the resource", func() {
+		request("GET", "/v1/members/1", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"data": {
+				"type": "members",
+				"id": "1",
+				"attributes": {"name": "Marvin"},
+				"relationships": {
+					"teams": {
+						"links": {
+							"self": "/v1/members/1/relationships/teams",
+							"related": "/v1/members/1/teams"
+						},
+						"data": [{"type": "teams", "id": "1"}]
+					}
+				}
+			}
+		}`))
+	})
+
+	It("creates the resource", func() {
+		request("POST", "/v1/members", `{"data": {"type": "members", "attributes": {"name": "Arthur"}, "relationships": {"teams": {"data": [{"type": "teams", "id": "2"}]}}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(rec.Header().Get("Location")).To(Equal("/v1/members/2"))
+		Expect(source.members[2]).To(Equal(Member{ID: 2, Name: "Arthur", TeamIDs: []string{"2"}}))
+	})
+
+	It("replaces to-many relationships", func() {
+		request("PATCH", "/v1/members/1/relationships/teams", `{"data": [{"type": "teams", "id": "3"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.members[1].TeamIDs).To(Equal([]string{"3"}))
+	})
+
+	It("adds to and removes from to-many relationships", func() {
+		request("POST", "/v1/members/1/relationships/teams", `{"data": [{"type": "teams", "id": "2"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.members[1].TeamIDs).To(Equal([]string{"1", "2"}))
+
+		rec = httptest.NewRecorder()
+		request("DELETE", "/v1/members/1/relationships/teams", `{"data": [{"type": "teams", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.members[1].TeamIDs).To(Equal([]string{"2"}))
+	})
+
+	It("panics for structs without interfaces or tags", func() {
+		Expect(func() { api.AddResource(Untagged{}, source) }).To(Panic())
+	})
+
+	It("panics for structs with invalid tags", func() {
+		Expect(func() { api.AddResource(InvalidlyTagged{}, source) }).To(Panic())
+	})
+})
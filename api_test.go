@@ -390,6 +390,16 @@ func (s *userSource) FindAll(req Request) (Responder, error) {
 }
 
 func (s *userSource) FindOne(id string, req Request) (Responder, error) {
+	if id == "1" {
+		u := User{ID: "1", Name: "Dieter"}
+
+		if s.pointers {
+			return &Response{Res: &u}, nil
+		}
+
+		return &Response{Res: u}, nil
+	}
+
 	return &Response{}, nil
 }
 
@@ -410,9 +420,8 @@ type commentSource struct {
 }
 
 func (s *commentSource) FindAll(req Request) (Responder, error) {
-	postsIDs, ok := req.QueryParams["postsID"]
-	if ok {
-		if postsIDs[0] == "1" {
+	if req.Relation != nil && req.Relation.ParentType == "posts" && req.Relation.Name == "comments" {
+		if req.Relation.ParentID == "1" {
 			c := Comment{
 				ID:    "1",
 				Value: "This is a stupid post!",
@@ -675,6 +684,14 @@ var _ = Describe("RestHandler", func() {
 				}}`))
 		})
 
+		It("GETs null for an empty to-one relationship from resource url", func() {
+			req, err := http.NewRequest("GET", "/v1/posts/2/author", nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"data": null}`))
+		})
+
 		It("GETs related structs from resource url", func() {
 			req, err := http.NewRequest("GET", "/v1/posts/1/comments", nil)
 			Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,134 @@
+//go:build go1.18
+// +build go1.18
+
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type typedMemberSource struct {
+	members map[int]Member
+}
+
+func (s *typedMemberSource) FindOne(ID string, req Request) (Member, error) {
+	intID, _ := strconv.Atoi(ID)
+	member, ok := s.members[intID]
+	if !ok {
+		return Member{}, NewHTTPError(nil, "member not found", http.StatusNotFound)
+	}
+
+	return member, nil
+}
+
+func (s *typedMemberSource) FindAll(req Request) ([]Member, error) {
+	members := []Member{}
+	for _, member := range s.members {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+
+	return members, nil
+}
+
+func (s *typedMemberSource) Create(member Member, req Request) (Member, error) {
+	member.ID = len(s.members) + 1
+	s.members[member.ID] = member
+	return member, nil
+}
+
+func (s *typedMemberSource) Delete(ID string, req Request) error {
+	intID, _ := strconv.Atoi(ID)
+	delete(s.members, intID)
+	return nil
+}
+
+func (s *typedMemberSource) Update(member Member, req Request) (Member, error) {
+	s.members[member.ID] = member
+	return member, nil
+}
+
+var _ = Describe("Typed resources", func() {
+	var (
+		api    *API
+		source *typedMemberSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		source = &typedMemberSource{members: map[int]Member{
+			1: {ID: 1, Name: "Marvin", TeamIDs: []string{"1"}},
+		}}
+		AddTypedResource[Member](api, source)
+		rec = httptest.NewRecorder()
+	})
+
+	request := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("finds one object", func() {
+		request("GET", "/v1/members/1", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"attributes":{"name":"Marvin"}`))
+	})
+
+	It("returns the errors of the typed source", func() {
+		request("GET", "/v1/members/2", "")
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("finds all objects", func() {
+		request("GET", "/v1/members", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"data":[{"type":"members","id":"1"`))
+	})
+
+	It("creates objects", func() {
+		request("POST", "/v1/members", `{"data": {"type": "members", "attributes": {"name": "Arthur"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(rec.Header().Get("Location")).To(Equal("/v1/members/2"))
+		Expect(source.members[2].Name).To(Equal("Arthur"))
+	})
+
+	It("updates objects", func() {
+		request("PATCH", "/v1/members/1", `{"data": {"type": "members", "id": "1", "attributes": {"name": "Ford"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.members[1]).To(Equal(Member{ID: 1, Name: "Ford", TeamIDs: []string{"1"}}))
+	})
+
+	It("edits relationships", func() {
+		request("POST", "/v1/members/1/relationships/teams", `{"data": [{"type": "teams", "id": "2"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"data":[{"type":"teams","id":"1"},{"type":"teams","id":"2"}]`))
+		Expect(source.members[1].TeamIDs).To(Equal([]string{"1", "2"}))
+	})
+
+	It("deletes objects", func() {
+		request("DELETE", "/v1/members/1", "")
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.members).To(BeEmpty())
+	})
+
+	It("only registers the implemented interfaces", func() {
+		untyped := newTypedSource[Member](source)
+		_, findAll := untyped.(FindAll)
+		_, paginated := untyped.(PaginatedFindAll)
+		Expect(findAll).To(BeTrue())
+		Expect(paginated).To(BeFalse())
+	})
+
+	It("panics for pointer types", func() {
+		Expect(func() { AddTypedResource[*Member](api, nil) }).To(Panic())
+	})
+})
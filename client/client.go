@@ -0,0 +1,339 @@
+// Package client provides a client to consume JSON API services, e.g. servers
+// built with api2go. It uses the jsonapi package to marshal and unmarshal the
+// structs of your application.
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+const mediaType = "application/vnd.api+json"
+
+// ErrNoNextPage is returned by Next if the response has no next link
+var ErrNoNextPage = errors.New("response has no next link")
+
+// Client sends requests to a JSON API service
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	codec      jsonapi.JSONCodec
+
+	// Header is added to all requests, e.g. for authentication
+	Header http.Header
+}
+
+// New returns a client for the JSON API service at baseURL. The baseURL
+// contains the prefix of the api, e.g. http://localhost:31415/v0
+func New(baseURL string) *Client {
+	return NewWithHTTPClient(baseURL, http.DefaultClient)
+}
+
+// NewWithHTTPClient returns a client that sends all requests with httpClient
+func NewWithHTTPClient(baseURL string, httpClient *http.Client) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+		Header:     http.Header{},
+	}
+}
+
+// SetJSONCodec replaces the JSONCodec that is used to encode request documents
+// and to decode response and error documents of this client. Passing nil
+// restores the codec of the jsonapi package, which is also used to (un)marshal
+// the resources and can be set with jsonapi.SetJSONCodec.
+func (c *Client) SetJSONCodec(codec jsonapi.JSONCodec) {
+	c.codec = codec
+}
+
+// jsonCodec returns the JSONCodec of the client or the one of the jsonapi
+// package if none is set
+func (c *Client) jsonCodec() jsonapi.JSONCodec {
+	if c.codec != nil {
+		return c.codec
+	}
+
+	return jsonapi.GetJSONCodec()
+}
+
+// Response is the response of the JSON API service. Document is nil if the
+// response has no body, e.g. for 204 No Content.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Document   *jsonapi.Document
+
+	body []byte
+}
+
+// NextURL returns the next link of the response document
+func (r *Response) NextURL() (string, bool) {
+	if r == nil || r.Document == nil {
+		return "", false
+	}
+
+	next, ok := r.Document.Links["next"]
+	if !ok || next.Href == "" {
+		return "", false
+	}
+
+	return next.Href, true
+}
+
+// Get fetches the resource with the given id and unmarshals it into target
+func (c *Client) Get(ctx context.Context, resourceType, id string, params *Params, target interface{}) (*Response, error) {
+	return c.fetch(ctx, c.resourceURL(params, resourceType, id), target)
+}
+
+// List fetches a collection and unmarshals it into target, which must be a
+// pointer to a slice
+func (c *Client) List(ctx context.Context, resourceType string, params *Params, target interface{}) (*Response, error) {
+	return c.fetch(ctx, c.resourceURL(params, resourceType), target)
+}
+
+// GetRelated fetches the related resources of a relationship and unmarshals
+// them into target
+func (c *Client) GetRelated(ctx context.Context, resourceType, id, name string, params *Params, target interface{}) (*Response, error) {
+	return c.fetch(ctx, c.resourceURL(params, resourceType, id, name), target)
+}
+
+// Follow fetches an absolute or relative link, e.g. a pagination link of a
+// previous response, and unmarshals the data into target
+func (c *Client) Follow(ctx context.Context, link string, target interface{}) (*Response, error) {
+	resolved, err := c.resolve(link)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.fetch(ctx, resolved, target)
+}
+
+// Next fetches the next page of a previous response and unmarshals the data
+// into target. It returns ErrNoNextPage if there is no next link.
+func (c *Client) Next(ctx context.Context, previous *Response, target interface{}) (*Response, error) {
+	next, ok := previous.NextURL()
+	if !ok {
+		return nil, ErrNoNextPage
+	}
+
+	return c.Follow(ctx, next, target)
+}
+
+// Create posts obj to the collection of its type. The resource returned by the
+// server, e.g. with a generated id, is unmarshalled into obj, so obj must be a
+// pointer.
+func (c *Client) Create(ctx context.Context, obj interface{}) (*Response, error) {
+	body, data, err := c.marshalResource(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.do(ctx, "POST", c.resourceURL(nil, data.Type), body)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, unmarshalResponse(response, obj)
+}
+
+// Update patches the resource of obj. If the server returns the updated
+// resource, it is unmarshalled into obj.
+func (c *Client) Update(ctx context.Context, obj interface{}) (*Response, error) {
+	body, data, err := c.marshalResource(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.do(ctx, "PATCH", c.resourceURL(nil, data.Type, data.ID), body)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, unmarshalResponse(response, obj)
+}
+
+// Delete deletes the resource with the given id
+func (c *Client) Delete(ctx context.Context, resourceType, id string) (*Response, error) {
+	return c.do(ctx, "DELETE", c.resourceURL(nil, resourceType, id), nil)
+}
+
+// GetRelationship fetches the resource identifiers of a relationship. The
+// DataObject of the container is nil for empty to-one relationships.
+func (c *Client) GetRelationship(ctx context.Context, resourceType, id, name string) (*jsonapi.RelationshipDataContainer, *Response, error) {
+	response, err := c.do(ctx, "GET", c.relationshipURL(resourceType, id, name), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var document struct {
+		Data *jsonapi.RelationshipDataContainer `json:"data"`
+	}
+	if err := c.jsonCodec().Unmarshal(response.body, &document); err != nil {
+		return nil, response, err
+	}
+
+	if document.Data == nil {
+		document.Data = &jsonapi.RelationshipDataContainer{}
+	}
+
+	return document.Data, response, nil
+}
+
+// ReplaceToOneRelationship replaces a to-one relationship, a nil identifier
+// clears it
+func (c *Client) ReplaceToOneRelationship(ctx context.Context, resourceType, id, name string, identifier *jsonapi.RelationshipData) (*Response, error) {
+	return c.sendRelationship(ctx, "PATCH", resourceType, id, name, identifier)
+}
+
+// ReplaceToManyRelationship replaces all members of a to-many relationship
+func (c *Client) ReplaceToManyRelationship(ctx context.Context, resourceType, id, name string, identifiers []jsonapi.RelationshipData) (*Response, error) {
+	return c.sendRelationship(ctx, "PATCH", resourceType, id, name, nonNilIdentifiers(identifiers))
+}
+
+// AddToManyRelationship adds members to a to-many relationship
+func (c *Client) AddToManyRelationship(ctx context.Context, resourceType, id, name string, identifiers []jsonapi.RelationshipData) (*Response, error) {
+	return c.sendRelationship(ctx, "POST", resourceType, id, name, nonNilIdentifiers(identifiers))
+}
+
+// RemoveFromManyRelationship removes members from a to-many relationship
+func (c *Client) RemoveFromManyRelationship(ctx context.Context, resourceType, id, name string, identifiers []jsonapi.RelationshipData) (*Response, error) {
+	return c.sendRelationship(ctx, "DELETE", resourceType, id, name, nonNilIdentifiers(identifiers))
+}
+
+func (c *Client) sendRelationship(ctx context.Context, method, resourceType, id, name string, data interface{}) (*Response, error) {
+	body, err := c.jsonCodec().Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(ctx, method, c.relationshipURL(resourceType, id, name), body)
+}
+
+func (c *Client) fetch(ctx context.Context, link string, target interface{}) (*Response, error) {
+	response, err := c.do(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, unmarshalResponse(response, target)
+}
+
+func (c *Client) do(ctx context.Context, method, link string, body []byte) (*Response, error) {
+	req, err := http.NewRequest(method, link, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	for name, values := range c.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", mediaType)
+	if body != nil {
+		req.Header.Set("Content-Type", mediaType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	payload, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, newError(resp.StatusCode, payload, c.jsonCodec())
+	}
+
+	response := &Response{StatusCode: resp.StatusCode, Header: resp.Header, body: payload}
+	if len(bytes.TrimSpace(payload)) > 0 {
+		response.Document = &jsonapi.Document{}
+		if err := c.jsonCodec().Unmarshal(payload, response.Document); err != nil {
+			return nil, fmt.Errorf("invalid response document: %s", err)
+		}
+	}
+
+	return response, nil
+}
+
+func (c *Client) resourceURL(params *Params, segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = (&url.URL{Path: segment}).EscapedPath()
+	}
+
+	link := c.baseURL + "/" + strings.Join(escaped, "/")
+	if query := params.Values().Encode(); query != "" {
+		link += "?" + query
+	}
+
+	return link
+}
+
+func (c *Client) relationshipURL(resourceType, id, name string) string {
+	return c.resourceURL(nil, resourceType, id, "relationships", name)
+}
+
+// resolve resolves links of the service relative to the base url
+func (c *Client) resolve(link string) (string, error) {
+	base, err := url.Parse(c.baseURL + "/")
+	if err != nil {
+		return "", err
+	}
+
+	reference, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+
+	return base.ResolveReference(reference).String(), nil
+}
+
+func (c *Client) marshalResource(obj interface{}) ([]byte, *jsonapi.Data, error) {
+	document, err := jsonapi.MarshalToStruct(obj, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if document.Data == nil || document.Data.DataObject == nil {
+		return nil, nil, errors.New("obj must be a single struct")
+	}
+
+	// included structs are not part of create and update requests
+	document.Included = nil
+	body, err := c.jsonCodec().Marshal(document)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return body, document.Data.DataObject, nil
+}
+
+// unmarshalResponse unmarshals the primary data of the response into target,
+// responses without primary data leave target untouched
+func unmarshalResponse(response *Response, target interface{}) error {
+	if target == nil || response.Document == nil || response.Document.Data == nil {
+		return nil
+	}
+
+	return jsonapi.Unmarshal(response.body, target)
+}
+
+func nonNilIdentifiers(identifiers []jsonapi.RelationshipData) []jsonapi.RelationshipData {
+	if identifiers == nil {
+		return []jsonapi.RelationshipData{}
+	}
+
+	return identifiers
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/manyminds/api2go"
+	"github.com/manyminds/api2go/examples/model"
+	"github.com/manyminds/api2go/examples/resource"
+	"github.com/manyminds/api2go/examples/storage"
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// countingCodec wraps the StandardJSONCodec and counts its calls
+type countingCodec struct {
+	jsonapi.StandardJSONCodec
+	calls int64
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	atomic.AddInt64(&c.calls, 1)
+	return c.StandardJSONCodec.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	atomic.AddInt64(&c.calls, 1)
+	return c.StandardJSONCodec.Unmarshal(data, v)
+}
+
+var _ = Describe("Client", func() {
+	var (
+		server *httptest.Server
+		client *Client
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(nil)
+		api := api2go.NewAPIWithBaseURL("v0", server.URL)
+		userStorage := storage.NewUserStorage()
+		chocStorage := storage.NewChocolateStorage()
+		api.AddResource(model.User{}, resource.UserResource{ChocStorage: chocStorage, UserStorage: userStorage})
+		api.AddResource(model.Chocolate{}, resource.ChocolateResource{ChocStorage: chocStorage, UserStorage: userStorage})
+		server.Config.Handler = api.Handler()
+		client = New(server.URL + "/v0")
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	createUser := func(name string, chocolates ...string) model.User {
+		user := model.User{Username: name, ChocolatesIDs: chocolates}
+		_, err := client.Create(ctx, &user)
+		Expect(err).ToNot(HaveOccurred())
+		return user
+	}
+
+	It("creates, gets, updates and deletes resources", func() {
+		chocolate := model.Chocolate{Name: "Ritter Sport", Taste: "Very Good"}
+		response, err := client.Create(ctx, &chocolate)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusCreated))
+		Expect(chocolate.ID).To(Equal("1"))
+
+		user := createUser("marvin", chocolate.ID)
+		Expect(user.ID).To(Equal("1"))
+
+		var fetched model.User
+		_, err = client.Get(ctx, "users", user.ID, &Params{Include: []string{"sweets"}}, &fetched)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fetched.Username).To(Equal("marvin"))
+		Expect(fetched.ChocolatesIDs).To(Equal([]string{"1"}))
+
+		fetched.Username = "better marvin"
+		_, err = client.Update(ctx, &fetched)
+		Expect(err).ToNot(HaveOccurred())
+
+		var users []model.User
+		_, err = client.List(ctx, "users", nil, &users)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(users).To(HaveLen(1))
+		Expect(users[0].Username).To(Equal("better marvin"))
+
+		response, err = client.Delete(ctx, "users", user.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusNoContent))
+
+		_, err = client.Get(ctx, "users", user.ID, nil, &fetched)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("decodes error documents", func() {
+		var user model.User
+		_, err := client.Get(ctx, "users", "404", nil, &user)
+		Expect(err).To(BeAssignableToTypeOf(Error{}))
+		clientError := err.(Error)
+		Expect(clientError.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(clientError.Errors).To(HaveLen(1))
+		Expect(clientError.Errors[0].Status).To(Equal("404"))
+		Expect(clientError.Error()).To(ContainSubstring("http error (404)"))
+	})
+
+	It("uses its JSONCodec for documents", func() {
+		codec := &countingCodec{}
+		client.SetJSONCodec(codec)
+
+		user := createUser("marvin")
+		Expect(atomic.LoadInt64(&codec.calls)).To(Equal(int64(2)))
+
+		_, _, err := client.GetRelationship(ctx, "users", user.ID, "sweets")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(atomic.LoadInt64(&codec.calls)).To(Equal(int64(4)))
+
+		_, err = client.Get(ctx, "users", "404", nil, nil)
+		Expect(err).To(BeAssignableToTypeOf(Error{}))
+		Expect(err.(Error).Errors).To(HaveLen(1))
+		Expect(atomic.LoadInt64(&codec.calls)).To(Equal(int64(5)))
+
+		client.SetJSONCodec(nil)
+		Expect(client.jsonCodec()).To(Equal(jsonapi.GetJSONCodec()))
+	})
+
+	It("reads and changes relationships", func() {
+		for _, name := range []string{"Ritter Sport", "Milka", "Lindt"} {
+			_, err := client.Create(ctx, &model.Chocolate{Name: name})
+			Expect(err).ToNot(HaveOccurred())
+		}
+		user := createUser("marvin", "1")
+
+		_, err := client.AddToManyRelationship(ctx, "users", user.ID, "sweets", []jsonapi.RelationshipData{{Type: "chocolates", ID: "2"}, {Type: "chocolates", ID: "3"}})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = client.RemoveFromManyRelationship(ctx, "users", user.ID, "sweets", []jsonapi.RelationshipData{{Type: "chocolates", ID: "1"}})
+		Expect(err).ToNot(HaveOccurred())
+
+		relationship, _, err := client.GetRelationship(ctx, "users", user.ID, "sweets")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(relationship.DataArray).To(Equal([]jsonapi.RelationshipData{{Type: "chocolates", ID: "2"}, {Type: "chocolates", ID: "3"}}))
+
+		_, err = client.ReplaceToManyRelationship(ctx, "users", user.ID, "sweets", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		var sweets []model.Chocolate
+		_, err = client.GetRelated(ctx, "users", user.ID, "sweets", nil, &sweets)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sweets).To(BeEmpty())
+	})
+
+	It("follows pagination links", func() {
+		for _, name := range []string{"a", "b", "c"} {
+			createUser(name)
+		}
+
+		var users []model.User
+		response, err := client.List(ctx, "users", &Params{Page: map[string]string{"number": "1", "size": "2"}}, &users)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(users).To(HaveLen(2))
+
+		var next []model.User
+		response, err = client.Next(ctx, response, &next)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(next).To(HaveLen(1))
+		Expect(next[0].Username).To(Equal("c"))
+
+		_, err = client.Next(ctx, response, &next)
+		Expect(err).To(Equal(ErrNoNextPage))
+	})
+
+	It("sends query parameters and headers", func() {
+		var request *http.Request
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request = r
+			w.Write([]byte(`{"data": []}`))
+		})
+		client.Header.Set("Authorization", "Bearer token")
+
+		var users []model.User
+		_, err := client.List(ctx, "users", &Params{
+			Include: []string{"sweets", "sweets.owner"},
+			Fields:  map[string][]string{"users": {"user-name"}},
+			Sort:    []string{"-user-name", "id"},
+			Filter:  map[string]string{"user-name": "marvin"},
+			Page:    map[string]string{"offset": "0", "limit": "10"},
+		}, &users)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(users).To(BeEmpty())
+		Expect(request.Header.Get("Accept")).To(Equal("application/vnd.api+json"))
+		Expect(request.Header.Get("Authorization")).To(Equal("Bearer token"))
+		Expect(request.URL.Query()).To(Equal(url.Values{
+			"include":           {"sweets,sweets.owner"},
+			"fields[users]":     {"user-name"},
+			"sort":              {"-user-name,id"},
+			"filter[user-name]": {"marvin"},
+			"page[offset]":      {"0"},
+			"page[limit]":       {"10"},
+		}))
+	})
+
+	It("stops if the context is canceled", func() {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		var users []model.User
+		_, err := client.List(canceled, "users", nil, &users)
+		Expect(err).To(HaveOccurred())
+	})
+})
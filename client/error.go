@@ -0,0 +1,46 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/manyminds/api2go"
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// Error is returned for responses with an error status code. Errors contains
+// the error objects of the error document, it is empty if the response had no
+// valid error document.
+type Error struct {
+	StatusCode int
+	Errors     []api2go.Error
+}
+
+func newError(status int, payload []byte, codec jsonapi.JSONCodec) Error {
+	var document struct {
+		Errors []jsonapi.Error `json:"errors"`
+	}
+
+	// the body of errors of proxies or load balancers is often not json
+	codec.Unmarshal(payload, &document)
+
+	var errors []api2go.Error
+	for _, decoded := range document.Errors {
+		errors = append(errors, api2go.ErrorFromJSONAPI(decoded))
+	}
+
+	return Error{StatusCode: status, Errors: errors}
+}
+
+// Error returns a string representation of the error for logging purposes.
+func (e Error) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("http error (%d)", e.StatusCode)
+	}
+
+	msg := e.Errors[0].Title
+	if e.Errors[0].Detail != "" {
+		msg += ": " + e.Errors[0].Detail
+	}
+
+	return fmt.Sprintf("http error (%d) %s and %d more errors", e.StatusCode, msg, len(e.Errors)-1)
+}
@@ -0,0 +1,277 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// PaginationStrategy computes the page parameters of a collection. The
+// iterator always follows the next link of a response, the strategy is only
+// asked for the following page if a service does not return next links.
+type PaginationStrategy interface {
+	// FirstPage returns the page parameters of the first request
+	FirstPage() map[string]string
+	// NextPage returns the page parameters of the page after current, or false
+	// if current was the last page
+	NextPage(current map[string]string, response *Response) (map[string]string, bool)
+}
+
+// validator is implemented by strategies that can check their configuration
+// before the first request
+type validator interface {
+	validate() error
+}
+
+// checkSize returns an error if a page size is not positive, the pages would
+// never advance otherwise
+func checkSize(name string, size int) error {
+	if size < 1 {
+		return fmt.Errorf("%s must be positive, got %d", name, size)
+	}
+
+	return nil
+}
+
+// PageNumberStrategy requests pages with page[number] and page[size]
+type PageNumberStrategy struct {
+	Size int
+}
+
+// FirstPage returns the first page with the configured size
+func (s PageNumberStrategy) FirstPage() map[string]string {
+	return map[string]string{"number": "1", "size": strconv.Itoa(s.Size)}
+}
+
+// NextPage increments the page number until a page is not full
+func (s PageNumberStrategy) NextPage(current map[string]string, response *Response) (map[string]string, bool) {
+	count := countRecords(response)
+	if count < s.Size {
+		return nil, false
+	}
+
+	number, _ := strconv.Atoi(current["number"])
+	return map[string]string{"number": strconv.Itoa(number + 1), "size": strconv.Itoa(s.Size)}, true
+}
+
+func (s PageNumberStrategy) validate() error {
+	return checkSize("PageNumberStrategy.Size", s.Size)
+}
+
+// OffsetStrategy requests pages with page[offset] and page[limit]
+type OffsetStrategy struct {
+	Limit int
+}
+
+// FirstPage returns the page at offset 0
+func (s OffsetStrategy) FirstPage() map[string]string {
+	return map[string]string{"offset": "0", "limit": strconv.Itoa(s.Limit)}
+}
+
+// NextPage increments the offset until a page is not full
+func (s OffsetStrategy) NextPage(current map[string]string, response *Response) (map[string]string, bool) {
+	count := countRecords(response)
+	if count < s.Limit {
+		return nil, false
+	}
+
+	offset, _ := strconv.Atoi(current["offset"])
+	return map[string]string{"offset": strconv.Itoa(offset + s.Limit), "limit": strconv.Itoa(s.Limit)}, true
+}
+
+func (s OffsetStrategy) validate() error {
+	return checkSize("OffsetStrategy.Limit", s.Limit)
+}
+
+// CursorStrategy requests pages with page[size] and page[after]. The cursor
+// is taken from the meta member "page": {"cursor": "..."} of the last record as
+// described by the cursor pagination profile, or its id if there is none.
+type CursorStrategy struct {
+	Size int
+}
+
+// FirstPage returns the first page with the configured size
+func (s CursorStrategy) FirstPage() map[string]string {
+	return map[string]string{"size": strconv.Itoa(s.Size)}
+}
+
+// NextPage requests the records after the last record until a page is not full
+func (s CursorStrategy) NextPage(current map[string]string, response *Response) (map[string]string, bool) {
+	count := countRecords(response)
+	if count < s.Size {
+		return nil, false
+	}
+
+	last := response.Document.Data.DataArray[count-1]
+	cursor := last.ID
+	if page, ok := last.Meta["page"].(map[string]interface{}); ok {
+		if value, ok := page["cursor"].(string); ok {
+			cursor = value
+		}
+	}
+
+	return map[string]string{"size": strconv.Itoa(s.Size), "after": cursor}, true
+}
+
+func (s CursorStrategy) validate() error {
+	return checkSize("CursorStrategy.Size", s.Size)
+}
+
+func countRecords(response *Response) int {
+	if response == nil || response.Document == nil || response.Document.Data == nil {
+		return 0
+	}
+
+	return len(response.Document.Data.DataArray)
+}
+
+// Iterator lazily fetches all pages of a collection. Use it like this:
+//
+//	pages := c.Pages(ctx, "users", nil, client.PageNumberStrategy{Size: 100})
+//	var users []model.User
+//	for pages.Next(&users) {
+//		// process users
+//	}
+//	if err := pages.Err(); err != nil {
+//		// handle error
+//	}
+type Iterator struct {
+	client       *Client
+	ctx          context.Context
+	resourceType string
+	params       Params
+	strategy     PaginationStrategy
+	page         map[string]string
+	link         string
+	response     *Response
+	err          error
+	done         bool
+}
+
+// Pages returns an iterator over all pages of a collection. The strategy can
+// be nil if the service returns next links. A strategy with a page size below
+// 1 stops the iterator before the first request with an error.
+func (c *Client) Pages(ctx context.Context, resourceType string, params *Params, strategy PaginationStrategy) *Iterator {
+	iterator := &Iterator{client: c, ctx: ctx, resourceType: resourceType, strategy: strategy}
+	if params != nil {
+		iterator.params = *params
+	}
+
+	if validated, ok := strategy.(validator); ok {
+		if err := validated.validate(); err != nil {
+			iterator.stop(err)
+			return iterator
+		}
+	}
+
+	if strategy != nil {
+		iterator.page = strategy.FirstPage()
+		iterator.params.Page = iterator.page
+	}
+
+	iterator.link = c.resourceURL(&iterator.params, resourceType)
+
+	return iterator
+}
+
+// Next fetches the next page and unmarshals it into target, which must be a
+// pointer to a slice. The slice is emptied before. It returns false if there
+// are no more pages or an error occurred.
+func (i *Iterator) Next(target interface{}) bool {
+	if i.done {
+		return false
+	}
+
+	if err := i.ctx.Err(); err != nil {
+		return i.stop(err)
+	}
+
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.Elem().Kind() != reflect.Slice {
+		return i.stop(errors.New("target must be a pointer to a slice"))
+	}
+	targetValue.Elem().Set(reflect.Zero(targetValue.Elem().Type()))
+
+	response, err := i.client.do(i.ctx, "GET", i.link, nil)
+	if err != nil {
+		return i.stop(err)
+	}
+
+	if err := unmarshalResponse(response, target); err != nil {
+		return i.stop(err)
+	}
+
+	i.response = response
+	i.advance()
+
+	return true
+}
+
+// advance determines the link of the following page. An empty page ends the
+// iteration even if it has a next link. Once a next link was followed, the
+// strategy is not asked anymore because its page parameters are outdated.
+func (i *Iterator) advance() {
+	if countRecords(i.response) == 0 {
+		i.done = true
+		return
+	}
+
+	if next, ok := i.response.NextURL(); ok {
+		link, err := i.client.resolve(next)
+		if err != nil {
+			i.stop(err)
+			return
+		}
+
+		i.link = link
+		i.strategy = nil
+		return
+	}
+
+	if i.strategy == nil {
+		i.done = true
+		return
+	}
+
+	page, ok := i.strategy.NextPage(i.page, i.response)
+	if !ok {
+		i.done = true
+		return
+	}
+
+	i.page = page
+	i.params.Page = page
+	i.link = i.client.resourceURL(&i.params, i.resourceType)
+}
+
+func (i *Iterator) stop(err error) bool {
+	i.err = err
+	i.done = true
+	return false
+}
+
+// Response returns the response of the current page
+func (i *Iterator) Response() *Response {
+	return i.response
+}
+
+// Err returns the error that stopped the iteration, if any
+func (i *Iterator) Err() error {
+	return i.err
+}
+
+// EachPage calls fn for every page of a collection after the page was
+// unmarshalled into target, so only one page is held in memory at a time. The
+// iteration stops at the first error of fn.
+func (c *Client) EachPage(ctx context.Context, resourceType string, params *Params, strategy PaginationStrategy, target interface{}, fn func(response *Response) error) error {
+	pages := c.Pages(ctx, resourceType, params, strategy)
+	for pages.Next(target) {
+		if err := fn(pages.Response()); err != nil {
+			return err
+		}
+	}
+
+	return pages.Err()
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/manyminds/api2go"
+	"github.com/manyminds/api2go/examples/model"
+	"github.com/manyminds/api2go/examples/resource"
+	"github.com/manyminds/api2go/examples/storage"
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// usersWithoutLinks serves five users without pagination links
+func usersWithoutLinks(queries *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.RawQuery)
+		query := r.URL.Query()
+
+		start, end := 0, 5
+		if offset := query.Get("page[offset]"); offset != "" {
+			start, _ = strconv.Atoi(offset)
+			limit, _ := strconv.Atoi(query.Get("page[limit]"))
+			end = start + limit
+		}
+		if size := query.Get("page[size]"); size != "" {
+			limit, _ := strconv.Atoi(size)
+			if after := query.Get("page[after]"); after != "" {
+				start, _ = strconv.Atoi(strings.TrimPrefix(after, "cursor-"))
+			}
+			if number := query.Get("page[number]"); number != "" {
+				page, _ := strconv.Atoi(number)
+				start = (page - 1) * limit
+			}
+			end = start + limit
+		}
+		if end > 5 {
+			end = 5
+		}
+		if start > end {
+			start = end
+		}
+
+		records := []string{}
+		for i := start + 1; i <= end; i++ {
+			records = append(records, fmt.Sprintf(`{"type": "users", "id": "%d", "attributes": {"user-name": "user %d"}, "meta": {"page": {"cursor": "cursor-%d"}}}`, i, i, i))
+		}
+
+		if query.Get("page[offset]") == "4" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"errors": [{"status": "500", "title": "database down"}]}`))
+			return
+		}
+
+		w.Write([]byte(`{"data": [` + strings.Join(records, ",") + `]}`))
+	}
+}
+
+var _ = Describe("Iterating pages", func() {
+	var (
+		server  *httptest.Server
+		client  *Client
+		ctx     context.Context
+		queries []string
+	)
+
+	BeforeEach(func() {
+		queries = []string{}
+		server = httptest.NewServer(usersWithoutLinks(&queries))
+		client = New(server.URL + "/v0")
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	collect := func(pages *Iterator) []string {
+		names := []string{}
+		var users []model.User
+		for pages.Next(&users) {
+			for _, user := range users {
+				names = append(names, user.Username)
+			}
+		}
+
+		return names
+	}
+
+	It("follows next links", func() {
+		api := api2go.NewAPIWithBaseURL("v0", server.URL)
+		userStorage := storage.NewUserStorage()
+		api.AddResource(model.User{}, resource.UserResource{ChocStorage: storage.NewChocolateStorage(), UserStorage: userStorage})
+		server.Config.Handler = api.Handler()
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			userStorage.Insert(model.User{Username: name})
+		}
+
+		pages := client.Pages(ctx, "users", nil, PageNumberStrategy{Size: 2})
+		Expect(collect(pages)).To(Equal([]string{"a", "b", "c", "d", "e"}))
+		Expect(pages.Err()).ToNot(HaveOccurred())
+	})
+
+	It("uses page numbers without next links", func() {
+		pages := client.Pages(ctx, "users", nil, PageNumberStrategy{Size: 5})
+		Expect(collect(pages)).To(HaveLen(5))
+		Expect(pages.Err()).ToNot(HaveOccurred())
+		Expect(queries).To(Equal([]string{
+			"page%5Bnumber%5D=1&page%5Bsize%5D=5",
+			"page%5Bnumber%5D=2&page%5Bsize%5D=5",
+		}))
+	})
+
+	It("uses cursors without next links", func() {
+		pages := client.Pages(ctx, "users", &Params{Sort: []string{"id"}}, CursorStrategy{Size: 3})
+		Expect(collect(pages)).To(Equal([]string{"user 1", "user 2", "user 3", "user 4", "user 5"}))
+		Expect(queries).To(Equal([]string{
+			"page%5Bsize%5D=3&sort=id",
+			"page%5Bafter%5D=cursor-3&page%5Bsize%5D=3&sort=id",
+		}))
+	})
+
+	It("stops at the first error", func() {
+		pages := client.Pages(ctx, "users", nil, OffsetStrategy{Limit: 2})
+		Expect(collect(pages)).To(Equal([]string{"user 1", "user 2", "user 3", "user 4"}))
+		Expect(pages.Err()).To(BeAssignableToTypeOf(Error{}))
+		Expect(pages.Err().(Error).StatusCode).To(Equal(http.StatusInternalServerError))
+
+		var users []model.User
+		Expect(pages.Next(&users)).To(BeFalse())
+	})
+
+	It("only follows next links without strategy", func() {
+		pages := client.Pages(ctx, "users", nil, nil)
+		Expect(collect(pages)).To(HaveLen(5))
+		Expect(queries).To(Equal([]string{""}))
+	})
+
+	It("streams pages to a callback", func() {
+		count := 0
+		var users []model.User
+		err := client.EachPage(ctx, "users", nil, OffsetStrategy{Limit: 1}, &users, func(response *Response) error {
+			Expect(users).To(HaveLen(1))
+			count++
+			if count == 2 {
+				return errors.New("enough")
+			}
+
+			return nil
+		})
+		Expect(err).To(MatchError("enough"))
+		Expect(count).To(Equal(2))
+	})
+
+	It("rejects page sizes below 1 before the first request", func() {
+		strategies := map[PaginationStrategy]string{
+			PageNumberStrategy{}:      "PageNumberStrategy.Size must be positive, got 0",
+			OffsetStrategy{Limit: -1}: "OffsetStrategy.Limit must be positive, got -1",
+			CursorStrategy{}:          "CursorStrategy.Size must be positive, got 0",
+		}
+		for strategy, message := range strategies {
+			pages := client.Pages(ctx, "users", nil, strategy)
+			Expect(collect(pages)).To(BeEmpty())
+			Expect(pages.Err()).To(MatchError(message))
+		}
+		Expect(queries).To(BeEmpty())
+	})
+
+	It("stops on an empty page", func() {
+		empty := &Response{Document: &jsonapi.Document{Data: &jsonapi.DataContainer{DataArray: []jsonapi.Data{}}}}
+		_, ok := PageNumberStrategy{Size: 2}.NextPage(PageNumberStrategy{Size: 2}.FirstPage(), empty)
+		Expect(ok).To(BeFalse())
+		_, ok = OffsetStrategy{Limit: 2}.NextPage(OffsetStrategy{Limit: 2}.FirstPage(), empty)
+		Expect(ok).To(BeFalse())
+		_, ok = CursorStrategy{Size: 2}.NextPage(CursorStrategy{Size: 2}.FirstPage(), empty)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("stops on an empty page with a next link", func() {
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			queries = append(queries, r.URL.RawQuery)
+			page, _ := strconv.Atoi(r.URL.Query().Get("page[number]"))
+			data := `[]`
+			if page < 2 {
+				data = fmt.Sprintf(`[{"type": "users", "id": "%d", "attributes": {"user-name": "user %d"}}]`, page+1, page+1)
+			}
+			next := fmt.Sprintf("%s/v0/users?page[number]=%d", server.URL, page+1)
+			w.Write([]byte(`{"data": ` + data + `, "links": {"next": "` + next + `"}}`))
+		})
+
+		pages := client.Pages(ctx, "users", nil, nil)
+		Expect(collect(pages)).To(Equal([]string{"user 1", "user 2"}))
+		Expect(pages.Err()).ToNot(HaveOccurred())
+		Expect(queries).To(HaveLen(3))
+	})
+
+	It("stops if the context is canceled", func() {
+		canceled, cancel := context.WithCancel(ctx)
+		var users []model.User
+		err := client.EachPage(canceled, "users", nil, PageNumberStrategy{Size: 1}, &users, func(response *Response) error {
+			cancel()
+			return nil
+		})
+		Expect(err).To(Equal(context.Canceled))
+		Expect(queries).To(HaveLen(1))
+	})
+})
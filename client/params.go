@@ -0,0 +1,56 @@
+package client
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Params contains the query parameters of a request
+type Params struct {
+	// Include contains the relationship paths to include, e.g. "comments.author"
+	Include []string
+	// Fields contains the sparse fieldsets by type
+	Fields map[string][]string
+	// Sort contains the sort fields, prefixed with "-" for descending order
+	Sort []string
+	// Filter is added as filter[name]=value
+	Filter map[string]string
+	// Page is added as page[name]=value, e.g. "number" and "size"
+	Page map[string]string
+	// Query contains additional query parameters
+	Query url.Values
+}
+
+// Values returns the query parameters of p
+func (p *Params) Values() url.Values {
+	values := url.Values{}
+	if p == nil {
+		return values
+	}
+
+	for name, value := range p.Query {
+		values[name] = append([]string{}, value...)
+	}
+
+	if len(p.Include) > 0 {
+		values.Set("include", strings.Join(p.Include, ","))
+	}
+
+	for resourceType, fields := range p.Fields {
+		values.Set("fields["+resourceType+"]", strings.Join(fields, ","))
+	}
+
+	if len(p.Sort) > 0 {
+		values.Set("sort", strings.Join(p.Sort, ","))
+	}
+
+	for name, value := range p.Filter {
+		values.Set("filter["+name+"]", value)
+	}
+
+	for name, value := range p.Page {
+		values.Set("page["+name+"]", value)
+	}
+
+	return values
+}
@@ -1,10 +1,11 @@
 package api2go
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"strconv"
+
+	"github.com/manyminds/api2go/jsonapi"
 )
 
 // HTTPError is used for errors
@@ -50,13 +51,70 @@ type ErrorSource struct {
 	Parameter string `json:"parameter,omitempty"`
 }
 
+// ToJSONAPI converts the error object to the jsonapi.Error with the same fields
+func (e Error) ToJSONAPI() jsonapi.Error {
+	converted := jsonapi.Error{
+		ID:     e.ID,
+		Status: e.Status,
+		Code:   e.Code,
+		Title:  e.Title,
+		Detail: e.Detail,
+		Meta:   e.Meta,
+	}
+
+	if e.Links != nil {
+		converted.Links = &jsonapi.ErrorLinks{About: e.Links.About}
+	}
+
+	if e.Source != nil {
+		converted.Source = &jsonapi.ErrorSource{Pointer: e.Source.Pointer, Parameter: e.Source.Parameter}
+	}
+
+	return converted
+}
+
+// ErrorFromJSONAPI converts a decoded jsonapi.Error to an Error, e.g. to pass
+// the errors of another service on with an HTTPError
+func ErrorFromJSONAPI(e jsonapi.Error) Error {
+	converted := Error{
+		ID:     e.ID,
+		Status: e.Status,
+		Code:   e.Code,
+		Title:  e.Title,
+		Detail: e.Detail,
+		Meta:   e.Meta,
+	}
+
+	if e.Links != nil {
+		converted.Links = &ErrorLinks{About: e.Links.About}
+	}
+
+	if e.Source != nil {
+		converted.Source = &ErrorSource{Pointer: e.Source.Pointer, Parameter: e.Source.Parameter}
+	}
+
+	return converted
+}
+
+// errorDocument is the top-level document of error responses
+type errorDocument struct {
+	JSONAPI *jsonapi.JSONAPI `json:"jsonapi,omitempty"`
+	Links   jsonapi.Links    `json:"links,omitempty"`
+	HTTPError
+}
+
 // marshalHTTPError marshals an internal httpError
 func marshalHTTPError(input HTTPError) string {
+	return marshalErrorDocument(errorDocument{HTTPError: input}, jsonapi.GetJSONCodec())
+}
+
+// marshalErrorDocument marshals an internal httpError along with the top-level members
+func marshalErrorDocument(input errorDocument, codec jsonapi.JSONCodec) string {
 	if len(input.Errors) == 0 {
 		input.Errors = []Error{{Title: input.msg, Status: strconv.Itoa(input.status)}}
 	}
 
-	data, err := json.Marshal(input)
+	data, err := codec.Marshal(input)
 
 	if err != nil {
 		log.Println(err)
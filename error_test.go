@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/manyminds/api2go/jsonapi"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -96,4 +97,38 @@ var _ = Describe("Errors test", func() {
 			Expect(result).To(Equal(expected))
 		})
 	})
+
+	Context("Converting", func() {
+		full := Error{
+			ID:     "001",
+			Links:  &ErrorLinks{About: "http://bla/blub"},
+			Status: "422",
+			Code:   "001",
+			Title:  "Title must not be empty",
+			Detail: "Never occures in real life",
+			Source: &ErrorSource{Pointer: "/data/attributes/title", Parameter: "title"},
+			Meta:   map[string]interface{}{"creator": "api2go"},
+		}
+
+		It("converts to jsonapi.Error and back", func() {
+			converted := full.ToJSONAPI()
+			Expect(converted).To(Equal(jsonapi.Error{
+				ID:     "001",
+				Links:  &jsonapi.ErrorLinks{About: "http://bla/blub"},
+				Status: "422",
+				Code:   "001",
+				Title:  "Title must not be empty",
+				Detail: "Never occures in real life",
+				Source: &jsonapi.ErrorSource{Pointer: "/data/attributes/title", Parameter: "title"},
+				Meta:   map[string]interface{}{"creator": "api2go"},
+			}))
+			Expect(ErrorFromJSONAPI(converted)).To(Equal(full))
+		})
+
+		It("keeps missing links and source", func() {
+			converted := Error{Title: "Bad Request"}.ToJSONAPI()
+			Expect(converted).To(Equal(jsonapi.Error{Title: "Bad Request"}))
+			Expect(ErrorFromJSONAPI(converted)).To(Equal(Error{Title: "Bad Request"}))
+		})
+	})
 })
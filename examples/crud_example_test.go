@@ -511,6 +511,15 @@ var _ = Describe("CrudExample", func() {
 			`))
 		})
 
+		It("Loading the sorted sweets uses FindAll with the relation", func() {
+			req, err := http.NewRequest("GET", "/v0/users/1/sweets?sort=name", nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"name":"Ritter Sport"`))
+			Expect(rec.Body.String()).ToNot(ContainSubstring(`"name":"Black Chocolate"`))
+		})
+
 		It("The relationship route works too", func() {
 			req, err := http.NewRequest("GET", "/v0/users/1/relationships/sweets", nil)
 			Expect(err).ToNot(HaveOccurred())
@@ -537,4 +546,75 @@ var _ = Describe("CrudExample", func() {
 			`))
 		})
 	})
+
+	Describe("Referential integrity", func() {
+		BeforeEach(func() {
+			api.EnableReferentialIntegrity()
+		})
+
+		postUser := func(relationships string) {
+			rec = httptest.NewRecorder()
+			req, err := http.NewRequest("POST", "/v0/users", strings.NewReader(`
+			{
+				"data": {
+					"type": "users",
+					"attributes": {"user-name": "marvin"},
+					"relationships": `+relationships+`
+				}
+			}
+			`))
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("rejects unknown relationships before unmarshalling", func() {
+			postUser(`{"bogus": {"data": [{"type": "chocolates", "id": "1"}]}}`)
+			Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(rec.Body.String()).To(MatchJSON(`
+			{
+				"errors": [{
+					"status": "422",
+					"code": "API2GO_UNKNOWN_RELATIONSHIP",
+					"title": "Unknown relationship",
+					"detail": "Resource users has no relationship bogus",
+					"source": {"pointer": "/data/relationships/bogus"}
+				}]
+			}
+			`))
+		})
+
+		It("rejects sweets that do not exist", func() {
+			postUser(`{"sweets": {"data": [{"type": "chocolates", "id": "999"}]}}`)
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Body.String()).To(MatchJSON(`
+			{
+				"errors": [{
+					"status": "404",
+					"code": "API2GO_RELATED_RESOURCE_NOT_FOUND",
+					"title": "Related resource not found",
+					"detail": "There is no chocolates with id 999",
+					"source": {"pointer": "/data/relationships/sweets/data/0"}
+				}]
+			}
+			`))
+		})
+
+		It("rejects unknown relationships on update", func() {
+			createUser()
+			rec = httptest.NewRecorder()
+			req, err := http.NewRequest("PATCH", "/v0/users/1", strings.NewReader(`
+			{
+				"data": {
+					"type": "users",
+					"id": "1",
+					"relationships": {"bogus": {"data": null}}
+				}
+			}
+			`))
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(rec.Body.String()).To(ContainSubstring("API2GO_UNKNOWN_RELATIONSHIP"))
+		})
+	})
 })
@@ -17,12 +17,11 @@ type ChocolateResource struct {
 
 // FindAll chocolates
 func (c ChocolateResource) FindAll(r api2go.Request) (api2go.Responder, error) {
-	usersID, ok := r.QueryParams["usersID"]
 	sweets := c.ChocStorage.GetAll()
-	if ok {
+	if r.Relation != nil && r.Relation.ParentType == "users" {
 		// this means that we want to show all sweets of a user, this is the route
-		// /v0/users/1/sweets
-		userID := usersID[0]
+		// /v0/users/1/sweets with sort or filter parameters, without them FindMany is used
+		userID := r.Relation.ParentID
 		// filter out sweets with userID, in real world, you would just run a different database query
 		filteredSweets := []model.Chocolate{}
 		user, err := c.UserStorage.GetOne(userID)
@@ -45,7 +44,16 @@ func (c ChocolateResource) FindAll(r api2go.Request) (api2go.Responder, error) {
 // FindOne choc
 func (c ChocolateResource) FindOne(ID string, r api2go.Request) (api2go.Responder, error) {
 	res, err := c.ChocStorage.GetOne(ID)
-	return &Response{Res: res}, err
+	if err != nil {
+		return &Response{}, api2go.NewHTTPError(err, err.Error(), http.StatusNotFound)
+	}
+
+	return &Response{Res: res}, nil
+}
+
+// FindMany chocs, used for included sweets and related sweets without sort or filter
+func (c ChocolateResource) FindMany(IDs []string, r api2go.Request) (api2go.Responder, error) {
+	return &Response{Res: c.ChocStorage.GetMany(IDs)}, nil
 }
 
 // Create a new choc
@@ -2,6 +2,7 @@ package resource
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
@@ -22,13 +23,23 @@ func (s UserResource) FindAll(r api2go.Request) (api2go.Responder, error) {
 	var result []model.User
 	users := s.UserStorage.GetAll()
 
+	// get the sweets of all users with one lookup
+	chocolateIDs := []string{}
+	for _, user := range users {
+		chocolateIDs = append(chocolateIDs, user.ChocolatesIDs...)
+	}
+
+	chocolates := map[string]model.Chocolate{}
+	for _, choc := range s.ChocStorage.GetMany(chocolateIDs) {
+		chocolates[choc.ID] = choc
+	}
+
 	for _, user := range users {
-		// get all sweets for the user
 		user.Chocolates = []*model.Chocolate{}
 		for _, chocolateID := range user.ChocolatesIDs {
-			choc, err := s.ChocStorage.GetOne(chocolateID)
-			if err != nil {
-				return &Response{}, err
+			choc, ok := chocolates[chocolateID]
+			if !ok {
+				return &Response{}, fmt.Errorf("Chocolate for id %s not found", chocolateID)
 			}
 			user.Chocolates = append(user.Chocolates, &choc)
 		}
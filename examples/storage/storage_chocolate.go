@@ -55,6 +55,18 @@ func (s ChocolateStorage) GetOne(id string) (model.Chocolate, error) {
 	return model.Chocolate{}, fmt.Errorf("Chocolate for id %s not found", id)
 }
 
+// GetMany tasty chocolates in one lookup, unknown ids are skipped
+func (s ChocolateStorage) GetMany(ids []string) []model.Chocolate {
+	result := []model.Chocolate{}
+	for _, id := range ids {
+		if choc, ok := s.chocolates[id]; ok {
+			result = append(result, *choc)
+		}
+	}
+
+	return result
+}
+
 // Insert a fresh one
 func (s *ChocolateStorage) Insert(c model.Chocolate) string {
 	id := fmt.Sprintf("%d", s.idCount)
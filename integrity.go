@@ -0,0 +1,239 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+const (
+	codeInvalidRelationshipType = "API2GO_INVALID_RELATIONSHIP_TYPE"
+	codeUnknownRelationship     = "API2GO_UNKNOWN_RELATIONSHIP"
+	codeUnknownResourceType     = "API2GO_UNKNOWN_RESOURCE_TYPE"
+	codeRelatedResourceNotFound = "API2GO_RELATED_RESOURCE_NOT_FOUND"
+)
+
+// referencedIdentifier is a resource identifier object of a request together with the reference it
+// belongs to and its position in the request document
+type referencedIdentifier struct {
+	jsonapi.RelationshipData
+	reference jsonapi.Reference
+	pointer   string
+}
+
+// checkDocumentReferences checks all relationships of the primary data of a create or update request
+func (res *resource) checkDocumentReferences(c APIContexter, r *http.Request, body []byte) error {
+	if !res.api.checkReferences {
+		return nil
+	}
+
+	var document struct {
+		Data *struct {
+			Relationships map[string]struct {
+				Data *jsonapi.RelationshipDataContainer `json:"data"`
+			} `json:"relationships"`
+		} `json:"data"`
+	}
+
+	// invalid documents are already rejected while unmarshalling
+	if err := res.api.jsonCodec().Unmarshal(body, &document); err != nil || document.Data == nil {
+		return nil
+	}
+
+	names := []string{}
+	for name := range document.Data.Relationships {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	unknown := []Error{}
+	identifiers := []referencedIdentifier{}
+	for _, name := range names {
+		reference, ok := res.getReference(name)
+		if !ok {
+			unknown = append(unknown, Error{
+				Status: strconv.Itoa(http.StatusUnprocessableEntity),
+				Code:   codeUnknownRelationship,
+				Title:  "Unknown relationship",
+				Detail: fmt.Sprintf("Resource %s has no relationship %s", res.name, name),
+				Source: &ErrorSource{Pointer: fmt.Sprintf("/data/relationships/%s", name)},
+			})
+			continue
+		}
+
+		pointer := fmt.Sprintf("/data/relationships/%s/data", name)
+		identifiers = append(identifiers, collectIdentifiers(reference, document.Data.Relationships[name].Data, pointer)...)
+	}
+
+	if len(unknown) > 0 {
+		return newReferenceError(http.StatusUnprocessableEntity, unknown)
+	}
+
+	return res.api.checkIdentifiers(c, r, identifiers, true)
+}
+
+// checkRelationshipReferences checks the data of a request to a relationship route
+func (res *resource) checkRelationshipReferences(c APIContexter, r *http.Request, reference jsonapi.Reference, data interface{}, checkExistence bool) error {
+	if !res.api.checkReferences || data == nil {
+		return nil
+	}
+
+	payload, err := res.api.jsonCodec().Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var container jsonapi.RelationshipDataContainer
+	if err := res.api.jsonCodec().Unmarshal(payload, &container); err != nil {
+		return err
+	}
+
+	return res.api.checkIdentifiers(c, r, collectIdentifiers(reference, &container, "/data"), checkExistence)
+}
+
+func (res *resource) getReference(name string) (jsonapi.Reference, bool) {
+	for _, reference := range res.references {
+		if reference.Name == name {
+			return reference, true
+		}
+	}
+
+	return jsonapi.Reference{}, false
+}
+
+// findMissingIDs returns all ids that do not exist in the resource
+func (res *resource) findMissingIDs(ids []string, req Request) ([]string, error) {
+	if checker, ok := res.source.(ExistenceChecker); ok {
+		return checker.FindMissingIDs(ids, req)
+	}
+
+	source, ok := res.source.(ResourceGetter)
+	if !ok {
+		return nil, nil
+	}
+
+	missing := []string{}
+	for _, id := range ids {
+		response, err := source.FindOne(id, req)
+		if err != nil {
+			if httpError, ok := err.(HTTPError); ok && httpError.status == http.StatusNotFound {
+				missing = append(missing, id)
+				continue
+			}
+
+			return nil, err
+		}
+
+		if response == nil || response.Result() == nil {
+			missing = append(missing, id)
+		}
+	}
+
+	return missing, nil
+}
+
+func collectIdentifiers(reference jsonapi.Reference, container *jsonapi.RelationshipDataContainer, pointer string) []referencedIdentifier {
+	if container == nil {
+		return nil
+	}
+
+	if container.DataObject != nil {
+		return []referencedIdentifier{{*container.DataObject, reference, pointer}}
+	}
+
+	identifiers := []referencedIdentifier{}
+	for index, data := range container.DataArray {
+		identifiers = append(identifiers, referencedIdentifier{data, reference, fmt.Sprintf("%s/%d", pointer, index)})
+	}
+
+	return identifiers
+}
+
+// checkIdentifiers returns 422 if the type of an identifier does not match its reference or is not
+// registered in the api and 404 if the referenced resource does not exist
+func (api *API) checkIdentifiers(c APIContexter, r *http.Request, identifiers []referencedIdentifier, checkExistence bool) error {
+	invalid := []Error{}
+	for _, identifier := range identifiers {
+		// polymorphic references do not declare a type
+		if identifier.reference.Type != "" && identifier.Type != identifier.reference.Type {
+			invalid = append(invalid, Error{
+				Status: strconv.Itoa(http.StatusUnprocessableEntity),
+				Code:   codeInvalidRelationshipType,
+				Title:  "Invalid relationship type",
+				Detail: fmt.Sprintf("Relationship %s expects type %s, got %s", identifier.reference.Name, identifier.reference.Type, identifier.Type),
+				Source: &ErrorSource{Pointer: identifier.pointer + "/type"},
+			})
+		} else if api.getResource(identifier.Type) == nil {
+			invalid = append(invalid, Error{
+				Status: strconv.Itoa(http.StatusUnprocessableEntity),
+				Code:   codeUnknownResourceType,
+				Title:  "Unknown resource type",
+				Detail: fmt.Sprintf("Relationship %s references the unknown type %s", identifier.reference.Name, identifier.Type),
+				Source: &ErrorSource{Pointer: identifier.pointer + "/type"},
+			})
+		}
+	}
+
+	if len(invalid) > 0 {
+		return newReferenceError(http.StatusUnprocessableEntity, invalid)
+	}
+
+	if !checkExistence {
+		return nil
+	}
+
+	types := []string{}
+	idsByType := map[string][]string{}
+	seen := map[string]bool{}
+	for _, identifier := range identifiers {
+		if _, ok := idsByType[identifier.Type]; !ok {
+			types = append(types, identifier.Type)
+		}
+
+		if key := identifier.Type + "/" + identifier.ID; !seen[key] {
+			seen[key] = true
+			idsByType[identifier.Type] = append(idsByType[identifier.Type], identifier.ID)
+		}
+	}
+
+	missing := map[string]bool{}
+	for _, resourceType := range types {
+		ids, err := api.getResource(resourceType).findMissingIDs(idsByType[resourceType], buildRequest(c, r))
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			missing[resourceType+"/"+id] = true
+		}
+	}
+
+	notFound := []Error{}
+	for _, identifier := range identifiers {
+		if missing[identifier.Type+"/"+identifier.ID] {
+			notFound = append(notFound, Error{
+				Status: strconv.Itoa(http.StatusNotFound),
+				Code:   codeRelatedResourceNotFound,
+				Title:  "Related resource not found",
+				Detail: fmt.Sprintf("There is no %s with id %s", identifier.Type, identifier.ID),
+				Source: &ErrorSource{Pointer: identifier.pointer},
+			})
+		}
+	}
+
+	if len(notFound) > 0 {
+		return newReferenceError(http.StatusNotFound, notFound)
+	}
+
+	return nil
+}
+
+func newReferenceError(status int, errors []Error) HTTPError {
+	httpError := NewHTTPError(nil, errors[0].Title, status)
+	httpError.Errors = errors
+
+	return httpError
+}
@@ -0,0 +1,269 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type integrityBoardResource struct {
+	board   Board
+	created interface{}
+	updated interface{}
+}
+
+func (s *integrityBoardResource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: s.board}, nil
+}
+
+func (s *integrityBoardResource) Create(obj interface{}, req Request) (Responder, error) {
+	s.created = obj
+	return &Response{Res: obj, Code: http.StatusCreated}, nil
+}
+
+func (s *integrityBoardResource) Update(obj interface{}, req Request) (Responder, error) {
+	s.updated = obj
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (b *Board) SetToOneReferenceID(name, ID string) error {
+	b.OwnerID = ID
+	return nil
+}
+
+func (b *Board) SetToManyReferenceIDs(name string, IDs []string) error {
+	b.MemberIDs = IDs
+	return nil
+}
+
+func (b *Board) AddToManyIDs(name string, IDs []string) error {
+	b.MemberIDs = append(b.MemberIDs, IDs...)
+	return nil
+}
+
+func (b *Board) DeleteToManyIDs(name string, IDs []string) error {
+	return nil
+}
+
+type integrityUser struct {
+	ID string `json:"-"`
+}
+
+func (u integrityUser) GetID() string {
+	return u.ID
+}
+
+func (u integrityUser) GetName() string {
+	return "users"
+}
+
+type integrityUserResource struct {
+	lookups []string
+}
+
+func (s *integrityUserResource) FindOne(ID string, req Request) (Responder, error) {
+	s.lookups = append(s.lookups, ID)
+	switch ID {
+	case "1", "2":
+		return &Response{Res: integrityUser{ID: ID}}, nil
+	case "empty":
+		return &Response{}, nil
+	case "broken":
+		return &Response{}, NewHTTPError(nil, "database down", http.StatusServiceUnavailable)
+	default:
+		return &Response{}, NewHTTPError(nil, "not found", http.StatusNotFound)
+	}
+}
+
+type batchUserResource struct {
+	integrityUserResource
+	batches [][]string
+}
+
+func (s *batchUserResource) FindMissingIDs(ids []string, req Request) ([]string, error) {
+	s.batches = append(s.batches, ids)
+	missing := []string{}
+	for _, id := range ids {
+		if id != "1" && id != "2" {
+			missing = append(missing, id)
+		}
+	}
+
+	return missing, nil
+}
+
+var _ = Describe("Referential integrity", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		boards *integrityBoardResource
+		users  *integrityUserResource
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.EnableReferentialIntegrity()
+		boards = &integrityBoardResource{board: Board{ID: "1", OwnerID: "1", MemberIDs: []string{"1"}}}
+		users = &integrityUserResource{}
+		api.AddResource(Board{}, boards)
+		api.AddResource(integrityUser{}, users)
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("creates resources with existing references", func() {
+		doRequest("POST", "/v1/boards", `{"data": {"type": "boards", "attributes": {"name": "Kanban"}, "relationships": {
+			"owner": {"data": {"type": "users", "id": "1"}},
+			"members": {"data": [{"type": "users", "id": "1"}, {"type": "users", "id": "2"}]}
+		}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(users.lookups).To(Equal([]string{"1", "2"}))
+	})
+
+	It("rejects references to missing resources with 404", func() {
+		doRequest("POST", "/v1/boards", `{"data": {"type": "boards", "attributes": {"name": "Kanban"}, "relationships": {
+			"owner": {"data": {"type": "users", "id": "999"}},
+			"members": {"data": [{"type": "users", "id": "1"}, {"type": "users", "id": "empty"}]}
+		}}}`)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(boards.created).To(BeNil())
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{
+			"status": "404",
+			"code": "API2GO_RELATED_RESOURCE_NOT_FOUND",
+			"title": "Related resource not found",
+			"detail": "There is no users with id empty",
+			"source": {"pointer": "/data/relationships/members/data/1"}
+		}, {
+			"status": "404",
+			"code": "API2GO_RELATED_RESOURCE_NOT_FOUND",
+			"title": "Related resource not found",
+			"detail": "There is no users with id 999",
+			"source": {"pointer": "/data/relationships/owner/data"}
+		}]}`))
+	})
+
+	It("rejects references with the wrong type with 422", func() {
+		doRequest("PATCH", "/v1/boards/1", `{"data": {"type": "boards", "id": "1", "relationships": {
+			"members": {"data": [{"type": "chocolates", "id": "1"}]}
+		}}}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(boards.updated).To(BeNil())
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{
+			"status": "422",
+			"code": "API2GO_INVALID_RELATIONSHIP_TYPE",
+			"title": "Invalid relationship type",
+			"detail": "Relationship members expects type users, got chocolates",
+			"source": {"pointer": "/data/relationships/members/data/0/type"}
+		}]}`))
+	})
+
+	It("rejects unknown relationships with 422", func() {
+		doRequest("POST", "/v1/boards", `{"data": {"type": "boards", "attributes": {"name": "Kanban"}, "relationships": {
+			"owner": {"data": {"type": "users", "id": "1"}},
+			"watchers": {"data": [{"type": "users", "id": "1"}]}
+		}}}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(boards.created).To(BeNil())
+		Expect(users.lookups).To(BeEmpty())
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{
+			"status": "422",
+			"code": "API2GO_UNKNOWN_RELATIONSHIP",
+			"title": "Unknown relationship",
+			"detail": "Resource boards has no relationship watchers",
+			"source": {"pointer": "/data/relationships/watchers"}
+		}]}`))
+	})
+
+	It("rejects references to unregistered types with 422", func() {
+		api = NewAPI("v1")
+		api.EnableReferentialIntegrity()
+		api.AddResource(Board{}, boards)
+		doRequest("PATCH", "/v1/boards/1", `{"data": {"type": "boards", "id": "1", "relationships": {
+			"owner": {"data": {"type": "users", "id": "1"}}
+		}}}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(boards.updated).To(BeNil())
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{
+			"status": "422",
+			"code": "API2GO_UNKNOWN_RESOURCE_TYPE",
+			"title": "Unknown resource type",
+			"detail": "Relationship owner references the unknown type users",
+			"source": {"pointer": "/data/relationships/owner/data/type"}
+		}]}`))
+
+		rec = httptest.NewRecorder()
+		doRequest("DELETE", "/v1/boards/1/relationships/members", `{"data": [{"type": "users", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/data/0/type"}`))
+	})
+
+	It("returns other errors of FindOne", func() {
+		doRequest("PATCH", "/v1/boards/1", `{"data": {"type": "boards", "id": "1", "relationships": {
+			"owner": {"data": {"type": "users", "id": "broken"}}
+		}}}`)
+		Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	It("checks relationship routes", func() {
+		doRequest("PATCH", "/v1/boards/1/relationships/owner", `{"data": {"type": "users", "id": "999"}}`)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/data"}`))
+		Expect(boards.updated).To(BeNil())
+
+		rec = httptest.NewRecorder()
+		doRequest("POST", "/v1/boards/1/relationships/members", `{"data": [{"type": "users", "id": "2"}, {"type": "users", "id": "999"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/data/1"}`))
+		Expect(boards.updated).To(BeNil())
+	})
+
+	It("allows to clear to-one relationships", func() {
+		doRequest("PATCH", "/v1/boards/1/relationships/owner", `{"data": null}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+	})
+
+	It("only checks the type when removing references", func() {
+		doRequest("DELETE", "/v1/boards/1/relationships/members", `{"data": [{"type": "users", "id": "999"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(users.lookups).To(BeEmpty())
+
+		rec = httptest.NewRecorder()
+		doRequest("DELETE", "/v1/boards/1/relationships/members", `{"data": [{"type": "chocolates", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+	})
+
+	It("uses the ExistenceChecker interface for batch lookups", func() {
+		api = NewAPI("v1")
+		api.EnableReferentialIntegrity()
+		batchUsers := &batchUserResource{}
+		api.AddResource(Board{}, boards)
+		api.AddResource(integrityUser{}, batchUsers)
+		doRequest("POST", "/v1/boards", `{"data": {"type": "boards", "relationships": {
+			"owner": {"data": {"type": "users", "id": "1"}},
+			"members": {"data": [{"type": "users", "id": "1"}, {"type": "users", "id": "3"}]}
+		}}}`)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/data/relationships/members/data/1"}`))
+		Expect(batchUsers.batches).To(Equal([][]string{{"1", "3"}}))
+		Expect(batchUsers.lookups).To(BeEmpty())
+	})
+
+	It("does not check anything if it is not enabled", func() {
+		api = NewAPI("v1")
+		api.AddResource(Board{}, boards)
+		api.AddResource(integrityUser{}, users)
+		doRequest("POST", "/v1/boards", `{"data": {"type": "boards", "relationships": {
+			"members": {"data": [{"type": "chocolates", "id": "999"}]}
+		}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(users.lookups).To(BeEmpty())
+	})
+})
@@ -0,0 +1,128 @@
+// Package resourceutil contains the helpers that the resource implementations
+// of the memory and sqlresource packages share.
+package resourceutil
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/manyminds/api2go"
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// Prototype converts between the objects passed to a resource, which have the
+// type of the prototype (a struct or a struct pointer), and pointers to structs
+// that are used internally
+type Prototype struct {
+	Type reflect.Type
+}
+
+// StructType returns the struct type of the prototype
+func (p Prototype) StructType() reflect.Type {
+	if p.Type.Kind() == reflect.Ptr {
+		return p.Type.Elem()
+	}
+
+	return p.Type
+}
+
+// Copy returns a pointer to a copy of obj
+func (p Prototype) Copy(obj interface{}) (reflect.Value, error) {
+	value := reflect.ValueOf(obj)
+	if !value.IsValid() || value.Type() != p.Type {
+		err := fmt.Errorf("expected an object of type %s but got %T", p.Type, obj)
+		return reflect.Value{}, api2go.NewHTTPError(err, err.Error(), http.StatusInternalServerError)
+	}
+
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return reflect.Value{}, api2go.NewHTTPError(nil, "object must not be nil", http.StatusInternalServerError)
+		}
+		value = value.Elem()
+	}
+
+	object := reflect.New(value.Type())
+	object.Elem().Set(value)
+	return object, nil
+}
+
+// Export returns a copy of an object in the form of the prototype
+func (p Prototype) Export(object reflect.Value) interface{} {
+	if p.Type.Kind() == reflect.Struct {
+		return object.Elem().Interface()
+	}
+
+	exported := reflect.New(p.Type.Elem())
+	exported.Elem().Set(object.Elem())
+	return exported.Interface()
+}
+
+// ToSlice returns copies of the objects as slice of the prototype type
+func (p Prototype) ToSlice(objects []reflect.Value) interface{} {
+	slice := reflect.MakeSlice(reflect.SliceOf(p.Type), 0, len(objects))
+	for _, object := range objects {
+		slice = reflect.Append(slice, reflect.ValueOf(p.Export(object)))
+	}
+
+	return slice.Interface()
+}
+
+// GetID returns the id of an object or an empty string if it has none
+func GetID(object reflect.Value) string {
+	if identifier, ok := jsonapi.Adapt(object.Interface()).(jsonapi.MarshalIdentifier); ok {
+		return identifier.GetID()
+	}
+
+	return ""
+}
+
+// SetID sets the id of a pointer to an object
+func SetID(object reflect.Value, ID string) error {
+	identifier, ok := jsonapi.Adapt(object.Interface()).(jsonapi.UnmarshalIdentifier)
+	if !ok {
+		return fmt.Errorf("%s does not implement jsonapi.UnmarshalIdentifier", object.Type())
+	}
+
+	return identifier.SetID(ID)
+}
+
+// NotFound returns the 404 error for an unknown id
+func NotFound(ID string) error {
+	message := fmt.Sprintf("object with id %s not found", ID)
+	return api2go.NewHTTPError(nil, message, http.StatusNotFound)
+}
+
+// GetPage returns the offset and limit of the pagination parameters, the limit
+// is negative if all objects are requested
+func GetPage(pagination map[string]string) (int, int, error) {
+	values := map[string]int{}
+	for _, key := range []string{"number", "size", "offset", "limit"} {
+		value, ok := pagination[key]
+		if !ok {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			message := fmt.Sprintf("invalid value %q for page[%s]", value, key)
+			return 0, 0, api2go.NewHTTPError(err, message, http.StatusBadRequest)
+		}
+		values[key] = parsed
+	}
+
+	if size, ok := values["size"]; ok {
+		number := values["number"]
+		if number < 1 {
+			number = 1
+		}
+		return (number - 1) * size, size, nil
+	}
+
+	if limit, ok := values["limit"]; ok {
+		return values["offset"], limit, nil
+	}
+
+	return values["offset"], -1, nil
+}
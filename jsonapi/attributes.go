@@ -0,0 +1,190 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// reservedAttributes are the member names that must not be used for attributes
+var reservedAttributes = []string{"id", "type"}
+
+// Options change how a single call marshals or unmarshals resources
+type Options struct {
+	// LegacyAttributes restores the legacy behavior for attributes. By default,
+	// the `id` and `type` members, the member of the ID field and members with
+	// the name of a relationship are removed from the attributes when
+	// marshalling and rejected when unmarshalling, because the specification
+	// does not allow them. With legacy attributes, the json encoding of the
+	// struct is used unchanged.
+	//
+	// Only an exported field named ID is known to hold the id and only members
+	// whose name equals the name of a Reference are known to be relationships.
+	// Fields that hold the id or a relationship under another name must be
+	// excluded with `json:"-"`.
+	LegacyAttributes bool
+
+	// Fields contains the sparse fieldsets by type name. Only the listed
+	// attributes of these types are marshalled, unknown names are ignored.
+	// Types without an entry keep all attributes.
+	Fields map[string][]string
+
+	// Codec encodes and decodes the attributes of the resources and is
+	// passed the documents of MarshalWithOptions and UnmarshalWithOptions.
+	// The MarshalJSON and UnmarshalJSON methods of the document structs, e.g.
+	// for links and relationships, still use the codec of the package. If
+	// nil, the codec of the package is used for everything, see SetJSONCodec.
+	Codec JSONCodec
+}
+
+// jsonCodec returns the codec of the options or the one of the package
+func (o Options) jsonCodec() JSONCodec {
+	if o.Codec != nil {
+		return o.Codec
+	}
+
+	return GetJSONCodec()
+}
+
+// optionsInformation passes the options of a marshal call along with the
+// ServerInformation, which may be nil
+type optionsInformation struct {
+	ServerInformation
+	options Options
+}
+
+// withOptions adds the options to information
+func withOptions(information ServerInformation, options Options) ServerInformation {
+	if wrapped, ok := information.(optionsInformation); ok {
+		information = wrapped.ServerInformation
+	}
+
+	return optionsInformation{ServerInformation: information, options: options}
+}
+
+// splitOptions returns the options added with withOptions and the original
+// information
+func splitOptions(information ServerInformation) (ServerInformation, Options) {
+	if wrapped, ok := information.(optionsInformation); ok {
+		return wrapped.ServerInformation, wrapped.options
+	}
+
+	return information, Options{}
+}
+
+// forbiddenAttributes returns the member names that must not be attributes of
+// element. Element may be nil, then only the relationships of tagged types are
+// known.
+func forbiddenAttributes(element interface{}, info *typeInfo) []string {
+	if info.forbidden != nil {
+		return info.forbidden
+	}
+
+	forbidden := append([]string{}, reservedAttributes...)
+	if info.idAttribute != "" {
+		forbidden = append(forbidden, info.idAttribute)
+	}
+
+	if references, ok := element.(MarshalReferences); ok {
+		for _, reference := range references.GetReferences() {
+			forbidden = append(forbidden, reference.Name)
+		}
+	}
+
+	return forbidden
+}
+
+// selectAttributes removes all forbidden members and, if a sparse fieldset is
+// given for the type, all members that were not requested from the marshalled
+// attributes. The remaining members are written in the order of the struct
+// fields that is kept in the type cache, so their values are never decoded.
+// Types with a custom json encoding are responsible for their forbidden
+// attributes themselves and keep the order of the json encoding.
+func selectAttributes(element interface{}, info *typeInfo, typeName string, attributes []byte, options Options) ([]byte, error) {
+	fields, sparse := options.Fields[typeName]
+	if !sparse && (info.attributes == nil || options.LegacyAttributes) {
+		return attributes, nil
+	}
+
+	var forbidden []string
+	if info.attributes != nil && !options.LegacyAttributes {
+		forbidden = forbiddenAttributes(element, info)
+	}
+
+	keep := func(name string) bool {
+		return (!sparse || containsString(fields, name)) && !containsString(forbidden, name)
+	}
+
+	if info.attributes != nil {
+		removed := false
+		for _, name := range info.attributes {
+			if !keep(name) {
+				removed = true
+				break
+			}
+		}
+
+		if !removed {
+			return attributes, nil
+		}
+	}
+
+	members := map[string]json.RawMessage{}
+	if err := options.jsonCodec().Unmarshal(attributes, &members); err != nil {
+		return nil, err
+	}
+
+	names := info.attributes
+	if names == nil {
+		names = fields
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for _, name := range names {
+		value, ok := members[name]
+		if !ok || !keep(name) {
+			continue
+		}
+
+		key, err := options.jsonCodec().Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if buffer.Len() > 1 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+
+	return buffer.Bytes(), nil
+}
+
+// checkForbiddenAttributes returns an error if the attributes of a record
+// contain a member that is forbidden for the target
+func checkForbiddenAttributes(attributes json.RawMessage, target interface{}, options Options) error {
+	if attributes == nil || options.LegacyAttributes {
+		return nil
+	}
+
+	members := map[string]json.RawMessage{}
+	if err := options.jsonCodec().Unmarshal(attributes, &members); err != nil {
+		return err
+	}
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	for _, name := range forbiddenAttributes(target, getTypeInfo(target)) {
+		if _, ok := members[name]; ok {
+			return fmt.Errorf(`invalid attribute "%s", the name is reserved for the id, the type or a relationship`, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package jsonapi
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Headline struct {
+	ID       string
+	Title    string   `json:"title"`
+	Type     string   `json:"type"`
+	Comments []string `json:"comments"`
+}
+
+func (a Headline) GetID() string {
+	return a.ID
+}
+
+func (a *Headline) SetID(ID string) error {
+	a.ID = ID
+	return nil
+}
+
+func (a Headline) GetReferences() []Reference {
+	return []Reference{{Type: "comments", Name: "comments"}}
+}
+
+func (a Headline) GetReferencedIDs() []ReferenceID {
+	referenceIDs := []ReferenceID{}
+	for _, ID := range a.Comments {
+		referenceIDs = append(referenceIDs, ReferenceID{ID: ID, Type: "comments", Name: "comments"})
+	}
+
+	return referenceIDs
+}
+
+func (a *Headline) SetToManyReferenceIDs(name string, IDs []string) error {
+	a.Comments = IDs
+	return nil
+}
+
+// Byline holds its id and its relationship in fields with other names, which
+// are not recognized as forbidden attributes
+type Byline struct {
+	Key    string `json:"key"`
+	Author string `json:"writer"`
+	Text   string `json:"text"`
+}
+
+func (b Byline) GetID() string {
+	return b.Key
+}
+
+func (b Byline) GetReferences() []Reference {
+	return []Reference{{Type: "authors", Name: "author"}}
+}
+
+func (b Byline) GetReferencedIDs() []ReferenceID {
+	return []ReferenceID{{ID: b.Author, Type: "authors", Name: "author"}}
+}
+
+var _ = Describe("Forbidden attributes", func() {
+	article := Headline{ID: "1", Title: "Hello", Type: "news", Comments: []string{"2"}}
+
+	Context("When marshalling", func() {
+		It("removes the id, type and relationship members", func() {
+			result, err := Marshal(article)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{
+				"data": {
+					"type": "headlines",
+					"id": "1",
+					"attributes": {"title": "Hello"},
+					"relationships": {"comments": {"data": [{"type": "comments", "id": "2"}]}}
+				}
+			}`))
+		})
+
+		It("does not return them as attribute names", func() {
+			_, err := Marshal(article)
+			Expect(err).ToNot(HaveOccurred())
+			names, ok := AttributeNames("headlines")
+			Expect(ok).To(BeTrue())
+			Expect(names).To(Equal([]string{"title", "comments"}))
+		})
+
+		It("keeps them with legacy attributes", func() {
+			result, err := MarshalWithOptions(article, nil, Options{LegacyAttributes: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ContainSubstring(`"attributes":{"ID":"1","title":"Hello","type":"news","comments":["2"]}`))
+
+			names, ok := AttributeNamesWithOptions("headlines", Options{LegacyAttributes: true})
+			Expect(ok).To(BeTrue())
+			Expect(names).To(Equal([]string{"ID", "title", "type", "comments"}))
+		})
+
+		It("only applies legacy attributes to the call", func() {
+			_, err := MarshalWithOptions(article, nil, Options{LegacyAttributes: true})
+			Expect(err).ToNot(HaveOccurred())
+			result, err := Marshal(article)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ContainSubstring(`"attributes":{"title":"Hello"}`))
+		})
+
+		It("keeps them in streams with legacy attributes", func() {
+			records := []MarshalIdentifier{article}
+			next := IteratorFunc(func() (MarshalIdentifier, error) {
+				if len(records) == 0 {
+					return nil, nil
+				}
+				record := records[0]
+				records = records[1:]
+				return record, nil
+			})
+
+			var buffer bytes.Buffer
+			stream := &Stream{Records: next, Options: Options{LegacyAttributes: true}}
+			Expect(MarshalTo(&buffer, stream, nil)).To(Succeed())
+			Expect(buffer.String()).To(ContainSubstring(`"attributes":{"ID":"1","title":"Hello","type":"news","comments":["2"]}`))
+		})
+
+		It("keeps fields for the id or relationships under other names", func() {
+			result, err := Marshal(Byline{Key: "1", Author: "2", Text: "Hello"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ContainSubstring(`"attributes":{"key":"1","writer":"2","text":"Hello"}`))
+		})
+	})
+
+	Context("With sparse fieldsets", func() {
+		byline := Byline{Key: "1", Author: "2", Text: "Hello"}
+
+		It("only marshals the requested attributes in the order of the fields", func() {
+			options := Options{Fields: map[string][]string{"bylines": {"text", "key", "unknown"}}}
+			result, err := MarshalWithOptions(byline, nil, options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ContainSubstring(`"attributes":{"key":"1","text":"Hello"}`))
+		})
+
+		It("removes all attributes for an empty fieldset", func() {
+			options := Options{Fields: map[string][]string{"bylines": {}}}
+			result, err := MarshalWithOptions(byline, nil, options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ContainSubstring(`"attributes":{}`))
+		})
+
+		It("keeps all attributes of other types", func() {
+			options := Options{Fields: map[string][]string{"headlines": {"title"}}}
+			result, err := MarshalWithOptions(byline, nil, options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ContainSubstring(`"attributes":{"key":"1","writer":"2","text":"Hello"}`))
+		})
+
+		It("still removes forbidden attributes", func() {
+			options := Options{Fields: map[string][]string{"headlines": {"title", "type", "comments"}}}
+			result, err := MarshalWithOptions(article, nil, options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ContainSubstring(`"attributes":{"title":"Hello"}`))
+
+			options.LegacyAttributes = true
+			result, err = MarshalWithOptions(article, nil, options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ContainSubstring(`"attributes":{"title":"Hello","type":"news","comments":["2"]}`))
+		})
+
+		It("filters types with a custom json encoding", func() {
+			name := getStructType(CustomJSONDocument{})
+			options := Options{Fields: map[string][]string{name: {"other", "custom"}}}
+			result, err := MarshalWithOptions(CustomJSONDocument{ID: "1"}, nil, options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ContainSubstring(`"attributes":{"custom":"yes"}`))
+
+			options.Fields[name] = []string{"other"}
+			result, err = MarshalWithOptions(CustomJSONDocument{ID: "1"}, nil, options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ContainSubstring(`"attributes":{}`))
+		})
+	})
+
+	Context("When unmarshalling", func() {
+		for _, name := range []string{"id", "type", "ID", "comments"} {
+			name := name
+			It("rejects the "+name+" member", func() {
+				var target Headline
+				err := Unmarshal([]byte(`{"data": {"type": "headlines", "id": "1", "attributes": {"title": "Hello", "`+name+`": "2"}}}`), &target)
+				Expect(err).To(MatchError(`invalid attribute "` + name + `", the name is reserved for the id, the type or a relationship`))
+			})
+		}
+
+		It("accepts them with legacy attributes", func() {
+			var target Headline
+			err := UnmarshalWithOptions([]byte(`{"data": {"type": "headlines", "id": "1", "attributes": {"title": "Hello", "type": "news"}}}`), &target, Options{LegacyAttributes: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal(Headline{ID: "1", Title: "Hello", Type: "news"}))
+		})
+	})
+})
@@ -120,3 +120,50 @@ func BenchmarkUnmarshalWithRelationships(b *testing.B) {
 		}
 	}
 }
+
+func largePostCollection() []Post {
+	posts := make([]Post, 1000)
+	for i := range posts {
+		posts[i] = Post{
+			ID:          i,
+			Title:       "Title",
+			Comments:    []Comment{{ID: i, Text: "Comment"}},
+			CommentsIDs: []int{i},
+		}
+	}
+
+	return posts
+}
+
+func BenchmarkMarshalLargeSlice(b *testing.B) {
+	posts := largePostCollection()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := Marshal(posts)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func BenchmarkMarshalLargeSliceWithSparseFieldsets(b *testing.B) {
+	posts := largePostCollection()
+	options := Options{Fields: map[string][]string{"posts": {}, "comments": {"text"}}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := MarshalWithOptions(posts, nil, options)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func BenchmarkGetStructType(b *testing.B) {
+	comment := Comment{ID: 1, Text: "Comment"}
+
+	for i := 0; i < b.N; i++ {
+		getStructType(comment)
+	}
+}
@@ -0,0 +1,83 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// The JSONCodec interface can be implemented to replace encoding/json, e.g.
+// with a faster drop-in replacement. It must behave like encoding/json,
+// including calls to the MarshalJSON and UnmarshalJSON methods of the
+// jsonapi structs.
+type JSONCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// StandardJSONCodec is the default JSONCodec which uses encoding/json.
+type StandardJSONCodec struct{}
+
+// Marshal calls json.Marshal
+func (StandardJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal calls json.Unmarshal
+func (StandardJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// codecHolder wraps the codec because atomic.Value requires values of the
+// same concrete type
+type codecHolder struct {
+	codec JSONCodec
+}
+
+var currentCodec atomic.Value
+
+func init() {
+	currentCodec.Store(codecHolder{StandardJSONCodec{}})
+}
+
+// SetJSONCodec replaces the JSONCodec that is used for all json encoding and
+// decoding of this package. Passing nil restores the StandardJSONCodec.
+func SetJSONCodec(codec JSONCodec) {
+	if codec == nil {
+		codec = StandardJSONCodec{}
+	}
+
+	currentCodec.Store(codecHolder{codec})
+}
+
+// GetJSONCodec returns the JSONCodec of this package.
+func GetJSONCodec() JSONCodec {
+	return currentCodec.Load().(codecHolder).codec
+}
+
+func jsonMarshal(v interface{}) ([]byte, error) {
+	return GetJSONCodec().Marshal(v)
+}
+
+func jsonUnmarshal(data []byte, v interface{}) error {
+	return GetJSONCodec().Unmarshal(data, v)
+}
+
+// marshalAttributes returns the attributes of element encoded with codec. The
+// attributes of tagged resources are encoded field by field, so their values
+// are encoded with codec as well.
+func marshalAttributes(element interface{}, codec JSONCodec) ([]byte, error) {
+	if tagged, ok := element.(*taggedResource); ok {
+		return tagged.marshalAttributes(codec)
+	}
+
+	return codec.Marshal(element)
+}
+
+// unmarshalAttributes decodes the attributes into target with codec
+func unmarshalAttributes(attributes []byte, target interface{}, codec JSONCodec) error {
+	if tagged, ok := target.(*taggedResource); ok {
+		return tagged.unmarshalAttributes(attributes, codec)
+	}
+
+	return codec.Unmarshal(attributes, target)
+}
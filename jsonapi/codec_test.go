@@ -0,0 +1,92 @@
+package jsonapi
+
+import (
+	"errors"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// failingCodec returns an error for every call
+type failingCodec struct{}
+
+func (failingCodec) Marshal(v interface{}) ([]byte, error) {
+	return nil, errors.New("marshal failed")
+}
+
+func (failingCodec) Unmarshal(data []byte, v interface{}) error {
+	return errors.New("unmarshal failed")
+}
+
+var _ = Describe("JSONCodec", func() {
+	var previous JSONCodec
+
+	BeforeEach(func() {
+		previous = GetJSONCodec()
+	})
+
+	AfterEach(func() {
+		SetJSONCodec(previous)
+	})
+
+	It("uses the StandardJSONCodec by default", func() {
+		SetJSONCodec(nil)
+		Expect(GetJSONCodec()).To(Equal(StandardJSONCodec{}))
+	})
+
+	It("uses the codec for marshalling", func() {
+		SetJSONCodec(failingCodec{})
+		_, err := Marshal(Comment{ID: 1, Text: "First!"})
+		Expect(err).To(MatchError("marshal failed"))
+	})
+
+	It("uses the codec for unmarshalling", func() {
+		SetJSONCodec(failingCodec{})
+		var comment Comment
+		err := Unmarshal([]byte(`{"data": {"type": "comments", "id": "1"}}`), &comment)
+		Expect(err).To(MatchError("unmarshal failed"))
+	})
+
+	It("uses the codec for the document structs", func() {
+		codec := &countingCodec{}
+		SetJSONCodec(codec)
+		_, err := (&DataContainer{DataObject: &Data{Type: "comments", ID: "1"}}).MarshalJSON()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(atomic.LoadInt64(&codec.calls)).To(BeNumerically(">", 0))
+	})
+
+	It("uses the codec of the options instead of the one of the package", func() {
+		SetJSONCodec(failingCodec{})
+		codec := &countingCodec{}
+		options := Options{Codec: codec}
+
+		payload, err := MarshalToStructWithOptions(Comment{ID: 1, Text: "First!"}, nil, options)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(payload.Data.DataObject.Attributes)).To(MatchJSON(`{"text": "First!"}`))
+		Expect(atomic.LoadInt64(&codec.calls)).To(BeNumerically(">", 0))
+
+		// the document structs are decoded with the codec of the package
+		SetJSONCodec(nil)
+		calls := atomic.LoadInt64(&codec.calls)
+		var comment Comment
+		err = UnmarshalWithOptions([]byte(`{"data": {"type": "comments", "id": "1", "attributes": {"text": "First!"}}}`), &comment, options)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(comment.Text).To(Equal("First!"))
+		Expect(atomic.LoadInt64(&codec.calls)).To(BeNumerically(">", calls))
+	})
+
+	It("uses the codec of the options for the attributes of tagged resources", func() {
+		SetJSONCodec(failingCodec{})
+		options := Options{Codec: StandardJSONCodec{}}
+
+		payload, err := MarshalToStructWithOptions(TaggedUser{ID: 1, Name: "Marvin"}, nil, options)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(payload.Data.DataObject.Attributes)).To(MatchJSON(`{"user-name": "Marvin"}`))
+
+		var user TaggedUser
+		err = setDataIntoTarget(&Data{Type: "members", ID: "1", Attributes: []byte(`{"user-name": "Marvin"}`)}, &user, options)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user.Name).To(Equal("Marvin"))
+	})
+})
@@ -11,13 +11,104 @@ var arraySuffix = []byte("[")
 var stringSuffix = []byte(`"`)
 
 // A Document represents a JSON API document as specified here: http://jsonapi.org.
+//
+// Profiles contains the profiles that are applied to the document, they are
+// marshaled into the "profile" member of the top-level links object.
 type Document struct {
+	JSONAPI  *JSONAPI               `json:"jsonapi,omitempty"`
 	Links    Links                  `json:"links,omitempty"`
+	Profiles []Link                 `json:"-"`
 	Data     *DataContainer         `json:"data"`
 	Included []Data                 `json:"included,omitempty"`
+	Errors   []Error                `json:"errors,omitempty"`
 	Meta     map[string]interface{} `json:"meta,omitempty"`
 }
 
+// JSONAPI describes the server implementation in the top-level jsonapi member
+// of a document as specified here: http://jsonapi.org/format/#document-jsonapi-object
+type JSONAPI struct {
+	Version string                 `json:"version,omitempty"`
+	Ext     []string               `json:"ext,omitempty"`
+	Profile []string               `json:"profile,omitempty"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+}
+
+// document has the same fields as Document but no custom (un)marshal methods.
+type document Document
+
+// MarshalJSON adds the Profiles field to the top-level links object. The data
+// member is omitted for error documents, because it must not coexist with the
+// errors member.
+func (d Document) MarshalJSON() ([]byte, error) {
+	isErrorDocument := len(d.Errors) > 0 && d.Data == nil
+	if len(d.Profiles) == 0 && !isErrorDocument {
+		return jsonMarshal(document(d))
+	}
+
+	var links map[string]interface{}
+	if len(d.Profiles) > 0 || len(d.Links) > 0 {
+		links = map[string]interface{}{}
+	}
+	if len(d.Profiles) > 0 {
+		links["profile"] = d.Profiles
+	}
+	for name, link := range d.Links {
+		links[name] = link
+	}
+
+	// the outer fields hide the ones of the embedded document
+	if isErrorDocument {
+		return jsonMarshal(struct {
+			Links map[string]interface{} `json:"links,omitempty"`
+			Data  *DataContainer         `json:"data,omitempty"`
+			document
+		}{links, nil, document(d)})
+	}
+
+	return jsonMarshal(struct {
+		Links map[string]interface{} `json:"links"`
+		document
+	}{links, document(d)})
+}
+
+// UnmarshalJSON extracts the "profile" member of the top-level links object
+// into the Profiles field.
+func (d *Document) UnmarshalJSON(payload []byte) error {
+	var raw struct {
+		Links map[string]json.RawMessage `json:"links"`
+		document
+	}
+
+	err := jsonUnmarshal(payload, &raw)
+	if err != nil {
+		return err
+	}
+
+	*d = Document(raw.document)
+	for name, value := range raw.Links {
+		if name == "profile" {
+			err = jsonUnmarshal(value, &d.Profiles)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		var link Link
+		err = jsonUnmarshal(value, &link)
+		if err != nil {
+			return err
+		}
+
+		if d.Links == nil {
+			d.Links = make(Links)
+		}
+		d.Links[name] = link
+	}
+
+	return nil
+}
+
 // A DataContainer is used to marshal and unmarshal single objects and arrays
 // of objects.
 type DataContainer struct {
@@ -29,11 +120,11 @@ type DataContainer struct {
 // root element is an object or to the DataArray field for arrays.
 func (c *DataContainer) UnmarshalJSON(payload []byte) error {
 	if bytes.HasPrefix(payload, objectSuffix) {
-		return json.Unmarshal(payload, &c.DataObject)
+		return jsonUnmarshal(payload, &c.DataObject)
 	}
 
 	if bytes.HasPrefix(payload, arraySuffix) {
-		return json.Unmarshal(payload, &c.DataArray)
+		return jsonUnmarshal(payload, &c.DataArray)
 	}
 
 	return errors.New("expected a JSON encoded object or array")
@@ -43,10 +134,10 @@ func (c *DataContainer) UnmarshalJSON(payload []byte) error {
 // field. It will return "null" if neither of them is set.
 func (c *DataContainer) MarshalJSON() ([]byte, error) {
 	if c.DataArray != nil {
-		return json.Marshal(c.DataArray)
+		return jsonMarshal(c.DataArray)
 	}
 
-	return json.Marshal(c.DataObject)
+	return jsonMarshal(c.DataObject)
 }
 
 // Link represents a link for return in the document.
@@ -59,12 +150,12 @@ type Link struct {
 // object value into the whole struct.
 func (l *Link) UnmarshalJSON(payload []byte) error {
 	if bytes.HasPrefix(payload, stringSuffix) {
-		return json.Unmarshal(payload, &l.Href)
+		return jsonUnmarshal(payload, &l.Href)
 	}
 
 	if bytes.HasPrefix(payload, objectSuffix) {
 		obj := make(map[string]interface{})
-		err := json.Unmarshal(payload, &obj)
+		err := jsonUnmarshal(payload, &obj)
 		if err != nil {
 			return err
 		}
@@ -84,9 +175,9 @@ func (l *Link) UnmarshalJSON(payload []byte) error {
 // field is empty, otherwise it marshals the whole struct.
 func (l Link) MarshalJSON() ([]byte, error) {
 	if len(l.Meta) == 0 {
-		return json.Marshal(l.Href)
+		return jsonMarshal(l.Href)
 	}
-	return json.Marshal(map[string]interface{}{
+	return jsonMarshal(map[string]interface{}{
 		"href": l.Href,
 		"meta": l.Meta,
 	})
@@ -102,6 +193,11 @@ type Data struct {
 	Attributes    json.RawMessage         `json:"attributes"`
 	Relationships map[string]Relationship `json:"relationships,omitempty"`
 	Links         Links                   `json:"links,omitempty"`
+	Meta          map[string]interface{}  `json:"meta,omitempty"`
+
+	// included records of the document, only set for records that are passed
+	// to SetReferencedStructs
+	included *includedRecords
 }
 
 // Relationship contains reference IDs to the related structs
@@ -123,12 +219,12 @@ type RelationshipDataContainer struct {
 func (c *RelationshipDataContainer) UnmarshalJSON(payload []byte) error {
 	if bytes.HasPrefix(payload, objectSuffix) {
 		// payload is an object
-		return json.Unmarshal(payload, &c.DataObject)
+		return jsonUnmarshal(payload, &c.DataObject)
 	}
 
 	if bytes.HasPrefix(payload, arraySuffix) {
 		// payload is an array
-		return json.Unmarshal(payload, &c.DataArray)
+		return jsonUnmarshal(payload, &c.DataArray)
 	}
 
 	return errors.New("Invalid json for relationship data array/object")
@@ -138,9 +234,9 @@ func (c *RelationshipDataContainer) UnmarshalJSON(payload []byte) error {
 // field. It will return "null" if neither of them is set.
 func (c *RelationshipDataContainer) MarshalJSON() ([]byte, error) {
 	if c.DataArray != nil {
-		return json.Marshal(c.DataArray)
+		return jsonMarshal(c.DataArray)
 	}
-	return json.Marshal(c.DataObject)
+	return jsonMarshal(c.DataObject)
 }
 
 // RelationshipData represents one specific reference ID.
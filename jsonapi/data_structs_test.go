@@ -232,4 +232,93 @@ var _ = Describe("JSONAPI Struct tests", func() {
 			}
 		})
 	})
+
+	Context("Marshal and Unmarshal profile links", func() {
+		It("marshals profiles into the top-level links object", func() {
+			document := Document{
+				Links:    Links{"self": Link{Href: "/posts"}},
+				Profiles: []Link{{Href: "https://example.com/profiles/timestamps"}},
+				Data:     &DataContainer{DataArray: []Data{}},
+			}
+			result, err := json.Marshal(document)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{
+				"links": {
+					"self": "/posts",
+					"profile": ["https://example.com/profiles/timestamps"]
+				},
+				"data": []
+			}`))
+		})
+
+		It("marshals documents without profiles as usual", func() {
+			result, err := json.Marshal(&Document{Data: &DataContainer{}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{"data": null}`))
+		})
+
+		It("unmarshals profiles from the top-level links object", func() {
+			var document Document
+			err := json.Unmarshal([]byte(`{
+				"links": {
+					"self": "/posts",
+					"profile": [
+						"https://example.com/profiles/timestamps",
+						{"href": "https://example.com/profiles/cursors", "meta": {"version": 2}}
+					]
+				},
+				"data": []
+			}`), &document)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.Links).To(Equal(Links{"self": Link{Href: "/posts"}}))
+			Expect(document.Profiles).To(Equal([]Link{
+				{Href: "https://example.com/profiles/timestamps"},
+				{Href: "https://example.com/profiles/cursors", Meta: map[string]interface{}{"version": float64(2)}},
+			}))
+			Expect(document.Data.DataArray).To(Equal([]Data{}))
+		})
+
+		It("unmarshals with an error for invalid links", func() {
+			err := json.Unmarshal([]byte(`{"links": {"self": 13}, "data": null}`), &Document{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Marshal and Unmarshal the jsonapi object", func() {
+		It("marshals the jsonapi object", func() {
+			document := Document{
+				JSONAPI: &JSONAPI{
+					Version: "1.1",
+					Ext:     []string{"https://jsonapi.org/ext/atomic"},
+					Meta:    map[string]interface{}{"server": "api2go"},
+				},
+				Data: &DataContainer{},
+			}
+			result, err := json.Marshal(document)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{
+				"jsonapi": {
+					"version": "1.1",
+					"ext": ["https://jsonapi.org/ext/atomic"],
+					"meta": {"server": "api2go"}
+				},
+				"data": null
+			}`))
+		})
+
+		It("unmarshals the jsonapi object", func() {
+			var document Document
+			err := json.Unmarshal([]byte(`{
+				"jsonapi": {"version": "1.1", "profile": ["https://example.com/profiles/timestamps"]},
+				"links": {"describedby": "https://example.com/openapi.json"},
+				"data": null
+			}`), &document)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.JSONAPI).To(Equal(&JSONAPI{
+				Version: "1.1",
+				Profile: []string{"https://example.com/profiles/timestamps"},
+			}))
+			Expect(document.Links).To(Equal(Links{"describedby": Link{Href: "https://example.com/openapi.json"}}))
+		})
+	})
 })
@@ -0,0 +1,58 @@
+package jsonapi
+
+import "fmt"
+
+// Error is an error object of a top-level errors member. It has the same
+// fields as api2go.Error, so that error documents can be decoded without
+// depending on the api2go package. Use api2go.Error.ToJSONAPI and
+// api2go.ErrorFromJSONAPI to convert between them.
+//
+// for more information see http://jsonapi.org/format/#error-objects
+type Error struct {
+	ID     string       `json:"id,omitempty"`
+	Links  *ErrorLinks  `json:"links,omitempty"`
+	Status string       `json:"status,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Title  string       `json:"title,omitempty"`
+	Detail string       `json:"detail,omitempty"`
+	Source *ErrorSource `json:"source,omitempty"`
+	Meta   interface{}  `json:"meta,omitempty"`
+}
+
+// ErrorLinks contains an About URL that leads to further details about the
+// particular occurrence of the problem.
+type ErrorLinks struct {
+	About string `json:"about,omitempty"`
+}
+
+// ErrorSource contains references to the source of an error. Pointer is a JSON
+// Pointer to the associated entity in the request document, Parameter is the
+// query parameter that caused the error.
+type ErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// ErrorDocument is returned by Unmarshal if the source JSON is an error
+// document. It contains the decoded error objects.
+type ErrorDocument struct {
+	Errors []Error
+}
+
+// Error returns a string representation of the error document for logging purposes.
+func (e ErrorDocument) Error() string {
+	if len(e.Errors) == 0 {
+		return "error document without errors"
+	}
+
+	first := e.Errors[0]
+	msg := first.Title
+	if first.Detail != "" {
+		msg += ": " + first.Detail
+	}
+	if first.Status != "" {
+		msg = fmt.Sprintf("(%s) %s", first.Status, msg)
+	}
+
+	return fmt.Sprintf("error document %s and %d more errors", msg, len(e.Errors)-1)
+}
@@ -0,0 +1,79 @@
+package jsonapi
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Error documents", func() {
+	errorJSON := `{
+		"errors": [{
+			"id": "1",
+			"links": {"about": "http://example.com/errors/1"},
+			"status": "422",
+			"code": "INVALID",
+			"title": "Invalid attribute",
+			"detail": "title must not be empty",
+			"source": {"pointer": "/data/attributes/title"},
+			"meta": {"field": "title"}
+		}, {
+			"status": "422",
+			"title": "Invalid attribute",
+			"source": {"parameter": "include"}
+		}]
+	}`
+
+	expectedErrors := []Error{{
+		ID:     "1",
+		Links:  &ErrorLinks{About: "http://example.com/errors/1"},
+		Status: "422",
+		Code:   "INVALID",
+		Title:  "Invalid attribute",
+		Detail: "title must not be empty",
+		Source: &ErrorSource{Pointer: "/data/attributes/title"},
+		Meta:   map[string]interface{}{"field": "title"},
+	}, {
+		Status: "422",
+		Title:  "Invalid attribute",
+		Source: &ErrorSource{Parameter: "include"},
+	}}
+
+	It("unmarshals the errors member of a document", func() {
+		var document Document
+		err := json.Unmarshal([]byte(errorJSON), &document)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(document.Errors).To(Equal(expectedErrors))
+		Expect(document.Data).To(BeNil())
+	})
+
+	It("marshals error documents without data", func() {
+		result, err := json.Marshal(Document{Errors: expectedErrors, Links: Links{"self": {Href: "/posts"}}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`{
+			"links": {"self": "/posts"},
+			"errors": [{
+				"id": "1",
+				"links": {"about": "http://example.com/errors/1"},
+				"status": "422",
+				"code": "INVALID",
+				"title": "Invalid attribute",
+				"detail": "title must not be empty",
+				"source": {"pointer": "/data/attributes/title"},
+				"meta": {"field": "title"}
+			}, {
+				"status": "422",
+				"title": "Invalid attribute",
+				"source": {"parameter": "include"}
+			}]
+		}`))
+	})
+
+	It("returns the errors of error documents in Unmarshal", func() {
+		var post SimplePost
+		err := Unmarshal([]byte(errorJSON), &post)
+		Expect(err).To(Equal(ErrorDocument{Errors: expectedErrors}))
+		Expect(err.Error()).To(Equal("error document (422) Invalid attribute: title must not be empty and 1 more errors"))
+	})
+})
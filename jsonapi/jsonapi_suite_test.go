@@ -1,13 +1,44 @@
 package jsonapi
 
 import (
+	"os"
+	"sync/atomic"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"testing"
 )
 
+// countingCodec wraps the StandardJSONCodec and counts its calls
+type countingCodec struct {
+	StandardJSONCodec
+	calls int64
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	atomic.AddInt64(&c.calls, 1)
+	return c.StandardJSONCodec.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	atomic.AddInt64(&c.calls, 1)
+	return c.StandardJSONCodec.Unmarshal(data, v)
+}
+
 func TestJsonapi(t *testing.T) {
+	// API2GO_TEST_JSON_CODEC=counting runs the suite with a custom JSONCodec
+	if os.Getenv("API2GO_TEST_JSON_CODEC") == "counting" {
+		codec := &countingCodec{}
+		SetJSONCodec(codec)
+		defer SetJSONCodec(nil)
+		defer func() {
+			if atomic.LoadInt64(&codec.calls) == 0 {
+				t.Error("expected the custom JSONCodec to be used")
+			}
+		}()
+	}
+
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Jsonapi Suite")
 }
@@ -1,7 +1,6 @@
 package jsonapi
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -80,6 +79,30 @@ type MarshalCustomLinks interface {
 	GetCustomLinks(string) Links
 }
 
+// The MarshalMeta interface can be implemented if the struct should have a
+// resource-level `meta` object, e.g. for permissions or computed values.
+type MarshalMeta interface {
+	MarshalIdentifier
+	GetMeta() map[string]interface{}
+}
+
+// The MarshalRelationshipMeta interface can be implemented if relationships
+// should have a `meta` object, e.g. the total count of a to-many relationship.
+type MarshalRelationshipMeta interface {
+	MarshalIdentifier
+	GetRelationshipMeta(name string) map[string]interface{}
+}
+
+// The MarshalRelationshipLinks interface can be implemented if relationships
+// should have custom links. They are added to the generated `self` and
+// `related` links and replace them if they use the same name, e.g. for a
+// paginated `related` link. The base url is empty if no ServerInformation
+// was passed.
+type MarshalRelationshipLinks interface {
+	MarshalIdentifier
+	GetRelationshipLinks(name, base string) Links
+}
+
 // A ServerInformation implementor can be passed to MarshalWithURLs to generate
 // the `self` and `related` urls inside `links`.
 type ServerInformation interface {
@@ -94,20 +117,38 @@ func MarshalWithURLs(data interface{}, information ServerInformation) ([]byte, e
 		return nil, err
 	}
 
-	return json.Marshal(document)
+	return jsonMarshal(document)
 }
 
 // Marshal wraps data in a Document and returns its JSON encoding.
 //
 // Data can be a struct, a pointer to a struct or a slice of structs. All structs
-// must at least implement the `MarshalIdentifier` interface.
+// must at least implement the `MarshalIdentifier` interface or use `jsonapi`
+// struct tags, see Adapt.
 func Marshal(data interface{}) ([]byte, error) {
 	document, err := MarshalToStruct(data, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return json.Marshal(document)
+	return jsonMarshal(document)
+}
+
+// MarshalWithOptions is like MarshalWithURLs but applies the options,
+// information may be nil.
+func MarshalWithOptions(data interface{}, information ServerInformation, options Options) ([]byte, error) {
+	document, err := MarshalToStructWithOptions(data, information, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return options.jsonCodec().Marshal(document)
+}
+
+// MarshalToStructWithOptions is like MarshalToStruct but applies the options,
+// information may be nil.
+func MarshalToStructWithOptions(data interface{}, information ServerInformation, options Options) (*Document, error) {
+	return MarshalToStruct(data, withOptions(information, options))
 }
 
 // MarshalToStruct marshals an api2go compatible struct into a jsonapi Document
@@ -123,7 +164,14 @@ func MarshalToStruct(data interface{}, information ServerInformation) (*Document
 	case reflect.Slice:
 		return marshalSlice(data, information)
 	case reflect.Struct, reflect.Ptr:
-		return marshalStruct(data.(MarshalIdentifier), information)
+		element, ok, err := asMarshalIdentifier(data)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("data must implement api2go.MarshalIdentifier or have a primary jsonapi tag")
+		}
+		return marshalStruct(element, information)
 	default:
 		return nil, errors.New("Marshal only accepts slice, struct or ptr types")
 	}
@@ -138,17 +186,20 @@ func marshalSlice(data interface{}, information ServerInformation) (*Document, e
 
 	for i := 0; i < val.Len(); i++ {
 		k := val.Index(i).Interface()
-		element, ok := k.(MarshalIdentifier)
+		element, ok, err := asMarshalIdentifier(k)
+		if err != nil {
+			return nil, err
+		}
 		if !ok {
 			return nil, errors.New("all elements within the slice must implement api2go.MarshalIdentifier")
 		}
 
-		err := marshalData(element, &dataElements[i], information)
+		err = marshalData(element, &dataElements[i], information)
 		if err != nil {
 			return nil, err
 		}
 
-		included, ok := k.(MarshalIncludedRelations)
+		included, ok := element.(MarshalIncludedRelations)
 		if ok {
 			referencedStructs = append(referencedStructs, included.GetReferencedStructs()...)
 		}
@@ -171,29 +222,51 @@ func marshalSlice(data interface{}, information ServerInformation) (*Document, e
 }
 
 func filterDuplicates(input []MarshalIdentifier, information ServerInformation) ([]Data, error) {
-	alreadyIncluded := map[string]map[string]bool{}
-	includedElements := []Data{}
+	included := newIncludedCollector(information)
+	if err := included.add(input); err != nil {
+		return nil, err
+	}
+
+	return included.included, nil
+}
+
+// includedCollector marshals referenced structs and skips the ones that were
+// already included
+type includedCollector struct {
+	information     ServerInformation
+	alreadyIncluded map[string]map[string]bool
+	included        []Data
+}
+
+func newIncludedCollector(information ServerInformation) *includedCollector {
+	return &includedCollector{
+		information:     information,
+		alreadyIncluded: map[string]map[string]bool{},
+		included:        []Data{},
+	}
+}
 
-	for _, referencedStruct := range input {
+func (c *includedCollector) add(referencedStructs []MarshalIdentifier) error {
+	for _, referencedStruct := range referencedStructs {
 		structType := getStructType(referencedStruct)
 
-		if alreadyIncluded[structType] == nil {
-			alreadyIncluded[structType] = make(map[string]bool)
+		if c.alreadyIncluded[structType] == nil {
+			c.alreadyIncluded[structType] = make(map[string]bool)
 		}
 
-		if !alreadyIncluded[structType][referencedStruct.GetID()] {
+		if !c.alreadyIncluded[structType][referencedStruct.GetID()] {
 			var data Data
-			err := marshalData(referencedStruct, &data, information)
+			err := marshalData(referencedStruct, &data, c.information)
 			if err != nil {
-				return nil, err
+				return err
 			}
 
-			includedElements = append(includedElements, data)
-			alreadyIncluded[structType][referencedStruct.GetID()] = true
+			c.included = append(c.included, data)
+			c.alreadyIncluded[structType][referencedStruct.GetID()] = true
 		}
 	}
 
-	return includedElements, nil
+	return nil
 }
 
 func marshalData(element MarshalIdentifier, data *Data, information ServerInformation) error {
@@ -202,31 +275,46 @@ func marshalData(element MarshalIdentifier, data *Data, information ServerInform
 		return errors.New("MarshalIdentifier must not be nil")
 	}
 
-	attributes, err := json.Marshal(element)
+	information, options := splitOptions(information)
+
+	attributes, err := marshalAttributes(element, options.jsonCodec())
+	if err != nil {
+		return err
+	}
+
+	info := getTypeInfo(element)
+	data.Type = info.typeName(element)
+	info.register(data.Type)
+
+	attributes, err = selectAttributes(element, info, data.Type, attributes, options)
 	if err != nil {
 		return err
 	}
 
 	data.Attributes = attributes
 	data.ID = element.GetID()
-	data.Type = getStructType(element)
 
-	if information != nil {
-		if customLinks, ok := element.(MarshalCustomLinks); ok {
-			if data.Links == nil {
-				data.Links = make(Links)
-			}
-			base := getLinkBaseURL(element, information)
-			for k, v := range customLinks.GetCustomLinks(base) {
-				if _, ok := data.Links[k]; !ok {
-					data.Links[k] = v
-				}
+	if information != nil && info.customLinks {
+		if data.Links == nil {
+			data.Links = make(Links)
+		}
+		base := getLinkBaseURL(element, information)
+		for k, v := range element.(MarshalCustomLinks).GetCustomLinks(base) {
+			if _, ok := data.Links[k]; !ok {
+				data.Links[k] = v
 			}
 		}
 	}
 
-	if references, ok := element.(MarshalLinkedRelations); ok {
-		data.Relationships = getStructRelationships(references, information)
+	if info.linkedRelations {
+		data.Relationships = getStructRelationships(element.(MarshalLinkedRelations), information)
+	}
+
+	if info.meta {
+		meta := element.(MarshalMeta).GetMeta()
+		if len(meta) > 0 {
+			data.Meta = meta
+		}
 	}
 
 	return nil
@@ -279,15 +367,11 @@ func getStructRelationships(relationer MarshalLinkedRelations, information Serve
 			}
 		}
 
-		// set URLs if necessary
-		links := getLinksForServerInformation(relationer, name, information)
-
 		relationship := Relationship{
-			Data:  &container,
-			Links: links,
+			Data: &container,
 		}
 
-		relationships[name] = relationship
+		relationships[name] = decorateRelationship(relationship, relationer, name, information)
 
 		// this marks the reference as already included
 		delete(notIncludedReferences, referenceIDs[0].Name)
@@ -302,22 +386,50 @@ func getStructRelationships(relationer MarshalLinkedRelations, information Serve
 			container.DataArray = []RelationshipData{}
 		}
 
-		links := getLinksForServerInformation(relationer, name, information)
-		relationship := Relationship{
-			Links: links,
-		}
+		relationship := Relationship{}
 
 		// skip relationship data completely if IsNotLoaded is set
 		if !reference.IsNotLoaded {
 			relationship.Data = &container
 		}
 
-		relationships[name] = relationship
+		relationships[name] = decorateRelationship(relationship, relationer, name, information)
 	}
 
 	return relationships
 }
 
+// decorateRelationship sets the links and meta of a relationship
+func decorateRelationship(relationship Relationship, relationer MarshalLinkedRelations, name string, information ServerInformation) Relationship {
+	// set URLs if necessary
+	relationship.Links = getLinksForServerInformation(relationer, name, information)
+
+	if customLinks, ok := relationer.(MarshalRelationshipLinks); ok {
+		base := ""
+		if information != nil {
+			base = getLinkBaseURL(relationer, information)
+		}
+
+		links := customLinks.GetRelationshipLinks(name, base)
+		if len(links) > 0 && relationship.Links == nil {
+			relationship.Links = make(Links)
+		}
+
+		for k, v := range links {
+			relationship.Links[k] = v
+		}
+	}
+
+	if metaSource, ok := relationer.(MarshalRelationshipMeta); ok {
+		meta := metaSource.GetRelationshipMeta(name)
+		if len(meta) > 0 {
+			relationship.Meta = meta
+		}
+	}
+
+	return relationship
+}
+
 func getLinkBaseURL(element MarshalIdentifier, information ServerInformation) string {
 	prefix := strings.Trim(information.GetBaseURL(), "/")
 	namespace := strings.Trim(information.GetPrefix(), "/")
@@ -374,15 +486,5 @@ func marshalStruct(data MarshalIdentifier, information ServerInformation) (*Docu
 }
 
 func getStructType(data interface{}) string {
-	entityName, ok := data.(EntityNamer)
-	if ok {
-		return entityName.GetName()
-	}
-
-	reflectType := reflect.TypeOf(data)
-	if reflectType.Kind() == reflect.Ptr {
-		return Pluralize(Jsonify(reflectType.Elem().Name()))
-	}
-
-	return Pluralize(Jsonify(reflectType.Name()))
+	return getTypeInfo(data).typeName(data)
 }
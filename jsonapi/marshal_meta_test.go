@@ -0,0 +1,177 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Task struct {
+	ID       string                 `json:"-"`
+	Title    string                 `json:"title"`
+	Editable bool                   `json:"-"`
+	Meta     map[string]interface{} `json:"-"`
+	Owner    *Owner                 `json:"-"`
+}
+
+func (t Task) GetID() string {
+	return t.ID
+}
+
+func (t *Task) SetID(ID string) error {
+	t.ID = ID
+	return nil
+}
+
+func (t Task) GetMeta() map[string]interface{} {
+	return map[string]interface{}{"editable": t.Editable}
+}
+
+func (t *Task) SetMeta(meta map[string]interface{}) error {
+	if _, ok := meta["invalid"]; ok {
+		return errors.New("invalid meta")
+	}
+
+	t.Meta = meta
+	return nil
+}
+
+func (t Task) GetReferences() []Reference {
+	return []Reference{{Type: "owners", Name: "owner"}}
+}
+
+func (t Task) GetReferencedIDs() []ReferenceID {
+	if t.Owner == nil {
+		return nil
+	}
+
+	return []ReferenceID{{ID: t.Owner.GetID(), Type: "owners", Name: "owner"}}
+}
+
+func (t Task) GetReferencedStructs() []MarshalIdentifier {
+	if t.Owner == nil {
+		return nil
+	}
+
+	return []MarshalIdentifier{*t.Owner}
+}
+
+type Owner struct {
+	ID        int    `json:"-"`
+	Name      string `json:"name"`
+	TaskCount int    `json:"-"`
+}
+
+func (o Owner) GetID() string {
+	return strconv.Itoa(o.ID)
+}
+
+func (o Owner) GetMeta() map[string]interface{} {
+	if o.TaskCount == 0 {
+		return nil
+	}
+
+	return map[string]interface{}{"taskCount": o.TaskCount}
+}
+
+var _ = Describe("Resource-level meta", func() {
+	Context("When marshaling objects implementing MarshalMeta", func() {
+		It("adds meta to each resource object", func() {
+			result, err := Marshal([]Task{
+				{ID: "1", Title: "Write docs", Editable: true},
+				{ID: "2", Title: "Review"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{
+				"data": [{
+					"type": "tasks",
+					"id": "1",
+					"attributes": {"title": "Write docs"},
+					"relationships": {"owner": {"data": null}},
+					"meta": {"editable": true}
+				}, {
+					"type": "tasks",
+					"id": "2",
+					"attributes": {"title": "Review"},
+					"relationships": {"owner": {"data": null}},
+					"meta": {"editable": false}
+				}]
+			}`))
+		})
+
+		It("adds meta to included resources and omits empty meta", func() {
+			result, err := Marshal([]Task{
+				{ID: "1", Title: "Write docs", Owner: &Owner{ID: 1, Name: "Marvin", TaskCount: 3}},
+				{ID: "2", Title: "Review", Owner: &Owner{ID: 2, Name: "Arthur"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{
+				"data": [{
+					"type": "tasks",
+					"id": "1",
+					"attributes": {"title": "Write docs"},
+					"relationships": {"owner": {"data": {"type": "owners", "id": "1"}}},
+					"meta": {"editable": false}
+				}, {
+					"type": "tasks",
+					"id": "2",
+					"attributes": {"title": "Review"},
+					"relationships": {"owner": {"data": {"type": "owners", "id": "2"}}},
+					"meta": {"editable": false}
+				}],
+				"included": [{
+					"type": "owners",
+					"id": "1",
+					"attributes": {"name": "Marvin"},
+					"meta": {"taskCount": 3}
+				}, {
+					"type": "owners",
+					"id": "2",
+					"attributes": {"name": "Arthur"}
+				}]
+			}`))
+		})
+	})
+
+	Context("When unmarshaling objects implementing UnmarshalMeta", func() {
+		It("sets the meta object", func() {
+			var task Task
+			err := Unmarshal([]byte(`{
+				"data": {
+					"type": "tasks",
+					"id": "1",
+					"attributes": {"title": "Write docs"},
+					"meta": {"editable": true}
+				}
+			}`), &task)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(task).To(Equal(Task{ID: "1", Title: "Write docs", Meta: map[string]interface{}{"editable": true}}))
+		})
+
+		It("does not call SetMeta without meta object", func() {
+			var task Task
+			err := Unmarshal([]byte(`{"data": {"type": "tasks", "id": "1", "attributes": {"title": "Write docs"}}}`), &task)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(task.Meta).To(BeNil())
+		})
+
+		It("returns errors of SetMeta", func() {
+			var tasks []Task
+			err := Unmarshal([]byte(`{"data": [{"type": "tasks", "id": "1", "meta": {"invalid": true}}]}`), &tasks)
+			Expect(err).To(MatchError("invalid meta"))
+		})
+
+		It("reads meta of included resources from the document", func() {
+			var document Document
+			err := json.Unmarshal([]byte(`{
+				"data": null,
+				"included": [{"type": "owners", "id": "1", "attributes": {"name": "Marvin"}, "meta": {"taskCount": 3}}]
+			}`), &document)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.Included[0].Meta).To(Equal(map[string]interface{}{"taskCount": float64(3)}))
+		})
+	})
+})
@@ -0,0 +1,194 @@
+package jsonapi
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Team struct {
+	ID                string                            `json:"-"`
+	MemberIDs         []string                          `json:"-"`
+	LeaderID          string                            `json:"-"`
+	RelationshipMeta  map[string]map[string]interface{} `json:"-"`
+	RelationshipLinks map[string]Links                  `json:"-"`
+}
+
+func (t Team) GetID() string {
+	return t.ID
+}
+
+func (t *Team) SetID(ID string) error {
+	t.ID = ID
+	return nil
+}
+
+func (t Team) GetReferences() []Reference {
+	return []Reference{
+		{Type: "users", Name: "members"},
+		{Type: "users", Name: "leader"},
+	}
+}
+
+func (t Team) GetReferencedIDs() []ReferenceID {
+	result := []ReferenceID{}
+	for _, ID := range t.MemberIDs {
+		result = append(result, ReferenceID{ID: ID, Type: "users", Name: "members"})
+	}
+
+	if t.LeaderID != "" {
+		result = append(result, ReferenceID{ID: t.LeaderID, Type: "users", Name: "leader"})
+	}
+
+	return result
+}
+
+func (t Team) GetRelationshipMeta(name string) map[string]interface{} {
+	if name != "members" {
+		return nil
+	}
+
+	return map[string]interface{}{"total": len(t.MemberIDs)}
+}
+
+func (t Team) GetRelationshipLinks(name, base string) Links {
+	if name != "members" {
+		return nil
+	}
+
+	return Links{"related": Link{Href: base + "/members?page[size]=10"}}
+}
+
+func (t *Team) SetToOneReferenceID(name, ID string) error {
+	t.LeaderID = ID
+	return nil
+}
+
+func (t *Team) SetToManyReferenceIDs(name string, IDs []string) error {
+	t.MemberIDs = IDs
+	return nil
+}
+
+func (t *Team) SetRelationshipMeta(name string, meta map[string]interface{}) error {
+	if _, ok := meta["invalid"]; ok {
+		return errors.New("invalid relationship meta")
+	}
+
+	if t.RelationshipMeta == nil {
+		t.RelationshipMeta = map[string]map[string]interface{}{}
+	}
+
+	t.RelationshipMeta[name] = meta
+	return nil
+}
+
+func (t *Team) SetRelationshipLinks(name string, links Links) error {
+	if t.RelationshipLinks == nil {
+		t.RelationshipLinks = map[string]Links{}
+	}
+
+	t.RelationshipLinks[name] = links
+	return nil
+}
+
+var _ = Describe("Relationship meta and links", func() {
+	Context("When marshaling", func() {
+		team := Team{ID: "1", MemberIDs: []string{"1", "2"}, LeaderID: "1"}
+
+		It("adds meta and custom links without ServerInformation", func() {
+			result, err := Marshal(team)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{
+				"data": {
+					"type": "teams",
+					"id": "1",
+					"attributes": {},
+					"relationships": {
+						"members": {
+							"links": {"related": "/members?page[size]=10"},
+							"data": [{"type": "users", "id": "1"}, {"type": "users", "id": "2"}],
+							"meta": {"total": 2}
+						},
+						"leader": {
+							"data": {"type": "users", "id": "1"}
+						}
+					}
+				}
+			}`))
+		})
+
+		It("replaces generated links with custom links of the same name", func() {
+			result, err := MarshalWithURLs(team, CompleteServerInformation{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{
+				"data": {
+					"type": "teams",
+					"id": "1",
+					"attributes": {},
+					"relationships": {
+						"members": {
+							"links": {
+								"self": "http://my.domain/v1/teams/1/relationships/members",
+								"related": "http://my.domain/v1/teams/1/members?page[size]=10"
+							},
+							"data": [{"type": "users", "id": "1"}, {"type": "users", "id": "2"}],
+							"meta": {"total": 2}
+						},
+						"leader": {
+							"links": {
+								"self": "http://my.domain/v1/teams/1/relationships/leader",
+								"related": "http://my.domain/v1/teams/1/leader"
+							},
+							"data": {"type": "users", "id": "1"}
+						}
+					}
+				}
+			}`))
+		})
+
+		It("adds meta to empty relationships", func() {
+			result, err := Marshal(Team{ID: "2"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(ContainSubstring(`"members":{"links":{"related":"/members?page[size]=10"},"data":[],"meta":{"total":0}}`))
+		})
+	})
+
+	Context("When unmarshaling", func() {
+		It("passes meta and links of relationships to the target", func() {
+			var team Team
+			err := Unmarshal([]byte(`{
+				"data": {
+					"type": "teams",
+					"id": "1",
+					"relationships": {
+						"members": {
+							"links": {"related": "/teams/1/members?page[size]=10"},
+							"data": [{"type": "users", "id": "1"}],
+							"meta": {"total": 12}
+						}
+					}
+				}
+			}`), &team)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(team).To(Equal(Team{
+				ID:                "1",
+				MemberIDs:         []string{"1"},
+				RelationshipMeta:  map[string]map[string]interface{}{"members": {"total": float64(12)}},
+				RelationshipLinks: map[string]Links{"members": {"related": Link{Href: "/teams/1/members?page[size]=10"}}},
+			}))
+		})
+
+		It("returns errors of SetRelationshipMeta", func() {
+			var team Team
+			err := Unmarshal([]byte(`{
+				"data": {
+					"type": "teams",
+					"id": "1",
+					"relationships": {"members": {"data": [], "meta": {"invalid": true}}}
+				}
+			}`), &team)
+			Expect(err).To(MatchError("invalid relationship meta"))
+		})
+	})
+})
@@ -0,0 +1,215 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+)
+
+// The Iterator interface yields the records of a streamed collection one by
+// one. Next returns a nil record if there are no more records.
+type Iterator interface {
+	Next() (MarshalIdentifier, error)
+}
+
+// IteratorFunc can be used to implement Iterator with a function, e.g. one that
+// scans the rows of a database cursor.
+type IteratorFunc func() (MarshalIdentifier, error)
+
+// Next calls f
+func (f IteratorFunc) Next() (MarshalIdentifier, error) {
+	return f()
+}
+
+// ChannelIterator returns an Iterator over the records sent to a channel. The
+// iteration ends when the channel is closed. The iterator implements io.Closer,
+// Close closes done to tell the producer that no more records are read, e.g.
+// because streaming was aborted. The producer should send in a select on done,
+// so that it does not block forever. done may be nil.
+func ChannelIterator(records <-chan MarshalIdentifier, done chan<- struct{}) Iterator {
+	return &channelIterator{records: records, done: done}
+}
+
+type channelIterator struct {
+	records <-chan MarshalIdentifier
+	done    chan<- struct{}
+	once    sync.Once
+}
+
+func (c *channelIterator) Next() (MarshalIdentifier, error) {
+	return <-c.records, nil
+}
+
+func (c *channelIterator) Close() error {
+	if c.done != nil {
+		c.once.Do(func() {
+			close(c.done)
+		})
+	}
+
+	return nil
+}
+
+// A Stream is a document whose primary data is read from Records while it is
+// written. All other top-level members are taken from Document, included
+// structs are collected and deduplicated while writing the primary data.
+type Stream struct {
+	Document Document
+	Records  Iterator
+
+	// Filter is called for every resource object of the primary data and
+	// included before it is written, e.g. to apply sparse fieldsets
+	Filter func(data *Data) error
+
+	// Options are applied to all records of the stream
+	Options Options
+}
+
+// MarshalTo writes the JSON encoding of data to w. Data can be everything
+// accepted by Marshal, an Iterator or a *Stream. Iterators and streams are
+// written record by record, so large collections never have to be held in
+// memory. If an error occurs while streaming, the output is incomplete.
+// Iterators are not closed by MarshalTo.
+func MarshalTo(w io.Writer, data interface{}, information ServerInformation) error {
+	switch typed := data.(type) {
+	case *Stream:
+		return typed.marshalTo(w, information)
+	case Iterator:
+		stream := &Stream{Records: typed}
+		return stream.marshalTo(w, information)
+	}
+
+	document, err := MarshalToStruct(data, information)
+	if err != nil {
+		return err
+	}
+
+	result, err := jsonMarshal(document)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(result)
+	return err
+}
+
+// errWriter remembers the first error of w, so that errors must only be checked
+// at the end
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) write(payload []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(payload)
+	}
+}
+
+func (e *errWriter) writeString(payload string) {
+	e.write([]byte(payload))
+}
+
+func (s *Stream) marshalTo(w io.Writer, information ServerInformation) error {
+	out := &errWriter{w: w}
+	information = withOptions(information, s.Options)
+
+	// all members besides data and included are written first
+	head := s.Document
+	head.Data = &DataContainer{}
+	head.Included = nil
+	codec := s.Options.jsonCodec()
+	payload, err := codec.Marshal(head)
+	if err != nil {
+		return err
+	}
+
+	var members map[string]json.RawMessage
+	if err := codec.Unmarshal(payload, &members); err != nil {
+		return err
+	}
+	delete(members, "data")
+
+	names := []string{}
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out.writeString("{")
+	for _, name := range names {
+		key, _ := codec.Marshal(name)
+		out.write(key)
+		out.writeString(":")
+		out.write(members[name])
+		out.writeString(",")
+	}
+
+	out.writeString(`"data":[`)
+	included := newIncludedCollector(information)
+	for count := 0; ; count++ {
+		record, err := s.Records.Next()
+		if err != nil {
+			return err
+		}
+
+		if record == nil {
+			break
+		}
+
+		var data Data
+		if err := marshalData(record, &data, information); err != nil {
+			return err
+		}
+
+		if s.Filter != nil {
+			if err := s.Filter(&data); err != nil {
+				return err
+			}
+		}
+
+		payload, err := codec.Marshal(data)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			out.writeString(",")
+		}
+		out.write(payload)
+
+		if out.err != nil {
+			return out.err
+		}
+
+		if referenced, ok := record.(MarshalIncludedRelations); ok {
+			if err := included.add(referenced.GetReferencedStructs()); err != nil {
+				return err
+			}
+		}
+	}
+	out.writeString("]")
+
+	if len(included.included) > 0 {
+		if s.Filter != nil {
+			for index := range included.included {
+				if err := s.Filter(&included.included[index]); err != nil {
+					return err
+				}
+			}
+		}
+
+		payload, err := codec.Marshal(included.included)
+		if err != nil {
+			return err
+		}
+
+		out.writeString(`,"included":`)
+		out.write(payload)
+	}
+
+	out.writeString("}")
+
+	return out.err
+}
@@ -0,0 +1,123 @@
+package jsonapi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func postIterator(posts []Post) Iterator {
+	index := 0
+	return IteratorFunc(func() (MarshalIdentifier, error) {
+		if index >= len(posts) {
+			return nil, nil
+		}
+
+		index++
+		return posts[index-1], nil
+	})
+}
+
+var _ = Describe("Marshalling to a writer", func() {
+	var (
+		posts  []Post
+		buffer *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		buffer = &bytes.Buffer{}
+		posts = []Post{
+			{ID: 1, Title: "First", Comments: []Comment{{ID: 1, Text: "Hi"}, {ID: 2, Text: "Ho"}}, CommentsIDs: []int{1, 2}},
+			{ID: 2, Title: "Second", Comments: []Comment{{ID: 2, Text: "Ho"}}, CommentsIDs: []int{2}},
+		}
+	})
+
+	It("writes the same json as Marshal", func() {
+		expected, err := Marshal(posts)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = MarshalTo(buffer, posts, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buffer.Bytes()).To(Equal(expected))
+	})
+
+	It("streams iterators with deduplicated included structs", func() {
+		expected, err := Marshal(posts)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = MarshalTo(buffer, postIterator(posts), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buffer.String()).To(MatchJSON(expected))
+		Expect(strings.Count(buffer.String(), `"type":"comments","id":"2","attributes"`)).To(Equal(1))
+	})
+
+	It("writes empty collections", func() {
+		err := MarshalTo(buffer, postIterator(nil), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buffer.String()).To(MatchJSON(`{"data": []}`))
+	})
+
+	It("writes the other top-level members of streams", func() {
+		records := make(chan MarshalIdentifier, 1)
+		records <- Comment{ID: 1, Text: "Hi"}
+		close(records)
+
+		err := MarshalTo(buffer, &Stream{
+			Document: Document{
+				JSONAPI:  &JSONAPI{Version: "1.1"},
+				Links:    Links{"next": {Href: "/comments?page[number]=2"}},
+				Profiles: []Link{{Href: "http://example.com/profile"}},
+				Meta:     map[string]interface{}{"count": 1},
+			},
+			Records: ChannelIterator(records, nil),
+			Filter: func(data *Data) error {
+				data.Meta = map[string]interface{}{"filtered": true}
+				return nil
+			},
+		}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buffer.String()).To(MatchJSON(`{
+			"jsonapi": {"version": "1.1"},
+			"links": {"next": "/comments?page[number]=2", "profile": ["http://example.com/profile"]},
+			"meta": {"count": 1},
+			"data": [{"type": "comments", "id": "1", "attributes": {"text": "Hi"}, "meta": {"filtered": true}}]
+		}`))
+	})
+
+	It("tells the producer of a channel iterator to stop when it is closed", func() {
+		records := make(chan MarshalIdentifier)
+		done := make(chan struct{})
+		stopped := make(chan bool)
+		go func() {
+			for id := 1; ; id++ {
+				select {
+				case records <- Comment{ID: id}:
+				case <-done:
+					stopped <- true
+					return
+				}
+			}
+		}()
+
+		iterator := ChannelIterator(records, done)
+		record, err := iterator.Next()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(record).To(Equal(Comment{ID: 1}))
+		Expect(iterator.(io.Closer).Close()).To(Succeed())
+		Expect(iterator.(io.Closer).Close()).To(Succeed())
+		Eventually(stopped).Should(Receive())
+	})
+
+	It("returns errors of the iterator", func() {
+		failing := IteratorFunc(func() (MarshalIdentifier, error) {
+			return nil, errors.New("connection lost")
+		})
+
+		err := MarshalTo(buffer, failing, nil)
+		Expect(err).To(MatchError("connection lost"))
+	})
+})
@@ -0,0 +1,839 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// tagName is the name of the struct tag that maps a struct to a resource.
+//
+// The following tags are supported:
+//
+//	ID     string    `jsonapi:"primary,users"`              // the id and the type of the resource
+//	Name   string    `jsonapi:"attr,user-name"`            // an attribute
+//	Email  string    `jsonapi:"attr,email,omitempty"`      // omitted if empty
+//	Sweets []string  `jsonapi:"relation,sweets,chocolates"` // a relationship with its type
+//	Owner  *User     `jsonapi:"relation,owner"`            // the type of structs can be omitted
+const tagName = "jsonapi"
+
+// taggedField is an attribute or relationship field of a tagged struct
+type taggedField struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// taggedRelation is a relationship field of a tagged struct
+type taggedRelation struct {
+	taggedField
+	resourceType string
+	toMany       bool
+	// structs is true if the field contains the referenced structs instead of
+	// their ids, then elemType is the (pointer) type of the structs
+	structs  bool
+	elemType reflect.Type
+}
+
+// taggedType contains the parsed struct tags of a type
+type taggedType struct {
+	name       string
+	primary    int
+	attributes []taggedField
+	relations  []taggedRelation
+	info       *typeInfo
+}
+
+// the optional marshal interfaces without MarshalIdentifier, which tagged
+// structs do not implement
+type (
+	customLinksSource interface {
+		GetCustomLinks(string) Links
+	}
+	metaSource interface {
+		GetMeta() map[string]interface{}
+	}
+	relationshipMetaSource interface {
+		GetRelationshipMeta(name string) map[string]interface{}
+	}
+	relationshipLinksSource interface {
+		GetRelationshipLinks(name, base string) Links
+	}
+)
+
+var (
+	customLinksSourceType = reflect.TypeOf((*customLinksSource)(nil)).Elem()
+	metaSourceType        = reflect.TypeOf((*metaSource)(nil)).Elem()
+)
+
+var taggedTypeCache = struct {
+	sync.RWMutex
+	byType map[reflect.Type]*taggedType
+}{
+	byType: map[reflect.Type]*taggedType{},
+}
+
+// Adapt returns a value that implements the marshal and unmarshal interfaces
+// of this package for a struct or a pointer to a struct that uses `jsonapi`
+// struct tags instead. The interfaces are implemented with the tagged primary,
+// attr and relation fields, all other fields are ignored. Unmarshalling
+// requires a pointer. Values that implement MarshalIdentifier or have no
+// primary tag are returned unchanged.
+//
+// Marshal and Unmarshal adapt tagged structs automatically, Adapt is only
+// needed to use them with the interfaces directly. Values with invalid tags
+// are returned unchanged as well, ValidateTags returns their error.
+func Adapt(value interface{}) interface{} {
+	if tagged, _ := adaptTagged(value); tagged != nil {
+		return tagged
+	}
+
+	return value
+}
+
+// ValidateTags returns an error if value is a struct or a pointer to a struct
+// with invalid `jsonapi` struct tags.
+func ValidateTags(value interface{}) error {
+	_, err := adaptTagged(value)
+	return err
+}
+
+// asMarshalIdentifier returns value as MarshalIdentifier, adapting tagged
+// structs. It returns an error if the struct tags of value are invalid.
+func asMarshalIdentifier(value interface{}) (MarshalIdentifier, bool, error) {
+	if identifier, ok := value.(MarshalIdentifier); ok {
+		return identifier, true, nil
+	}
+
+	tagged, err := adaptTagged(value)
+	if tagged == nil {
+		return nil, false, err
+	}
+
+	return tagged, true, nil
+}
+
+// asUnmarshalIdentifier returns target as UnmarshalIdentifier, adapting tagged
+// structs. It returns an error if the struct tags of target are invalid.
+func asUnmarshalIdentifier(target interface{}) (UnmarshalIdentifier, bool, error) {
+	if identifier, ok := target.(UnmarshalIdentifier); ok {
+		return identifier, true, nil
+	}
+
+	tagged, err := adaptTagged(target)
+	if tagged == nil {
+		return nil, false, err
+	}
+
+	return tagged, true, nil
+}
+
+func adaptTagged(value interface{}) (*taggedResource, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	if _, ok := value.(MarshalIdentifier); ok {
+		return nil, nil
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() == reflect.Ptr {
+		if reflectValue.IsNil() {
+			return nil, nil
+		}
+		reflectValue = reflectValue.Elem()
+	}
+
+	tagged, err := getTaggedType(reflect.TypeOf(value))
+	if tagged == nil {
+		return nil, err
+	}
+
+	return &taggedResource{value: reflectValue, original: value, tagged: tagged}, nil
+}
+
+// getTaggedType returns the cached tags of a struct or struct pointer type or
+// nil if it has no primary tag. Invalid tags are returned as error.
+func getTaggedType(reflectType reflect.Type) (*taggedType, error) {
+	taggedTypeCache.RLock()
+	tagged, ok := taggedTypeCache.byType[reflectType]
+	taggedTypeCache.RUnlock()
+	if ok {
+		return tagged, nil
+	}
+
+	tagged, err := newTaggedType(reflectType)
+	if err != nil {
+		return nil, err
+	}
+
+	taggedTypeCache.Lock()
+	taggedTypeCache.byType[reflectType] = tagged
+	taggedTypeCache.Unlock()
+
+	return tagged, nil
+}
+
+func newTaggedType(reflectType reflect.Type) (*taggedType, error) {
+	structType := reflectType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	if structType.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	name, primary, ok, err := getPrimaryTag(structType)
+	if !ok {
+		return nil, err
+	}
+
+	tagged := &taggedType{name: name, primary: primary}
+	attributeNames := []string{}
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag, ok := field.Tag.Lookup(tagName)
+		if !ok || i == primary {
+			continue
+		}
+
+		if field.PkgPath != "" {
+			return nil, fmt.Errorf("jsonapi tag of unexported field %s.%s", structType, field.Name)
+		}
+
+		values := strings.Split(tag, ",")
+		switch values[0] {
+		case "attr":
+			attribute := taggedField{index: i, name: Jsonify(field.Name)}
+			if len(values) > 1 && values[1] != "" {
+				attribute.name = values[1]
+			}
+			attribute.omitEmpty = len(values) > 2 && values[2] == "omitempty"
+			tagged.attributes = append(tagged.attributes, attribute)
+			attributeNames = append(attributeNames, attribute.name)
+		case "relation":
+			relation, err := newTaggedRelation(field, i, values)
+			if err != nil {
+				return nil, fmt.Errorf("jsonapi tag of field %s.%s: %s", structType, field.Name, err)
+			}
+			tagged.relations = append(tagged.relations, relation)
+		case "primary":
+			return nil, fmt.Errorf("%s has more than one primary jsonapi tag", structType)
+		default:
+			return nil, fmt.Errorf("jsonapi tag of field %s.%s: unknown kind %q", structType, field.Name, values[0])
+		}
+	}
+
+	tagged.info = &typeInfo{
+		name:              name,
+		attributes:        attributeNames,
+		linkedRelations:   true,
+		includedRelations: true,
+		customLinks:       reflectType.Implements(customLinksSourceType),
+		meta:              reflectType.Implements(metaSourceType),
+		forbidden:         append([]string{}, reservedAttributes...),
+	}
+	for _, relation := range tagged.relations {
+		tagged.info.forbidden = append(tagged.info.forbidden, relation.name)
+	}
+
+	return tagged, nil
+}
+
+// getPrimaryTag returns the type name and the index of the primary field of a struct
+func getPrimaryTag(structType reflect.Type) (string, int, bool, error) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		values := strings.Split(field.Tag.Get(tagName), ",")
+		if values[0] != "primary" {
+			continue
+		}
+
+		if field.PkgPath != "" || !isIDKind(field.Type.Kind()) {
+			return "", 0, false, fmt.Errorf("primary jsonapi tag of %s.%s requires an exported string or integer field", structType, field.Name)
+		}
+
+		if len(values) > 1 && values[1] != "" {
+			return values[1], i, true, nil
+		}
+
+		return Pluralize(Jsonify(structType.Name())), i, true, nil
+	}
+
+	return "", 0, false, nil
+}
+
+func newTaggedRelation(field reflect.StructField, index int, values []string) (taggedRelation, error) {
+	relation := taggedRelation{taggedField: taggedField{index: index, name: Jsonify(field.Name)}}
+	if len(values) > 1 && values[1] != "" {
+		relation.name = values[1]
+	}
+	if len(values) > 2 {
+		relation.resourceType = values[2]
+	}
+
+	elemType := field.Type
+	if elemType.Kind() == reflect.Slice {
+		relation.toMany = true
+		elemType = elemType.Elem()
+	}
+
+	if isIDKind(elemType.Kind()) {
+		relation.elemType = elemType
+		if relation.resourceType == "" {
+			return relation, errors.New("the type of id relationships is required")
+		}
+
+		return relation, nil
+	}
+
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	if structType.Kind() != reflect.Struct {
+		return relation, errors.New("relationships require ids or structs")
+	}
+
+	relation.structs = true
+	relation.elemType = elemType
+
+	if relation.resourceType == "" {
+		name, _, ok, err := getPrimaryTag(structType)
+		if err != nil {
+			return relation, err
+		}
+
+		if ok {
+			relation.resourceType = name
+		} else if reflect.PtrTo(structType).Implements(reflect.TypeOf((*MarshalIdentifier)(nil)).Elem()) {
+			relation.resourceType = getStructType(reflect.New(structType).Interface())
+		} else {
+			return relation, errors.New("the type of the relationship is required")
+		}
+	}
+
+	return relation, nil
+}
+
+func isIDKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
+
+func formatID(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	default:
+		return strconv.FormatUint(value.Uint(), 10)
+	}
+}
+
+// parseID sets value to the given id, an empty id sets the zero value
+func parseID(ID string, value reflect.Value) error {
+	if ID == "" {
+		value.Set(reflect.Zero(value.Type()))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(ID)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(ID, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid id %q: %s", ID, err)
+		}
+		value.SetInt(parsed)
+	default:
+		parsed, err := strconv.ParseUint(ID, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid id %q: %s", ID, err)
+		}
+		value.SetUint(parsed)
+	}
+
+	return nil
+}
+
+// isEmptyValue reports whether value is empty like encoding/json does for omitempty
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+
+	return false
+}
+
+// taggedResource implements the marshal and unmarshal interfaces for a tagged
+// struct, the optional interfaces are forwarded to the original value
+type taggedResource struct {
+	value    reflect.Value
+	original interface{}
+	tagged   *taggedType
+}
+
+// GetID returns the formatted primary field
+func (t *taggedResource) GetID() string {
+	return formatID(t.value.Field(t.tagged.primary))
+}
+
+// SetID parses the id into the primary field
+func (t *taggedResource) SetID(ID string) error {
+	if !t.value.CanAddr() {
+		return errors.New("target must be a ptr")
+	}
+
+	return parseID(ID, t.value.Field(t.tagged.primary))
+}
+
+// GetName returns the type of the primary tag
+func (t *taggedResource) GetName() string {
+	return t.tagged.name
+}
+
+// MarshalJSON marshals the attribute fields into an attributes object
+func (t *taggedResource) MarshalJSON() ([]byte, error) {
+	return t.marshalAttributes(GetJSONCodec())
+}
+
+// marshalAttributes marshals the attribute fields with codec
+func (t *taggedResource) marshalAttributes(codec JSONCodec) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+
+	for _, attribute := range t.tagged.attributes {
+		field := t.value.Field(attribute.index)
+		if attribute.omitEmpty && isEmptyValue(field) {
+			continue
+		}
+
+		name, err := codec.Marshal(attribute.name)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := codec.Marshal(field.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		if buffer.Len() > 1 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON unmarshals an attributes object into the attribute fields
+func (t *taggedResource) UnmarshalJSON(data []byte) error {
+	return t.unmarshalAttributes(data, GetJSONCodec())
+}
+
+// unmarshalAttributes unmarshals an attributes object into the attribute
+// fields with codec
+func (t *taggedResource) unmarshalAttributes(data []byte, codec JSONCodec) error {
+	if !t.value.CanAddr() {
+		return errors.New("target must be a ptr")
+	}
+
+	attributes := map[string]json.RawMessage{}
+	if err := codec.Unmarshal(data, &attributes); err != nil {
+		return err
+	}
+
+	for _, attribute := range t.tagged.attributes {
+		value, ok := attributes[attribute.name]
+		if !ok {
+			continue
+		}
+
+		if err := codec.Unmarshal(value, t.value.Field(attribute.index).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetReferences returns all relation fields
+func (t *taggedResource) GetReferences() []Reference {
+	references := []Reference{}
+	for _, relation := range t.tagged.relations {
+		reference := Reference{Type: relation.resourceType, Name: relation.name, Relationship: ToOneRelationship}
+		if relation.toMany {
+			reference.Relationship = ToManyRelationship
+		}
+		references = append(references, reference)
+	}
+
+	return references
+}
+
+// GetReferencedIDs returns the ids of all relation fields, zero ids are skipped
+func (t *taggedResource) GetReferencedIDs() []ReferenceID {
+	referenceIDs := []ReferenceID{}
+	for _, relation := range t.tagged.relations {
+		relationshipType := ToOneRelationship
+		if relation.toMany {
+			relationshipType = ToManyRelationship
+		}
+
+		for _, element := range relation.elements(t.value) {
+			ID := relation.elementID(element)
+			if ID == "" {
+				continue
+			}
+
+			referenceIDs = append(referenceIDs, ReferenceID{
+				ID:           ID,
+				Type:         relation.resourceType,
+				Name:         relation.name,
+				Relationship: relationshipType,
+			})
+		}
+	}
+
+	return referenceIDs
+}
+
+// GetReferencedStructs returns the structs of all relation fields that contain structs
+func (t *taggedResource) GetReferencedStructs() []MarshalIdentifier {
+	referencedStructs := []MarshalIdentifier{}
+	for _, relation := range t.tagged.relations {
+		if !relation.structs {
+			continue
+		}
+
+		for _, element := range relation.elements(t.value) {
+			if relation.elementID(element) == "" {
+				continue
+			}
+
+			if identifier, ok, _ := asMarshalIdentifier(element.Interface()); ok {
+				referencedStructs = append(referencedStructs, identifier)
+			}
+		}
+	}
+
+	return referencedStructs
+}
+
+// SetToOneReferenceIDWithType sets a to-one relation field, an empty id clears it
+func (t *taggedResource) SetToOneReferenceIDWithType(name string, ID ReferenceID) error {
+	relation, field, err := t.relationField(name, false)
+	if err != nil {
+		return err
+	}
+
+	if ID.ID == "" {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	element, err := relation.newElement(ID.ID)
+	if err != nil {
+		return err
+	}
+
+	field.Set(element)
+	return nil
+}
+
+// SetToManyReferenceIDsWithType replaces a to-many relation field
+func (t *taggedResource) SetToManyReferenceIDsWithType(name string, IDs []ReferenceID) error {
+	relation, field, err := t.relationField(name, true)
+	if err != nil {
+		return err
+	}
+
+	elements := reflect.MakeSlice(field.Type(), 0, len(IDs))
+	for _, ID := range IDs {
+		element, err := relation.newElement(ID.ID)
+		if err != nil {
+			return err
+		}
+		elements = reflect.Append(elements, element)
+	}
+
+	field.Set(elements)
+	return nil
+}
+
+// AddToManyIDs adds the ids to a to-many relation field that are not part of it yet
+func (t *taggedResource) AddToManyIDs(name string, IDs []string) error {
+	relation, field, err := t.relationField(name, true)
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for _, element := range relation.elements(t.value) {
+		existing[relation.elementID(element)] = true
+	}
+
+	elements := field
+	for _, ID := range IDs {
+		if existing[ID] {
+			continue
+		}
+
+		element, err := relation.newElement(ID)
+		if err != nil {
+			return err
+		}
+		elements = reflect.Append(elements, element)
+		existing[ID] = true
+	}
+
+	field.Set(elements)
+	return nil
+}
+
+// DeleteToManyIDs removes the ids from a to-many relation field
+func (t *taggedResource) DeleteToManyIDs(name string, IDs []string) error {
+	relation, field, err := t.relationField(name, true)
+	if err != nil {
+		return err
+	}
+
+	elements := reflect.MakeSlice(field.Type(), 0, field.Len())
+	for _, element := range relation.elements(t.value) {
+		if !containsString(IDs, relation.elementID(element)) {
+			elements = reflect.Append(elements, element)
+		}
+	}
+
+	field.Set(elements)
+	return nil
+}
+
+// SetReferencedStructs unmarshals included records into the structs of a
+// relation field with the same id
+func (t *taggedResource) SetReferencedStructs(name string, included []Data) error {
+	for _, relation := range t.tagged.relations {
+		if relation.name != name || !relation.structs || !t.value.CanAddr() {
+			continue
+		}
+
+		for _, element := range relation.elements(t.value) {
+			target := element
+			if target.Kind() != reflect.Ptr {
+				target = target.Addr()
+			}
+
+			ID := relation.elementID(element)
+			for _, record := range included {
+				if record.ID != ID || record.Type != relation.resourceType {
+					continue
+				}
+
+				if err := UnmarshalIncluded(record, target.Interface()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetCustomLinks forwards to the original value
+func (t *taggedResource) GetCustomLinks(base string) Links {
+	if source, ok := t.original.(customLinksSource); ok {
+		return source.GetCustomLinks(base)
+	}
+
+	return nil
+}
+
+// GetMeta forwards to the original value
+func (t *taggedResource) GetMeta() map[string]interface{} {
+	if source, ok := t.original.(metaSource); ok {
+		return source.GetMeta()
+	}
+
+	return nil
+}
+
+// SetMeta forwards to the original value
+func (t *taggedResource) SetMeta(meta map[string]interface{}) error {
+	if target, ok := t.original.(UnmarshalMeta); ok {
+		return target.SetMeta(meta)
+	}
+
+	return nil
+}
+
+// GetRelationshipMeta forwards to the original value
+func (t *taggedResource) GetRelationshipMeta(name string) map[string]interface{} {
+	if source, ok := t.original.(relationshipMetaSource); ok {
+		return source.GetRelationshipMeta(name)
+	}
+
+	return nil
+}
+
+// SetRelationshipMeta forwards to the original value
+func (t *taggedResource) SetRelationshipMeta(name string, meta map[string]interface{}) error {
+	if target, ok := t.original.(UnmarshalRelationshipMeta); ok {
+		return target.SetRelationshipMeta(name, meta)
+	}
+
+	return nil
+}
+
+// GetRelationshipLinks forwards to the original value
+func (t *taggedResource) GetRelationshipLinks(name, base string) Links {
+	if source, ok := t.original.(relationshipLinksSource); ok {
+		return source.GetRelationshipLinks(name, base)
+	}
+
+	return nil
+}
+
+// SetRelationshipLinks forwards to the original value
+func (t *taggedResource) SetRelationshipLinks(name string, links Links) error {
+	if target, ok := t.original.(UnmarshalRelationshipLinks); ok {
+		return target.SetRelationshipLinks(name, links)
+	}
+
+	return nil
+}
+
+// relationField returns the settable field of a relation
+func (t *taggedResource) relationField(name string, toMany bool) (taggedRelation, reflect.Value, error) {
+	for _, relation := range t.tagged.relations {
+		if relation.name != name {
+			continue
+		}
+
+		if relation.toMany != toMany {
+			break
+		}
+
+		if !t.value.CanAddr() {
+			return relation, reflect.Value{}, errors.New("target must be a ptr")
+		}
+
+		return relation, t.value.Field(relation.index), nil
+	}
+
+	if toMany {
+		return taggedRelation{}, reflect.Value{}, fmt.Errorf("There is no to-many relationship with the name %s", name)
+	}
+
+	return taggedRelation{}, reflect.Value{}, fmt.Errorf("There is no to-one relationship with the name %s", name)
+}
+
+// elements returns the ids or structs of the relation field, nil pointers are skipped
+func (r taggedRelation) elements(value reflect.Value) []reflect.Value {
+	field := value.Field(r.index)
+	if !r.toMany {
+		if field.Kind() == reflect.Ptr && field.IsNil() {
+			return nil
+		}
+
+		return []reflect.Value{field}
+	}
+
+	elements := []reflect.Value{}
+	for i := 0; i < field.Len(); i++ {
+		element := field.Index(i)
+		if element.Kind() == reflect.Ptr && element.IsNil() {
+			continue
+		}
+		elements = append(elements, element)
+	}
+
+	return elements
+}
+
+// elementID returns the id of an element of the relation field, it is empty for zero ids
+func (r taggedRelation) elementID(element reflect.Value) string {
+	if !r.structs {
+		if isEmptyValue(element) {
+			return ""
+		}
+
+		return formatID(element)
+	}
+
+	identifier, ok, _ := asMarshalIdentifier(element.Interface())
+	if !ok {
+		return ""
+	}
+
+	if tagged, ok := identifier.(*taggedResource); ok && isEmptyValue(tagged.value.Field(tagged.tagged.primary)) {
+		return ""
+	}
+
+	return identifier.GetID()
+}
+
+// newElement returns an element of the relation field with the given id
+func (r taggedRelation) newElement(ID string) (reflect.Value, error) {
+	if !r.structs {
+		element := reflect.New(r.elemType).Elem()
+		return element, parseID(ID, element)
+	}
+
+	structType := r.elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	element := reflect.New(structType)
+	target, ok, err := asUnmarshalIdentifier(element.Interface())
+	if err != nil {
+		return element, err
+	}
+
+	if !ok {
+		return element, fmt.Errorf("struct %s does not implement UnmarshalIdentifier", structType)
+	}
+
+	if err := target.SetID(ID); err != nil {
+		return element, err
+	}
+
+	if r.elemType.Kind() == reflect.Ptr {
+		return element, nil
+	}
+
+	return element.Elem(), nil
+}
@@ -0,0 +1,219 @@
+package jsonapi
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type TaggedUser struct {
+	ID       int        `jsonapi:"primary,members"`
+	Name     string     `jsonapi:"attr,user-name"`
+	Email    string     `jsonapi:"attr,email,omitempty"`
+	SweetIDs []string   `jsonapi:"relation,sweets,chocolates"`
+	Pet      *TaggedPet `jsonapi:"relation,pet"`
+	Password string
+}
+
+type TaggedPet struct {
+	ID   string `jsonapi:"primary"`
+	Name string `jsonapi:"attr"`
+}
+
+type TaggedMeta struct {
+	ID string `jsonapi:"primary,metas"`
+}
+
+func (t TaggedMeta) GetMeta() map[string]interface{} {
+	return map[string]interface{}{"tagged": true}
+}
+
+type TaggedTicket struct {
+	ID      string `jsonapi:"primary,tickets"`
+	Owner   string `jsonapi:"attr,owner"`
+	Title   string `jsonapi:"attr,title"`
+	OwnerID string `jsonapi:"relation,owner,members"`
+}
+
+type InvalidTag struct {
+	ID   string `jsonapi:"primary,invalids"`
+	Name string `jsonapi:"attribute,name"`
+}
+
+type InvalidPrimary struct {
+	ID   float64 `jsonapi:"primary,invalids"`
+	Name string  `jsonapi:"attr,name"`
+}
+
+var _ = Describe("Struct tags", func() {
+	Context("When marshalling", func() {
+		It("derives id, attributes and relationships", func() {
+			result, err := Marshal(TaggedUser{
+				ID:       1,
+				Name:     "Marvin",
+				SweetIDs: []string{"2", "3"},
+				Pet:      &TaggedPet{ID: "4", Name: "Fluffy"},
+				Password: "secret",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{
+				"data": {
+					"type": "members",
+					"id": "1",
+					"attributes": {"user-name": "Marvin"},
+					"relationships": {
+						"sweets": {"data": [{"type": "chocolates", "id": "2"}, {"type": "chocolates", "id": "3"}]},
+						"pet": {"data": {"type": "taggedPets", "id": "4"}}
+					}
+				},
+				"included": [{"type": "taggedPets", "id": "4", "attributes": {"name": "Fluffy"}}]
+			}`))
+		})
+
+		It("marshals empty relationships and omitempty attributes", func() {
+			result, err := Marshal([]*TaggedUser{{ID: 1, Email: "marvin@example.com"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{
+				"data": [{
+					"type": "members",
+					"id": "1",
+					"attributes": {"user-name": "", "email": "marvin@example.com"},
+					"relationships": {
+						"sweets": {"data": []},
+						"pet": {"data": null}
+					}
+				}]
+			}`))
+		})
+
+		It("uses the optional interfaces of the struct", func() {
+			result, err := Marshal(TaggedMeta{ID: "1"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{"data": {"type": "metas", "id": "1", "attributes": {}, "meta": {"tagged": true}}}`))
+		})
+
+		It("registers the attribute names", func() {
+			_, err := Marshal(TaggedUser{ID: 1})
+			Expect(err).ToNot(HaveOccurred())
+			names, ok := AttributeNames("members")
+			Expect(ok).To(BeTrue())
+			Expect(names).To(ConsistOf("user-name", "email"))
+		})
+
+		It("caches the relationships as forbidden attributes", func() {
+			info := getTypeInfo(Adapt(TaggedTicket{}))
+			Expect(forbiddenAttributes(nil, info)).To(Equal([]string{"id", "type", "owner"}))
+
+			result, err := Marshal(TaggedTicket{ID: "1", Owner: "Marvin", Title: "Hello", OwnerID: "2"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{"data": {
+				"type": "tickets",
+				"id": "1",
+				"attributes": {"title": "Hello"},
+				"relationships": {"owner": {"data": {"type": "members", "id": "2"}}}
+			}}`))
+
+			names, ok := AttributeNames("tickets")
+			Expect(ok).To(BeTrue())
+			Expect(names).To(Equal([]string{"title"}))
+		})
+
+		It("returns an error for invalid tags", func() {
+			_, err := Marshal(InvalidTag{ID: "1"})
+			Expect(err).To(MatchError(`jsonapi tag of field jsonapi.InvalidTag.Name: unknown kind "attribute"`))
+
+			_, err = Marshal([]InvalidTag{{ID: "1"}})
+			Expect(err).To(HaveOccurred())
+
+			_, err = Marshal(InvalidPrimary{ID: 1})
+			Expect(err).To(MatchError("primary jsonapi tag of jsonapi.InvalidPrimary.ID requires an exported string or integer field"))
+			Expect(ValidateTags(&InvalidPrimary{})).To(HaveOccurred())
+			Expect(ValidateTags(TaggedUser{})).To(Succeed())
+		})
+	})
+
+	Context("When unmarshalling", func() {
+		It("returns an error for invalid tags", func() {
+			var invalid InvalidTag
+			err := Unmarshal([]byte(`{"data": {"type": "invalids", "id": "1", "attributes": {"name": "Marvin"}}}`), &invalid)
+			Expect(err).To(MatchError(`jsonapi tag of field jsonapi.InvalidTag.Name: unknown kind "attribute"`))
+
+			var invalids []InvalidPrimary
+			err = Unmarshal([]byte(`{"data": [{"type": "invalids", "id": "1", "attributes": {"name": "Marvin"}}]}`), &invalids)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("sets id, attributes and relationships", func() {
+			var user TaggedUser
+			err := Unmarshal([]byte(`{
+				"data": {
+					"type": "members",
+					"id": "1",
+					"attributes": {"user-name": "Marvin", "email": "marvin@example.com", "Password": "secret"},
+					"relationships": {
+						"sweets": {"data": [{"type": "chocolates", "id": "2"}]},
+						"pet": {"data": {"type": "taggedPets", "id": "4"}}
+					}
+				}
+			}`), &user)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(user).To(Equal(TaggedUser{
+				ID:       1,
+				Name:     "Marvin",
+				Email:    "marvin@example.com",
+				SweetIDs: []string{"2"},
+				Pet:      &TaggedPet{ID: "4"},
+			}))
+		})
+
+		It("unmarshals included structs", func() {
+			var users []TaggedUser
+			err := Unmarshal([]byte(`{
+				"data": [{
+					"type": "members",
+					"id": "1",
+					"relationships": {"pet": {"data": {"type": "taggedPets", "id": "4"}}}
+				}],
+				"included": [{"type": "taggedPets", "id": "4", "attributes": {"name": "Fluffy"}}]
+			}`), &users)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(users).To(Equal([]TaggedUser{{ID: 1, Pet: &TaggedPet{ID: "4", Name: "Fluffy"}}}))
+		})
+
+		It("clears to-one relationships", func() {
+			user := TaggedUser{ID: 1, Pet: &TaggedPet{ID: "4"}}
+			err := Unmarshal([]byte(`{"data": {"type": "members", "id": "1", "relationships": {"pet": {"data": null}}}}`), &user)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(user.Pet).To(BeNil())
+		})
+
+		It("returns an error for invalid ids", func() {
+			var user TaggedUser
+			err := Unmarshal([]byte(`{"data": {"type": "members", "id": "one"}}`), &user)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error for unknown relationships", func() {
+			var user TaggedUser
+			err := Unmarshal([]byte(`{"data": {"type": "members", "id": "1", "relationships": {"sweets": {"data": {"type": "chocolates", "id": "2"}}}}}`), &user)
+			Expect(err).To(MatchError("There is no to-one relationship with the name sweets"))
+		})
+	})
+
+	Context("Adapt", func() {
+		It("returns structs that implement MarshalIdentifier unchanged", func() {
+			comment := Comment{ID: 1}
+			Expect(Adapt(comment)).To(Equal(comment))
+			Expect(Adapt("invalid")).To(Equal("invalid"))
+		})
+
+		It("edits to-many relationships", func() {
+			user := &TaggedUser{ID: 1, SweetIDs: []string{"1", "2"}}
+			editor, ok := Adapt(user).(EditToManyRelations)
+			Expect(ok).To(BeTrue())
+			Expect(editor.AddToManyIDs("sweets", []string{"2", "3"})).To(Succeed())
+			Expect(user.SweetIDs).To(Equal([]string{"1", "2", "3"}))
+			Expect(editor.DeleteToManyIDs("sweets", []string{"1"})).To(Succeed())
+			Expect(user.SweetIDs).To(Equal([]string{"2", "3"}))
+		})
+	})
+})
@@ -0,0 +1,259 @@
+package jsonapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// typeInfo contains the reflection metadata of a marshalled type, so that it
+// must be computed only once per type
+type typeInfo struct {
+	// name is the guessed type name, it is empty for EntityNamer types because
+	// their name can depend on the value
+	name string
+	// attributes contains the json member names of all attribute fields, it is
+	// nil if the type has a custom json encoding
+	attributes []string
+	// idAttribute is the json member name of the ID field or empty if the type
+	// has no such field
+	idAttribute string
+	// forbidden contains the member names that must not be attributes. It is
+	// only set for tagged types, whose relationships are known from the tags,
+	// for other types the relationships are taken from every value.
+	forbidden []string
+
+	entityNamer       bool
+	linkedRelations   bool
+	includedRelations bool
+	customLinks       bool
+	meta              bool
+}
+
+var (
+	entityNamerType       = reflect.TypeOf((*EntityNamer)(nil)).Elem()
+	linkedRelationsType   = reflect.TypeOf((*MarshalLinkedRelations)(nil)).Elem()
+	includedRelationsType = reflect.TypeOf((*MarshalIncludedRelations)(nil)).Elem()
+	customLinksType       = reflect.TypeOf((*MarshalCustomLinks)(nil)).Elem()
+	metaType              = reflect.TypeOf((*MarshalMeta)(nil)).Elem()
+	jsonMarshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType     = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+var typeInfoCache = struct {
+	sync.RWMutex
+	byType map[reflect.Type]*typeInfo
+	byName map[string][]*typeInfo
+}{
+	byType: map[reflect.Type]*typeInfo{},
+	byName: map[string][]*typeInfo{},
+}
+
+// getTypeInfo returns the cached metadata of the type of element
+func getTypeInfo(element interface{}) *typeInfo {
+	if tagged, ok := element.(*taggedResource); ok {
+		return tagged.tagged.info
+	}
+
+	reflectType := reflect.TypeOf(element)
+
+	typeInfoCache.RLock()
+	info, ok := typeInfoCache.byType[reflectType]
+	typeInfoCache.RUnlock()
+	if ok {
+		return info
+	}
+
+	info = newTypeInfo(reflectType)
+
+	typeInfoCache.Lock()
+	typeInfoCache.byType[reflectType] = info
+	typeInfoCache.Unlock()
+
+	return info
+}
+
+func newTypeInfo(reflectType reflect.Type) *typeInfo {
+	info := &typeInfo{
+		entityNamer:       reflectType.Implements(entityNamerType),
+		linkedRelations:   reflectType.Implements(linkedRelationsType),
+		includedRelations: reflectType.Implements(includedRelationsType),
+		customLinks:       reflectType.Implements(customLinksType),
+		meta:              reflectType.Implements(metaType),
+	}
+
+	structType := reflectType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	if !info.entityNamer {
+		info.name = Pluralize(Jsonify(structType.Name()))
+	}
+
+	if structType.Kind() == reflect.Struct && !hasCustomJSONEncoding(reflectType) {
+		info.attributes = getAttributeNames(structType)
+		info.idAttribute = getIDAttributeName(structType)
+	}
+
+	return info
+}
+
+// typeName returns the type name of element
+func (t *typeInfo) typeName(element interface{}) string {
+	if t.entityNamer {
+		return element.(EntityNamer).GetName()
+	}
+
+	return t.name
+}
+
+// register remembers the attributes of the type for the given type name, so
+// that they can be looked up with AttributeNames
+func (t *typeInfo) register(name string) {
+	typeInfoCache.RLock()
+	registered := containsTypeInfo(typeInfoCache.byName[name], t)
+	typeInfoCache.RUnlock()
+	if registered {
+		return
+	}
+
+	typeInfoCache.Lock()
+	if !containsTypeInfo(typeInfoCache.byName[name], t) {
+		typeInfoCache.byName[name] = append(typeInfoCache.byName[name], t)
+	}
+	typeInfoCache.Unlock()
+}
+
+func containsTypeInfo(infos []*typeInfo, info *typeInfo) bool {
+	for _, candidate := range infos {
+		if candidate == info {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AttributeNames returns the names of all attributes of the structs that were
+// marshalled with the given type name. It returns false if no such struct was
+// marshalled yet or if a struct has a custom json encoding, so that its
+// attributes are unknown. Members that are removed while marshalling are not
+// returned, members with the name of a relationship only for tagged structs.
+func AttributeNames(name string) ([]string, bool) {
+	return AttributeNamesWithOptions(name, Options{})
+}
+
+// AttributeNamesWithOptions is like AttributeNames but returns the attributes
+// that are marshalled with the options.
+func AttributeNamesWithOptions(name string, options Options) ([]string, bool) {
+	typeInfoCache.RLock()
+	defer typeInfoCache.RUnlock()
+
+	infos := typeInfoCache.byName[name]
+	if len(infos) == 0 {
+		return nil, false
+	}
+
+	names := []string{}
+	for _, info := range infos {
+		if info.attributes == nil {
+			return nil, false
+		}
+
+		var forbidden []string
+		if !options.LegacyAttributes {
+			forbidden = forbiddenAttributes(nil, info)
+		}
+
+		for _, attribute := range info.attributes {
+			if !containsString(names, attribute) && !containsString(forbidden, attribute) {
+				names = append(names, attribute)
+			}
+		}
+	}
+
+	return names, true
+}
+
+func hasCustomJSONEncoding(reflectType reflect.Type) bool {
+	if reflectType.Kind() != reflect.Ptr {
+		reflectType = reflect.PtrTo(reflectType)
+	}
+
+	return reflectType.Implements(jsonMarshalerType) || reflectType.Implements(textMarshalerType)
+}
+
+// getAttributeNames returns the json member names of a struct like
+// encoding/json computes them, including the fields of embedded structs
+func getAttributeNames(structType reflect.Type) []string {
+	names := []string{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			for _, embedded := range getAttributeNames(fieldType) {
+				if !containsString(names, embedded) {
+					names = append(names, embedded)
+				}
+			}
+			continue
+		}
+
+		// unexported fields are not marshalled
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// getIDAttributeName returns the json member name of the exported ID field
+func getIDAttributeName(structType reflect.Type) string {
+	field, ok := structType.FieldByName("ID")
+	if !ok || field.PkgPath != "" || len(field.Index) > 1 {
+		return ""
+	}
+
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,76 @@
+package jsonapi
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Timestamps struct {
+	Created string `json:"created"`
+	Updated string `json:"updated,omitempty"`
+}
+
+type Memo struct {
+	Timestamps
+	ID       string `json:"-"`
+	Title    string `json:"title"`
+	Body     string `json:",omitempty"`
+	internal string
+}
+
+func (d Memo) GetID() string {
+	return d.ID
+}
+
+func (d Memo) GetName() string {
+	return "memos"
+}
+
+type CustomJSONDocument struct {
+	ID string
+}
+
+func (d CustomJSONDocument) GetID() string {
+	return d.ID
+}
+
+func (d CustomJSONDocument) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"custom": "yes"})
+}
+
+var _ = Describe("Type metadata cache", func() {
+	It("caches the metadata per type", func() {
+		Expect(getTypeInfo(Comment{})).To(BeIdenticalTo(getTypeInfo(Comment{ID: 1})))
+		Expect(getTypeInfo(&Comment{})).ToNot(BeIdenticalTo(getTypeInfo(Comment{})))
+		Expect(getStructType(&Comment{})).To(Equal("comments"))
+	})
+
+	It("knows the implemented interfaces", func() {
+		info := getTypeInfo(Post{})
+		Expect(info.linkedRelations).To(BeTrue())
+		Expect(info.includedRelations).To(BeTrue())
+		Expect(info.entityNamer).To(BeFalse())
+		Expect(getTypeInfo(Memo{}).entityNamer).To(BeTrue())
+	})
+
+	It("returns the attribute names of marshalled types", func() {
+		_, err := Marshal(Memo{ID: "1"})
+		Expect(err).ToNot(HaveOccurred())
+
+		names, ok := AttributeNames("memos")
+		Expect(ok).To(BeTrue())
+		Expect(names).To(Equal([]string{"created", "updated", "title", "Body"}))
+	})
+
+	It("does not know the attributes of unknown types or types with custom json", func() {
+		_, ok := AttributeNames("not-marshalled-yet")
+		Expect(ok).To(BeFalse())
+
+		_, err := Marshal(CustomJSONDocument{ID: "1"})
+		Expect(err).ToNot(HaveOccurred())
+		_, ok = AttributeNames("custom-json-documents")
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -1,7 +1,6 @@
 package jsonapi
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -25,6 +24,47 @@ type UnmarshalToManyRelations interface {
 	SetToManyReferenceIDs(name string, IDs []string) error
 }
 
+// The UnmarshalMeta interface can be implemented to receive the
+// resource-level `meta` object during unmarshalling.
+type UnmarshalMeta interface {
+	SetMeta(meta map[string]interface{}) error
+}
+
+// The UnmarshalRelationshipMeta interface can be implemented to receive the
+// `meta` object of relationships during unmarshalling.
+type UnmarshalRelationshipMeta interface {
+	SetRelationshipMeta(name string, meta map[string]interface{}) error
+}
+
+// The UnmarshalRelationshipLinks interface can be implemented to receive the
+// links of relationships during unmarshalling.
+type UnmarshalRelationshipLinks interface {
+	SetRelationshipLinks(name string, links Links) error
+}
+
+// The UnmarshalToOneRelationsWithType interface can be implemented instead of
+// UnmarshalToOneRelations to unmarshal to-one relations together with the type
+// of the referenced struct, e.g. for polymorphic relationships. The ID of the
+// ReferenceID is empty if the relationship is cleared.
+type UnmarshalToOneRelationsWithType interface {
+	SetToOneReferenceIDWithType(name string, ID ReferenceID) error
+}
+
+// The UnmarshalToManyRelationsWithType interface can be implemented instead of
+// UnmarshalToManyRelations to unmarshal to-many relations together with the
+// types of the referenced structs, e.g. for polymorphic relationships.
+type UnmarshalToManyRelationsWithType interface {
+	SetToManyReferenceIDsWithType(name string, IDs []ReferenceID) error
+}
+
+// The UnmarshalIncludedRelations interface can be implemented to receive the
+// included records of a compound document that belong to a relationship. The
+// records can be unmarshalled with UnmarshalIncluded, which resolves their
+// relationships with the included records of the same document as well.
+type UnmarshalIncludedRelations interface {
+	SetReferencedStructs(name string, included []Data) error
+}
+
 // The EditToManyRelations interface can be optionally implemented to add and
 // delete to-many relationships on a already unmarshalled struct. These methods
 // are used by our API for the to-many relationship update routes.
@@ -76,9 +116,25 @@ type EditToManyRelations interface {
 	DeleteToManyIDs(name string, IDs []string) error
 }
 
+// The EditToManyRelationsWithType interface can be implemented instead of
+// EditToManyRelations to add and delete to-many relationships together with
+// the type of the referenced resources, e.g. for polymorphic relationships.
+type EditToManyRelationsWithType interface {
+	AddToManyIDsWithType(name string, IDs []ReferenceID) error
+	DeleteToManyIDsWithType(name string, IDs []ReferenceID) error
+}
+
 // Unmarshal parses a JSON API compatible JSON and populates the target which
-// must implement the `UnmarshalIdentifier` interface.
+// must implement the `UnmarshalIdentifier` interface or use `jsonapi` struct
+// tags, see Adapt. If the JSON is an error document, an ErrorDocument with the
+// decoded error objects is returned.
 func Unmarshal(data []byte, target interface{}) error {
+	return UnmarshalWithOptions(data, target, Options{})
+}
+
+// UnmarshalWithOptions is like Unmarshal but applies the options to the
+// primary data and all included records.
+func UnmarshalWithOptions(data []byte, target interface{}, options Options) error {
 	if target == nil {
 		return errors.New("target must not be nil")
 	}
@@ -89,17 +145,27 @@ func Unmarshal(data []byte, target interface{}) error {
 
 	ctx := &Document{}
 
-	err := json.Unmarshal(data, ctx)
+	err := options.jsonCodec().Unmarshal(data, ctx)
 	if err != nil {
 		return err
 	}
 
+	if ctx.Errors != nil {
+		return ErrorDocument{Errors: ctx.Errors}
+	}
+
 	if ctx.Data == nil {
 		return errors.New(`Source JSON is empty and has no "attributes" payload object`)
 	}
 
+	included := newIncludedRecords(ctx, options)
+
 	if ctx.Data.DataObject != nil {
-		return setDataIntoTarget(ctx.Data.DataObject, target)
+		if err := setDataIntoTarget(ctx.Data.DataObject, target, options); err != nil {
+			return err
+		}
+
+		return setIncludedRelations(ctx.Data.DataObject, target, included.descend(*ctx.Data.DataObject))
 	}
 
 	if ctx.Data.DataArray != nil {
@@ -116,7 +182,10 @@ func Unmarshal(data []byte, target interface{}) error {
 			// otherwise create a new target and append
 			var targetRecord, emptyValue reflect.Value
 			for i := 0; i < targetValue.Len(); i++ {
-				marshalCasted, ok := targetValue.Index(i).Interface().(MarshalIdentifier)
+				marshalCasted, ok, err := asMarshalIdentifier(targetValue.Index(i).Interface())
+				if err != nil {
+					return err
+				}
 				if !ok {
 					return errors.New("existing structs must implement interface MarshalIdentifier")
 				}
@@ -128,13 +197,21 @@ func Unmarshal(data []byte, target interface{}) error {
 
 			if targetRecord == emptyValue || targetRecord.IsNil() {
 				targetRecord = reflect.New(targetType)
-				err := setDataIntoTarget(&record, targetRecord.Interface())
+				err := setDataIntoTarget(&record, targetRecord.Interface(), options)
+				if err != nil {
+					return err
+				}
+				err = setIncludedRelations(&record, targetRecord.Interface(), included.descend(record))
 				if err != nil {
 					return err
 				}
 				targetValue = reflect.Append(targetValue, targetRecord.Elem())
 			} else {
-				err := setDataIntoTarget(&record, targetRecord.Interface())
+				err := setDataIntoTarget(&record, targetRecord.Interface(), options)
+				if err != nil {
+					return err
+				}
+				err = setIncludedRelations(&record, targetRecord.Interface(), included.descend(record))
 				if err != nil {
 					return err
 				}
@@ -147,8 +224,132 @@ func Unmarshal(data []byte, target interface{}) error {
 	return nil
 }
 
-func setDataIntoTarget(data *Data, target interface{}) error {
-	castedTarget, ok := target.(UnmarshalIdentifier)
+// UnmarshalIncluded populates the target with an included record that was
+// passed to SetReferencedStructs. If the target implements
+// UnmarshalIncludedRelations, the relationships of the record are resolved with
+// the included records of the same document. Records that reference one of
+// their ancestors are unmarshalled without resolving their relationships
+// again, so cyclic documents can be unmarshalled as well.
+func UnmarshalIncluded(data Data, target interface{}) error {
+	var options Options
+	if data.included != nil {
+		options = data.included.options
+	}
+
+	if err := setDataIntoTarget(&data, target, options); err != nil {
+		return err
+	}
+
+	return setIncludedRelations(&data, target, data.included)
+}
+
+// includedRecords contains all records of a compound document by type and id
+// together with the records that are currently being resolved and the options
+// of the Unmarshal call
+type includedRecords struct {
+	records   map[string]map[string]Data
+	ancestors map[string]map[string]bool
+	options   Options
+}
+
+func newIncludedRecords(document *Document, options Options) *includedRecords {
+	if len(document.Included) == 0 {
+		return nil
+	}
+
+	included := &includedRecords{
+		records:   map[string]map[string]Data{},
+		ancestors: map[string]map[string]bool{},
+		options:   options,
+	}
+
+	// primary data can be referenced by included records as well
+	primary := document.Data.DataArray
+	if document.Data.DataObject != nil {
+		primary = []Data{*document.Data.DataObject}
+	}
+
+	for _, records := range [][]Data{primary, document.Included} {
+		for _, record := range records {
+			if included.records[record.Type] == nil {
+				included.records[record.Type] = map[string]Data{}
+			}
+			included.records[record.Type][record.ID] = record
+		}
+	}
+
+	return included
+}
+
+// descend returns the included records for the relationships of record, or
+// nil if the record is already being resolved
+func (i *includedRecords) descend(record Data) *includedRecords {
+	if i == nil || i.ancestors[record.Type][record.ID] {
+		return nil
+	}
+
+	ancestors := map[string]map[string]bool{}
+	for resourceType, IDs := range i.ancestors {
+		ancestors[resourceType] = map[string]bool{}
+		for ID := range IDs {
+			ancestors[resourceType][ID] = true
+		}
+	}
+
+	if ancestors[record.Type] == nil {
+		ancestors[record.Type] = map[string]bool{}
+	}
+	ancestors[record.Type][record.ID] = true
+
+	return &includedRecords{records: i.records, ancestors: ancestors, options: i.options}
+}
+
+// passes the included records of all relationships to the target if it
+// implements UnmarshalIncludedRelations
+func setIncludedRelations(data *Data, target interface{}, included *includedRecords) error {
+	castedTarget, ok := Adapt(target).(UnmarshalIncludedRelations)
+	if !ok || included == nil {
+		return nil
+	}
+
+	for name, rel := range data.Relationships {
+		if rel.Data == nil {
+			continue
+		}
+
+		identifiers := rel.Data.DataArray
+		if rel.Data.DataObject != nil {
+			identifiers = []RelationshipData{*rel.Data.DataObject}
+		}
+
+		records := []Data{}
+		for _, identifier := range identifiers {
+			record, ok := included.records[identifier.Type][identifier.ID]
+			if !ok {
+				continue
+			}
+
+			record.included = included.descend(record)
+			records = append(records, record)
+		}
+
+		if len(records) == 0 {
+			continue
+		}
+
+		if err := castedTarget.SetReferencedStructs(name, records); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setDataIntoTarget(data *Data, target interface{}, options Options) error {
+	castedTarget, ok, err := asUnmarshalIdentifier(target)
+	if err != nil {
+		return err
+	}
 	if !ok {
 		return errors.New("target must implement UnmarshalIdentifier interface")
 	}
@@ -157,13 +358,17 @@ func setDataIntoTarget(data *Data, target interface{}) error {
 		return errors.New("invalid record, no type was specified")
 	}
 
-	err := checkType(data.Type, castedTarget)
+	err = checkType(data.Type, castedTarget)
 	if err != nil {
 		return err
 	}
 
+	if err := checkForbiddenAttributes(data.Attributes, castedTarget, options); err != nil {
+		return err
+	}
+
 	if data.Attributes != nil {
-		err = json.Unmarshal(data.Attributes, castedTarget)
+		err = unmarshalAttributes(data.Attributes, castedTarget, options.jsonCodec())
 		if err != nil {
 			return err
 		}
@@ -173,31 +378,62 @@ func setDataIntoTarget(data *Data, target interface{}) error {
 		return err
 	}
 
+	if metaTarget, ok := castedTarget.(UnmarshalMeta); ok && data.Meta != nil {
+		if err := metaTarget.SetMeta(data.Meta); err != nil {
+			return err
+		}
+	}
+
+	if err := setRelationshipMetaAndLinks(data.Relationships, castedTarget); err != nil {
+		return err
+	}
+
 	return setRelationshipIDs(data.Relationships, castedTarget)
 }
 
+// passes the meta objects and links of all relationships to the target if it
+// implements UnmarshalRelationshipMeta or UnmarshalRelationshipLinks
+func setRelationshipMetaAndLinks(relationships map[string]Relationship, target UnmarshalIdentifier) error {
+	metaTarget, hasMeta := target.(UnmarshalRelationshipMeta)
+	linksTarget, hasLinks := target.(UnmarshalRelationshipLinks)
+
+	for name, rel := range relationships {
+		if hasMeta && rel.Meta != nil {
+			if err := metaTarget.SetRelationshipMeta(name, rel.Meta); err != nil {
+				return err
+			}
+		}
+
+		if hasLinks && rel.Links != nil {
+			if err := linksTarget.SetRelationshipLinks(name, rel.Links); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 // extracts all found relationships and set's them via SetToOneReferenceID or
-// SetToManyReferenceIDs
+// SetToManyReferenceIDs, or their variants with type if they are implemented
 func setRelationshipIDs(relationships map[string]Relationship, target UnmarshalIdentifier) error {
 	for name, rel := range relationships {
 		// if Data is nil, it means that we have an empty toOne relationship
 		if rel.Data == nil {
-			castedToOne, ok := target.(UnmarshalToOneRelations)
-			if !ok {
-				return fmt.Errorf("struct %s does not implement UnmarshalToOneRelations", reflect.TypeOf(target))
+			if err := clearToOneReferenceID(target, name); err != nil {
+				return err
 			}
-
-			castedToOne.SetToOneReferenceID(name, "")
 			break
 		}
 
 		// valid toOne case
 		if rel.Data.DataObject != nil {
-			castedToOne, ok := target.(UnmarshalToOneRelations)
-			if !ok {
-				return fmt.Errorf("struct %s does not implement UnmarshalToOneRelations", reflect.TypeOf(target))
-			}
-			err := castedToOne.SetToOneReferenceID(name, rel.Data.DataObject.ID)
+			err := setToOneReferenceID(target, ReferenceID{
+				ID:           rel.Data.DataObject.ID,
+				Type:         rel.Data.DataObject.Type,
+				Name:         name,
+				Relationship: ToOneRelationship,
+			})
 			if err != nil {
 				return err
 			}
@@ -205,15 +441,16 @@ func setRelationshipIDs(relationships map[string]Relationship, target UnmarshalI
 
 		// valid toMany case
 		if rel.Data.DataArray != nil {
-			castedToMany, ok := target.(UnmarshalToManyRelations)
-			if !ok {
-				return fmt.Errorf("struct %s does not implement UnmarshalToManyRelations", reflect.TypeOf(target))
-			}
-			IDs := make([]string, len(rel.Data.DataArray))
+			IDs := make([]ReferenceID, len(rel.Data.DataArray))
 			for index, relData := range rel.Data.DataArray {
-				IDs[index] = relData.ID
+				IDs[index] = ReferenceID{
+					ID:           relData.ID,
+					Type:         relData.Type,
+					Name:         name,
+					Relationship: ToManyRelationship,
+				}
 			}
-			err := castedToMany.SetToManyReferenceIDs(name, IDs)
+			err := setToManyReferenceIDs(target, name, IDs)
 			if err != nil {
 				return err
 			}
@@ -223,6 +460,51 @@ func setRelationshipIDs(relationships map[string]Relationship, target UnmarshalI
 	return nil
 }
 
+func setToOneReferenceID(target UnmarshalIdentifier, ID ReferenceID) error {
+	if castedWithType, ok := target.(UnmarshalToOneRelationsWithType); ok {
+		return castedWithType.SetToOneReferenceIDWithType(ID.Name, ID)
+	}
+
+	castedToOne, ok := target.(UnmarshalToOneRelations)
+	if !ok {
+		return fmt.Errorf("struct %s does not implement UnmarshalToOneRelations", reflect.TypeOf(target))
+	}
+
+	return castedToOne.SetToOneReferenceID(ID.Name, ID.ID)
+}
+
+func clearToOneReferenceID(target UnmarshalIdentifier, name string) error {
+	if castedWithType, ok := target.(UnmarshalToOneRelationsWithType); ok {
+		return castedWithType.SetToOneReferenceIDWithType(name, ReferenceID{Name: name, Relationship: ToOneRelationship})
+	}
+
+	castedToOne, ok := target.(UnmarshalToOneRelations)
+	if !ok {
+		return fmt.Errorf("struct %s does not implement UnmarshalToOneRelations", reflect.TypeOf(target))
+	}
+
+	castedToOne.SetToOneReferenceID(name, "")
+	return nil
+}
+
+func setToManyReferenceIDs(target UnmarshalIdentifier, name string, IDs []ReferenceID) error {
+	if castedWithType, ok := target.(UnmarshalToManyRelationsWithType); ok {
+		return castedWithType.SetToManyReferenceIDsWithType(name, IDs)
+	}
+
+	castedToMany, ok := target.(UnmarshalToManyRelations)
+	if !ok {
+		return fmt.Errorf("struct %s does not implement UnmarshalToManyRelations", reflect.TypeOf(target))
+	}
+
+	plainIDs := make([]string, len(IDs))
+	for index, ID := range IDs {
+		plainIDs[index] = ID.ID
+	}
+
+	return castedToMany.SetToManyReferenceIDs(name, plainIDs)
+}
+
 func checkType(incomingType string, target UnmarshalIdentifier) error {
 	actualType := getStructType(target)
 	if incomingType != actualType {
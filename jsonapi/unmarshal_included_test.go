@@ -0,0 +1,142 @@
+package jsonapi
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Story struct {
+	ID     string   `json:"-"`
+	Title  string   `json:"title"`
+	Writer *Writer  `json:"-"`
+	Tags   []string `json:"-"`
+}
+
+func (s Story) GetID() string {
+	return s.ID
+}
+
+func (s *Story) SetID(ID string) error {
+	s.ID = ID
+	return nil
+}
+
+func (s *Story) SetToOneReferenceID(name, ID string) error {
+	return nil
+}
+
+func (s *Story) SetToManyReferenceIDs(name string, IDs []string) error {
+	s.Tags = IDs
+	return nil
+}
+
+func (s *Story) SetReferencedStructs(name string, included []Data) error {
+	if name != "writer" {
+		return nil
+	}
+
+	s.Writer = &Writer{}
+	return UnmarshalIncluded(included[0], s.Writer)
+}
+
+type Writer struct {
+	ID      string  `json:"-"`
+	Name    string  `json:"name"`
+	Stories []Story `json:"-"`
+}
+
+func (w *Writer) SetID(ID string) error {
+	w.ID = ID
+	return nil
+}
+
+func (w *Writer) SetToManyReferenceIDs(name string, IDs []string) error {
+	return nil
+}
+
+func (w *Writer) SetReferencedStructs(name string, included []Data) error {
+	for _, record := range included {
+		var story Story
+		if err := UnmarshalIncluded(record, &story); err != nil {
+			return err
+		}
+		w.Stories = append(w.Stories, story)
+	}
+
+	return nil
+}
+
+var _ = Describe("Unmarshalling included records", func() {
+	compoundDocument := `{
+		"data": {
+			"type": "stories",
+			"id": "1",
+			"attributes": {"title": "First"},
+			"relationships": {
+				"writer": {"data": {"type": "writers", "id": "1"}},
+				"tags": {"data": [{"type": "tags", "id": "1"}]}
+			}
+		},
+		"included": [
+			{
+				"type": "writers",
+				"id": "1",
+				"attributes": {"name": "Marvin"},
+				"relationships": {
+					"stories": {"data": [{"type": "stories", "id": "1"}, {"type": "stories", "id": "2"}]}
+				}
+			},
+			{
+				"type": "stories",
+				"id": "2",
+				"attributes": {"title": "Second"},
+				"relationships": {
+					"writer": {"data": {"type": "writers", "id": "1"}}
+				}
+			}
+		]
+	}`
+
+	It("populates referenced structs and stops at cycles", func() {
+		var story Story
+		err := Unmarshal([]byte(compoundDocument), &story)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(story.Title).To(Equal("First"))
+		Expect(story.Tags).To(Equal([]string{"1"}))
+		Expect(story.Writer).ToNot(BeNil())
+		Expect(story.Writer.Name).To(Equal("Marvin"))
+		Expect(story.Writer.Stories).To(HaveLen(2))
+
+		// the primary data and the writer are already being resolved
+		Expect(story.Writer.Stories[0].Title).To(Equal("First"))
+		Expect(story.Writer.Stories[0].Writer).To(BeNil())
+
+		second := story.Writer.Stories[1]
+		Expect(second.Title).To(Equal("Second"))
+		Expect(second.Writer).ToNot(BeNil())
+		Expect(second.Writer.Name).To(Equal("Marvin"))
+		Expect(second.Writer.Stories).To(BeEmpty())
+	})
+
+	It("populates referenced structs of every record in arrays", func() {
+		var stories []Story
+		err := Unmarshal([]byte(`{
+			"data": [
+				{"type": "stories", "id": "1", "relationships": {"writer": {"data": {"type": "writers", "id": "1"}}}},
+				{"type": "stories", "id": "2", "relationships": {"writer": {"data": null}}}
+			],
+			"included": [{"type": "writers", "id": "1", "attributes": {"name": "Marvin"}}]
+		}`), &stories)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stories).To(HaveLen(2))
+		Expect(stories[0].Writer.Name).To(Equal("Marvin"))
+		Expect(stories[1].Writer).To(BeNil())
+	})
+
+	It("does not call SetReferencedStructs without included records", func() {
+		var story Story
+		err := Unmarshal([]byte(`{"data": {"type": "stories", "id": "1", "relationships": {"writer": {"data": {"type": "writers", "id": "1"}}}}}`), &story)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(story.Writer).To(BeNil())
+	})
+})
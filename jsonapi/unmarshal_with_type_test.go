@@ -0,0 +1,58 @@
+package jsonapi
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Activity struct {
+	ID      string        `json:"-"`
+	Title   string        `json:"title"`
+	Subject ReferenceID   `json:"-"`
+	Items   []ReferenceID `json:"-"`
+}
+
+func (a *Activity) SetID(ID string) error {
+	a.ID = ID
+	return nil
+}
+
+func (a *Activity) SetToOneReferenceIDWithType(name string, ID ReferenceID) error {
+	a.Subject = ID
+	return nil
+}
+
+func (a *Activity) SetToManyReferenceIDsWithType(name string, IDs []ReferenceID) error {
+	a.Items = IDs
+	return nil
+}
+
+var _ = Describe("Unmarshalling polymorphic relationships", func() {
+	It("passes the type of to-one and to-many relationships", func() {
+		var activity Activity
+		err := Unmarshal([]byte(`{
+			"data": {
+				"type": "activities",
+				"id": "1",
+				"attributes": {"title": "New feedback"},
+				"relationships": {
+					"subject": {"data": {"type": "users", "id": "2"}},
+					"items": {"data": [{"type": "comments", "id": "3"}, {"type": "reviews", "id": "3"}]}
+				}
+			}
+		}`), &activity)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(activity.Subject).To(Equal(ReferenceID{ID: "2", Type: "users", Name: "subject", Relationship: ToOneRelationship}))
+		Expect(activity.Items).To(Equal([]ReferenceID{
+			{ID: "3", Type: "comments", Name: "items", Relationship: ToManyRelationship},
+			{ID: "3", Type: "reviews", Name: "items", Relationship: ToManyRelationship},
+		}))
+	})
+
+	It("passes an empty reference id for cleared to-one relationships", func() {
+		activity := Activity{Subject: ReferenceID{ID: "2", Type: "users"}}
+		err := Unmarshal([]byte(`{"data": {"type": "activities", "id": "1", "relationships": {"subject": {"data": null}}}}`), &activity)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(activity.Subject).To(Equal(ReferenceID{Name: "subject", Relationship: ToOneRelationship}))
+	})
+})
@@ -0,0 +1,417 @@
+package jsonapi
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Violation describes a part of a document that does not conform to the JSON
+// API specification. Pointer is a JSON Pointer to the offending member.
+type Violation struct {
+	Pointer string
+	Message string
+}
+
+// String returns the pointer and the message of the violation.
+func (v Violation) String() string {
+	return v.Pointer + ": " + v.Message
+}
+
+var topLevelMembers = map[string]bool{
+	"data":     true,
+	"errors":   true,
+	"meta":     true,
+	"jsonapi":  true,
+	"links":    true,
+	"included": true,
+}
+
+// Validate checks a JSON encoded document against the JSON API specification
+// and returns all found violations. It is meant to be used in tests of custom
+// responders or manually built documents, e.g.
+//
+//	Expect(jsonapi.Validate(rec.Body.Bytes())).To(BeEmpty())
+//
+// See http://jsonapi.org/format/#document-structure for the rules.
+func Validate(doc []byte) []Violation {
+	var document interface{}
+	if err := jsonUnmarshal(doc, &document); err != nil {
+		return []Violation{{Pointer: "", Message: "invalid json: " + err.Error()}}
+	}
+
+	v := &validator{resources: map[string]string{}}
+	v.validateDocument(document)
+
+	return v.violations
+}
+
+type validator struct {
+	violations []Violation
+	// resources contains the pointer of each resource object by type and id
+	resources map[string]string
+}
+
+func (v *validator) add(pointer, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validateDocument(value interface{}) {
+	document, ok := value.(map[string]interface{})
+	if !ok {
+		v.add("", "a document must be an object")
+		return
+	}
+
+	_, hasData := document["data"]
+	_, hasErrors := document["errors"]
+	_, hasMeta := document["meta"]
+	_, hasIncluded := document["included"]
+
+	if !hasData && !hasErrors && !hasMeta {
+		v.add("", "a document must contain at least one of data, errors or meta")
+	}
+
+	if hasData && hasErrors {
+		v.add("", "data and errors must not coexist in the same document")
+	}
+
+	if hasIncluded && !hasData {
+		v.add("/included", "included must not be present without data")
+	}
+
+	for _, name := range sortedKeys(document) {
+		if !topLevelMembers[name] && !strings.Contains(name, ":") {
+			v.add("/"+escapePointer(name), "%s is not a valid top-level member", name)
+		}
+	}
+
+	if links, ok := document["links"]; ok {
+		v.validateLinks(links, "/links")
+	}
+
+	primary := []map[string]interface{}{}
+	if data, ok := document["data"]; ok {
+		primary = v.validatePrimaryData(data)
+	}
+
+	if included, ok := document["included"]; ok {
+		v.validateIncluded(included, primary)
+	}
+
+	if errors, ok := document["errors"]; ok {
+		v.validateErrors(errors)
+	}
+}
+
+// validatePrimaryData returns all resource objects of the primary data
+func (v *validator) validatePrimaryData(data interface{}) []map[string]interface{} {
+	switch typed := data.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		return v.validateResources([]interface{}{typed}, "/data", false, false)
+	case []interface{}:
+		return v.validateResources(typed, "/data", true, false)
+	default:
+		v.add("/data", "data must be null, an object or an array")
+		return nil
+	}
+}
+
+func (v *validator) validateIncluded(included interface{}, primary []map[string]interface{}) {
+	records, ok := included.([]interface{})
+	if !ok {
+		v.add("/included", "included must be an array")
+		return
+	}
+
+	resources := v.validateResources(records, "/included", true, true)
+
+	// every included resource must be reachable by a chain of relationships
+	// starting at the primary data
+	reached := map[string]bool{}
+	queue := primary
+	for len(queue) > 0 {
+		resource := queue[0]
+		queue = queue[1:]
+		for _, identifier := range linkedIdentifiers(resource) {
+			if reached[identifier] {
+				continue
+			}
+			reached[identifier] = true
+
+			for _, candidate := range resources {
+				if resourceKey(candidate) == identifier {
+					queue = append(queue, candidate)
+				}
+			}
+		}
+	}
+
+	for index, resource := range resources {
+		if resource == nil {
+			continue
+		}
+
+		if !reached[resourceKey(resource)] {
+			v.add(fmt.Sprintf("/included/%d", index), "included resource is not referenced by a relationship of the primary data or another included resource (full linkage)")
+		}
+	}
+}
+
+// validateResources validates resource objects and returns them at the same
+// indexes, invalid resource objects are nil
+func (v *validator) validateResources(records []interface{}, pointer string, isArray, requireID bool) []map[string]interface{} {
+	resources := make([]map[string]interface{}, len(records))
+	for index, record := range records {
+		resourcePointer := pointer
+		if isArray {
+			resourcePointer = fmt.Sprintf("%s/%d", pointer, index)
+		}
+
+		resource, ok := record.(map[string]interface{})
+		if !ok {
+			v.add(resourcePointer, "a resource object must be an object")
+			continue
+		}
+
+		if v.validateResource(resource, resourcePointer, requireID) {
+			resources[index] = resource
+		}
+	}
+
+	return resources
+}
+
+func (v *validator) validateResource(resource map[string]interface{}, pointer string, requireID bool) bool {
+	valid := true
+	resourceType, ok := resource["type"].(string)
+	if !ok || resourceType == "" {
+		v.add(pointer+"/type", "a resource object must have a type member with a string")
+		valid = false
+	}
+
+	ID, hasID := resource["id"]
+	if _, ok := ID.(string); hasID && !ok {
+		v.add(pointer+"/id", "the id member must be a string")
+		valid = false
+	} else if !hasID && requireID {
+		v.add(pointer, "a resource object must have an id member")
+		valid = false
+	}
+
+	if attributes, ok := resource["attributes"]; ok {
+		v.validateMemberNames(attributes, pointer+"/attributes")
+	}
+
+	if relationships, ok := resource["relationships"]; ok {
+		v.validateRelationships(relationships, pointer+"/relationships")
+	}
+
+	if links, ok := resource["links"]; ok {
+		v.validateLinks(links, pointer+"/links")
+	}
+
+	if valid && hasID {
+		key := resourceKey(resource)
+		if first, ok := v.resources[key]; ok {
+			v.add(pointer, "duplicate resource object with type %s and id %s, first defined at %s", resourceType, ID, first)
+		} else {
+			v.resources[key] = pointer
+		}
+	}
+
+	return valid
+}
+
+func (v *validator) validateRelationships(value interface{}, pointer string) {
+	relationships, ok := value.(map[string]interface{})
+	if !ok {
+		v.add(pointer, "relationships must be an object")
+		return
+	}
+
+	v.validateMemberNames(relationships, pointer)
+
+	for _, name := range sortedKeys(relationships) {
+		relationshipPointer := pointer + "/" + escapePointer(name)
+		relationship, ok := relationships[name].(map[string]interface{})
+		if !ok {
+			v.add(relationshipPointer, "a relationship must be an object")
+			continue
+		}
+
+		_, hasData := relationship["data"]
+		_, hasLinks := relationship["links"]
+		_, hasMeta := relationship["meta"]
+		if !hasData && !hasLinks && !hasMeta {
+			v.add(relationshipPointer, "a relationship must contain at least one of links, data or meta")
+		}
+
+		if links, ok := relationship["links"]; ok {
+			v.validateLinks(links, relationshipPointer+"/links")
+		}
+
+		switch data := relationship["data"].(type) {
+		case nil:
+		case map[string]interface{}:
+			v.validateIdentifier(data, relationshipPointer+"/data")
+		case []interface{}:
+			for index, identifier := range data {
+				v.validateIdentifier(identifier, fmt.Sprintf("%s/data/%d", relationshipPointer, index))
+			}
+		default:
+			v.add(relationshipPointer+"/data", "resource linkage must be null, an object or an array")
+		}
+	}
+}
+
+func (v *validator) validateIdentifier(value interface{}, pointer string) {
+	identifier, ok := value.(map[string]interface{})
+	if !ok {
+		v.add(pointer, "a resource identifier object must be an object")
+		return
+	}
+
+	if _, ok := identifier["type"].(string); !ok {
+		v.add(pointer+"/type", "a resource identifier object must have a type member with a string")
+	}
+
+	if _, ok := identifier["id"].(string); !ok {
+		v.add(pointer+"/id", "a resource identifier object must have an id member with a string")
+	}
+}
+
+func (v *validator) validateLinks(value interface{}, pointer string) {
+	links, ok := value.(map[string]interface{})
+	if !ok {
+		v.add(pointer, "links must be an object")
+		return
+	}
+
+	for _, name := range sortedKeys(links) {
+		linkPointer := pointer + "/" + escapePointer(name)
+		switch link := links[name].(type) {
+		case nil, string:
+		case map[string]interface{}:
+			if _, ok := link["href"].(string); !ok {
+				v.add(linkPointer, "a link object must have a href member with a string")
+			}
+		case []interface{}:
+			// the profile link of the top-level links object is an array of links
+			if pointer == "/links" && name == "profile" {
+				for index, profile := range link {
+					v.validateLinks(map[string]interface{}{strconv.Itoa(index): profile}, linkPointer)
+				}
+				continue
+			}
+			v.add(linkPointer, "a link must be a string or an object with a href member")
+		default:
+			v.add(linkPointer, "a link must be a string or an object with a href member")
+		}
+	}
+}
+
+func (v *validator) validateErrors(value interface{}) {
+	errors, ok := value.([]interface{})
+	if !ok {
+		v.add("/errors", "errors must be an array")
+		return
+	}
+
+	for index, value := range errors {
+		errorPointer := fmt.Sprintf("/errors/%d", index)
+		errorObject, ok := value.(map[string]interface{})
+		if !ok {
+			v.add(errorPointer, "an error object must be an object")
+			continue
+		}
+
+		if links, ok := errorObject["links"]; ok {
+			v.validateLinks(links, errorPointer+"/links")
+		}
+	}
+}
+
+// validateMemberNames checks the names of all members of an object
+func (v *validator) validateMemberNames(value interface{}, pointer string) {
+	members, ok := value.(map[string]interface{})
+	if !ok {
+		v.add(pointer, "%s must be an object", pointer[strings.LastIndex(pointer, "/")+1:])
+		return
+	}
+
+	for _, name := range sortedKeys(members) {
+		if !isValidMemberName(name) {
+			v.add(pointer+"/"+escapePointer(name), "%q is not a valid member name", name)
+		}
+	}
+}
+
+// isValidMemberName checks the rules of http://jsonapi.org/format/#document-member-names
+func isValidMemberName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	runes := []rune(name)
+	for index, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r >= 0x80:
+		case r == '-' || r == '_' || r == ' ':
+			// allowed, but not at the beginning or the end
+			if index == 0 || index == len(runes)-1 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func linkedIdentifiers(resource map[string]interface{}) []string {
+	if resource == nil {
+		return nil
+	}
+
+	relationships, _ := resource["relationships"].(map[string]interface{})
+	identifiers := []string{}
+	for _, name := range sortedKeys(relationships) {
+		relationship, _ := relationships[name].(map[string]interface{})
+		switch data := relationship["data"].(type) {
+		case map[string]interface{}:
+			identifiers = append(identifiers, resourceKey(data))
+		case []interface{}:
+			for _, identifier := range data {
+				if casted, ok := identifier.(map[string]interface{}); ok {
+					identifiers = append(identifiers, resourceKey(casted))
+				}
+			}
+		}
+	}
+
+	return identifiers
+}
+
+func resourceKey(resource map[string]interface{}) string {
+	return fmt.Sprintf("%v/%v", resource["type"], resource["id"])
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// escapePointer escapes a reference token of a JSON Pointer
+func escapePointer(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
@@ -0,0 +1,106 @@
+package jsonapi
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validating documents", func() {
+	It("accepts documents built by Marshal", func() {
+		post := Post{
+			ID:          1,
+			Title:       "Foobar",
+			Comments:    []Comment{{ID: 1, Text: "First!"}, {ID: 2, Text: "Second!"}},
+			CommentsIDs: []int{1, 2},
+		}
+		result, err := Marshal([]Post{post})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(Validate(result)).To(BeEmpty())
+	})
+
+	It("accepts error documents and meta only documents", func() {
+		Expect(Validate([]byte(`{"errors": [{"status": "404", "links": {"about": "http://example.com"}}]}`))).To(BeEmpty())
+		Expect(Validate([]byte(`{"meta": {"count": 1}}`))).To(BeEmpty())
+		Expect(Validate([]byte(`{"data": null}`))).To(BeEmpty())
+	})
+
+	It("reports invalid json", func() {
+		violations := Validate([]byte(`{"data": `))
+		Expect(violations).To(HaveLen(1))
+		Expect(violations[0].Pointer).To(Equal(""))
+	})
+
+	It("reports missing top-level members", func() {
+		Expect(Validate([]byte(`{"links": {"self": "/posts"}}`))).To(Equal([]Violation{
+			{Pointer: "", Message: "a document must contain at least one of data, errors or meta"},
+		}))
+	})
+
+	It("reports data and errors together", func() {
+		Expect(Validate([]byte(`{"data": null, "errors": []}`))).To(Equal([]Violation{
+			{Pointer: "", Message: "data and errors must not coexist in the same document"},
+		}))
+	})
+
+	It("reports included resources without full linkage", func() {
+		Expect(Validate([]byte(`{
+			"data": {"type": "posts", "id": "1", "relationships": {"author": {"data": {"type": "users", "id": "1"}}}},
+			"included": [
+				{"type": "users", "id": "1", "relationships": {"avatar": {"data": {"type": "images", "id": "1"}}}},
+				{"type": "images", "id": "1"},
+				{"type": "comments", "id": "1"}
+			]
+		}`))).To(Equal([]Violation{
+			{Pointer: "/included/2", Message: "included resource is not referenced by a relationship of the primary data or another included resource (full linkage)"},
+		}))
+	})
+
+	It("reports duplicate resources", func() {
+		Expect(Validate([]byte(`{
+			"data": [{"type": "posts", "id": "1"}, {"type": "posts", "id": "1"}]
+		}`))).To(Equal([]Violation{
+			{Pointer: "/data/1", Message: "duplicate resource object with type posts and id 1, first defined at /data/0"},
+		}))
+	})
+
+	It("reports illegal member names", func() {
+		Expect(Validate([]byte(`{
+			"data": {
+				"type": "posts",
+				"id": "1",
+				"attributes": {"title": "", "-draft": true, "a.b": 1, "café au lait": 2},
+				"relationships": {"author/editor": {"data": null}}
+			},
+			"unknown": true
+		}`))).To(Equal([]Violation{
+			{Pointer: "/unknown", Message: "unknown is not a valid top-level member"},
+			{Pointer: "/data/attributes/-draft", Message: `"-draft" is not a valid member name`},
+			{Pointer: "/data/attributes/a.b", Message: `"a.b" is not a valid member name`},
+			{Pointer: "/data/relationships/author~1editor", Message: `"author/editor" is not a valid member name`},
+		}))
+	})
+
+	It("reports invalid links", func() {
+		Expect(Validate([]byte(`{
+			"links": {"self": {"href": "/posts"}, "next": 2, "prev": {"meta": {}}, "profile": ["http://example.com/profile", {}]},
+			"data": []
+		}`))).To(Equal([]Violation{
+			{Pointer: "/links/next", Message: "a link must be a string or an object with a href member"},
+			{Pointer: "/links/prev", Message: "a link object must have a href member with a string"},
+			{Pointer: "/links/profile/1", Message: "a link object must have a href member with a string"},
+		}))
+	})
+
+	It("reports invalid resource objects", func() {
+		Expect(Validate([]byte(`{
+			"data": {"id": 1, "relationships": {"author": {}, "comments": {"data": [{"type": "comments"}]}}},
+			"included": [{"type": "users"}]
+		}`))).To(Equal([]Violation{
+			{Pointer: "/data/type", Message: "a resource object must have a type member with a string"},
+			{Pointer: "/data/id", Message: "the id member must be a string"},
+			{Pointer: "/data/relationships/author", Message: "a relationship must contain at least one of links, data or meta"},
+			{Pointer: "/data/relationships/comments/data/0/id", Message: "a resource identifier object must have an id member with a string"},
+			{Pointer: "/included/0", Message: "a resource object must have an id member"},
+		}))
+	})
+})
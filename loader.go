@@ -0,0 +1,317 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// batchLoader loads resources by id during one request. All ids of a resource that are requested
+// together are loaded with one FindMany call if the resource implements it, otherwise FindOne is
+// called for every id. Ids are only looked up once per loader.
+type batchLoader struct {
+	api *API
+	c   APIContexter
+	r   *http.Request
+	// loaded contains the objects by resource name and id, nil for ids that do not exist
+	loaded map[string]map[string]interface{}
+}
+
+func newBatchLoader(api *API, c APIContexter, r *http.Request) *batchLoader {
+	return &batchLoader{api: api, c: c, r: r, loaded: map[string]map[string]interface{}{}}
+}
+
+// load returns the objects of the resource with the given ids in the order of the ids, ids that do
+// not exist are skipped
+func (l *batchLoader) load(res *resource, ids []string) ([]interface{}, error) {
+	loaded := l.loaded[res.name]
+	if loaded == nil {
+		loaded = map[string]interface{}{}
+		l.loaded[res.name] = loaded
+	}
+
+	missing := []string{}
+	requested := map[string]bool{}
+	for _, id := range ids {
+		if _, ok := loaded[id]; !ok && !requested[id] {
+			requested[id] = true
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		objects, err := l.find(res, missing)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range missing {
+			loaded[id] = nil
+		}
+
+		for _, object := range objects {
+			identifier, ok := jsonapi.Adapt(object).(jsonapi.MarshalIdentifier)
+			if !ok {
+				return nil, fmt.Errorf("Resource %s returned an object without id", res.name)
+			}
+			loaded[identifier.GetID()] = object
+		}
+	}
+
+	objects := []interface{}{}
+	added := map[string]bool{}
+	for _, id := range ids {
+		if object := loaded[id]; object != nil && !added[id] {
+			objects = append(objects, object)
+			added[id] = true
+		}
+	}
+
+	return objects, nil
+}
+
+// find looks up ids that were not loaded yet
+func (l *batchLoader) find(res *resource, ids []string) ([]interface{}, error) {
+	if source, ok := res.source.(FindMany); ok {
+		response, err := source.FindMany(ids, buildRequest(l.c, l.r))
+		if err != nil {
+			return nil, err
+		}
+
+		if response == nil {
+			return nil, nil
+		}
+
+		return toObjects(response.Result()), nil
+	}
+
+	source, ok := res.source.(ResourceGetter)
+	if !ok {
+		return nil, fmt.Errorf("Resource %s does not implement the ResourceGetter interface", res.name)
+	}
+
+	objects := []interface{}{}
+	for _, id := range ids {
+		response, err := source.FindOne(id, buildRequest(l.c, l.r))
+		if err != nil {
+			if httpError, ok := err.(HTTPError); ok && httpError.status == http.StatusNotFound {
+				continue
+			}
+
+			return nil, err
+		}
+
+		if response != nil && response.Result() != nil {
+			objects = append(objects, response.Result())
+		}
+	}
+
+	return objects, nil
+}
+
+// toObjects returns the elements of a slice or the result itself
+func toObjects(result interface{}) []interface{} {
+	if result == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(result)
+	if value.Kind() != reflect.Slice {
+		return []interface{}{result}
+	}
+
+	objects := make([]interface{}, value.Len())
+	for i := range objects {
+		objects[i] = value.Index(i).Interface()
+	}
+
+	return objects
+}
+
+// getResource returns the resource with the given name or nil
+func (api *API) getResource(name string) *resource {
+	for i := range api.resources {
+		if api.resources[i].name == name {
+			return &api.resources[i]
+		}
+	}
+
+	return nil
+}
+
+// includeTree contains the relationship paths of the include query parameter
+type includeTree map[string]includeTree
+
+func parseIncludeTree(r *http.Request) includeTree {
+	tree := includeTree{}
+	for _, path := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if path == "" {
+			continue
+		}
+
+		node := tree
+		for _, name := range strings.Split(path, ".") {
+			if node[name] == nil {
+				node[name] = includeTree{}
+			}
+			node = node[name]
+		}
+	}
+
+	return tree
+}
+
+// includeResources adds the resources of the include query parameter to the included resources of
+// the document. The related resources of all records are loaded together, resources that are already
+// part of the document are not loaded again.
+func (l *batchLoader) includeResources(document *jsonapi.Document, info information) error {
+	tree := parseIncludeTree(l.r)
+	if len(tree) == 0 || document.Data == nil {
+		return nil
+	}
+
+	records := []*jsonapi.Data{}
+	if document.Data.DataObject != nil {
+		records = append(records, document.Data.DataObject)
+	}
+	for i := range document.Data.DataArray {
+		records = append(records, &document.Data.DataArray[i])
+	}
+
+	known := map[string]*jsonapi.Data{}
+	for _, record := range records {
+		known[record.Type+"/"+record.ID] = record
+	}
+	for i := range document.Included {
+		known[document.Included[i].Type+"/"+document.Included[i].ID] = &document.Included[i]
+	}
+
+	included := []jsonapi.Data{}
+	if err := l.include(records, tree, "", known, &included, info); err != nil {
+		return err
+	}
+
+	document.Included = append(document.Included, included...)
+	return nil
+}
+
+// include resolves the relationships of the tree for records and then the nested relationships for the
+// related records, one level of the tree after the other
+func (l *batchLoader) include(records []*jsonapi.Data, tree includeTree, prefix string, known map[string]*jsonapi.Data, included *[]jsonapi.Data, info information) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	names := []string{}
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	types := []string{}
+	idsByType := map[string][]string{}
+	seen := map[string]bool{}
+	identifiersByName := map[string][]jsonapi.RelationshipData{}
+	for _, name := range names {
+		found := false
+		for _, record := range records {
+			relationship, ok := record.Relationships[name]
+			if !ok {
+				continue
+			}
+			found = true
+
+			for _, identifier := range relationshipIdentifiers(relationship) {
+				identifiersByName[name] = append(identifiersByName[name], identifier)
+				if _, ok := idsByType[identifier.Type]; !ok {
+					types = append(types, identifier.Type)
+				}
+
+				if key := identifier.Type + "/" + identifier.ID; !seen[key] {
+					seen[key] = true
+					idsByType[identifier.Type] = append(idsByType[identifier.Type], identifier.ID)
+				}
+			}
+		}
+
+		if !found {
+			return NewHTTPError(nil, fmt.Sprintf("invalid include path %s%s", prefix, name), http.StatusBadRequest)
+		}
+	}
+
+	for _, resourceType := range types {
+		res := l.api.getResource(resourceType)
+		if res == nil {
+			continue
+		}
+
+		ids := []string{}
+		for _, id := range idsByType[resourceType] {
+			if _, ok := known[resourceType+"/"+id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+
+		if len(ids) == 0 {
+			continue
+		}
+
+		objects, err := l.load(res, ids)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range objects {
+			document, err := jsonapi.MarshalToStructWithOptions(object, info, l.api.marshalOptions(l.r))
+			if err != nil {
+				return err
+			}
+
+			*included = append(*included, *document.Data.DataObject)
+		}
+	}
+
+	// the included slice is not modified anymore on this level
+	for i := range *included {
+		data := &(*included)[i]
+		if _, ok := known[data.Type+"/"+data.ID]; !ok {
+			known[data.Type+"/"+data.ID] = data
+		}
+	}
+
+	for _, name := range names {
+		if len(tree[name]) == 0 {
+			continue
+		}
+
+		related := []*jsonapi.Data{}
+		for _, identifier := range identifiersByName[name] {
+			if data, ok := known[identifier.Type+"/"+identifier.ID]; ok {
+				related = append(related, data)
+			}
+		}
+
+		if err := l.include(related, tree[name], prefix+name+".", known, included, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// relationshipIdentifiers returns the resource identifiers of the linkage of a relationship
+func relationshipIdentifiers(relationship jsonapi.Relationship) []jsonapi.RelationshipData {
+	if relationship.Data == nil {
+		return nil
+	}
+
+	if relationship.Data.DataObject != nil {
+		return []jsonapi.RelationshipData{*relationship.Data.DataObject}
+	}
+
+	return relationship.Data.DataArray
+}
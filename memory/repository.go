@@ -0,0 +1,495 @@
+// Package memory provides a concurrency-safe in-memory resource for api2go. It can
+// be used for prototypes and tests and as a reference implementation of the
+// resource interfaces.
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/manyminds/api2go"
+	"github.com/manyminds/api2go/internal/resourceutil"
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// Repository stores the objects of one resource type in memory. It implements
+// the CRUD, FindAll, PaginatedFindAll, FindMany, RelationshipAdder and
+// RelationshipRemover interfaces of api2go, so it can be passed to AddResource
+// together with the same prototype.
+//
+// The objects must implement jsonapi.MarshalIdentifier and
+// jsonapi.UnmarshalIdentifier or use jsonapi struct tags. They are copied when
+// they are stored and returned, so that changes of the caller do not affect the
+// repository. The copies are shallow, slices and maps are shared.
+type Repository struct {
+	mutex       sync.RWMutex
+	prototype   resourceutil.Prototype
+	objects     map[string]reflect.Value
+	order       []string
+	nextID      int
+	idGenerator func() string
+}
+
+// New creates a repository for objects of the type of prototype, which should
+// be an empty struct like `User{}` or a pointer like `&User{}`.
+func New(prototype interface{}) *Repository {
+	resourceType := reflect.TypeOf(prototype)
+	if resourceType == nil || (resourceType.Kind() != reflect.Struct &&
+		(resourceType.Kind() != reflect.Ptr || resourceType.Elem().Kind() != reflect.Struct)) {
+		panic("pass an empty resource struct or a struct pointer to memory.New!")
+	}
+
+	return &Repository{
+		prototype: resourceutil.Prototype{Type: resourceType},
+		objects:   map[string]reflect.Value{},
+		nextID:    1,
+	}
+}
+
+// SetIDGenerator sets the function that generates the ids of created objects
+// without id. By default, ascending numbers are used.
+func (r *Repository) SetIDGenerator(generator func() string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.idGenerator = generator
+}
+
+// FindAll returns all objects that match the filter query parameters, e.g.
+// `filter[name]=Marvin,Arthur`, ordered by the sort query parameter, e.g.
+// `sort=-age,name`, or in the order they were created.
+func (r *Repository) FindAll(req api2go.Request) (api2go.Responder, error) {
+	objects, err := r.query(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api2go.Response{Res: r.prototype.ToSlice(objects), Code: http.StatusOK}, nil
+}
+
+// PaginatedFindAll returns a page of the objects of FindAll, using either the
+// page[number] and page[size] or the page[offset] and page[limit] parameters.
+func (r *Repository) PaginatedFindAll(req api2go.Request) (uint, api2go.Responder, error) {
+	objects, err := r.query(req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	offset, limit, err := resourceutil.GetPage(req.Pagination)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	count := uint(len(objects))
+	if offset > len(objects) {
+		offset = len(objects)
+	}
+	if limit >= 0 && offset+limit < len(objects) {
+		objects = objects[offset : offset+limit]
+	} else {
+		objects = objects[offset:]
+	}
+
+	return count, &api2go.Response{Res: r.prototype.ToSlice(objects), Code: http.StatusOK}, nil
+}
+
+// FindOne returns the object with the given id or a 404 error.
+func (r *Repository) FindOne(ID string, req api2go.Request) (api2go.Responder, error) {
+	r.mutex.RLock()
+	object, ok := r.objects[ID]
+	r.mutex.RUnlock()
+	if !ok {
+		return nil, resourceutil.NotFound(ID)
+	}
+
+	return &api2go.Response{Res: r.prototype.Export(object), Code: http.StatusOK}, nil
+}
+
+// FindMany returns the objects with the given ids in the order of the ids,
+// unknown ids are skipped.
+func (r *Repository) FindMany(IDs []string, req api2go.Request) (api2go.Responder, error) {
+	r.mutex.RLock()
+	objects := []reflect.Value{}
+	for _, ID := range IDs {
+		if object, ok := r.objects[ID]; ok {
+			objects = append(objects, object)
+		}
+	}
+	r.mutex.RUnlock()
+
+	return &api2go.Response{Res: r.prototype.ToSlice(objects), Code: http.StatusOK}, nil
+}
+
+// Create stores a new object. If it has no id, a new one is generated. A 409
+// error is returned if an object with the same id exists already.
+func (r *Repository) Create(obj interface{}, req api2go.Request) (api2go.Responder, error) {
+	object, err := r.prototype.Copy(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ID := resourceutil.GetID(object)
+	if ID == "" {
+		ID = r.generateID()
+		if err := resourceutil.SetID(object, ID); err != nil {
+			return nil, api2go.NewHTTPError(err, err.Error(), http.StatusBadRequest)
+		}
+	}
+
+	if _, ok := r.objects[ID]; ok {
+		message := fmt.Sprintf("object with id %s exists already", ID)
+		return nil, api2go.NewHTTPError(nil, message, http.StatusConflict)
+	}
+
+	r.objects[ID] = object
+	r.order = append(r.order, ID)
+
+	return &api2go.Response{Res: r.prototype.Export(object), Code: http.StatusCreated}, nil
+}
+
+// Update replaces the stored object with the same id or returns a 404 error.
+func (r *Repository) Update(obj interface{}, req api2go.Request) (api2go.Responder, error) {
+	object, err := r.prototype.Copy(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ID := resourceutil.GetID(object)
+	if _, ok := r.objects[ID]; !ok {
+		return nil, resourceutil.NotFound(ID)
+	}
+	r.objects[ID] = object
+
+	return &api2go.Response{Res: r.prototype.Export(object), Code: http.StatusOK}, nil
+}
+
+// Delete removes the object with the given id or returns a 404 error.
+func (r *Repository) Delete(ID string, req api2go.Request) (api2go.Responder, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.objects[ID]; !ok {
+		return nil, resourceutil.NotFound(ID)
+	}
+
+	delete(r.objects, ID)
+	for i, candidate := range r.order {
+		if candidate == ID {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+// AddToRelationship adds ids to a to-many relationship of a stored object with
+// its AddToManyIDsWithType or AddToManyIDs method in a single step.
+func (r *Repository) AddToRelationship(ID, name string, IDs []jsonapi.ReferenceID, req api2go.Request) (api2go.Responder, error) {
+	return r.editToMany(ID, name, IDs, true)
+}
+
+// RemoveFromRelationship removes ids from a to-many relationship of a stored
+// object with its DeleteToManyIDsWithType or DeleteToManyIDs method in a single
+// step.
+func (r *Repository) RemoveFromRelationship(ID, name string, IDs []jsonapi.ReferenceID, req api2go.Request) (api2go.Responder, error) {
+	return r.editToMany(ID, name, IDs, false)
+}
+
+func (r *Repository) editToMany(ID, name string, IDs []jsonapi.ReferenceID, add bool) (api2go.Responder, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, ok := r.objects[ID]
+	if !ok {
+		return nil, resourceutil.NotFound(ID)
+	}
+
+	// edit a copy, so that the stored object stays unchanged if the edit fails
+	object := reflect.New(stored.Type().Elem())
+	object.Elem().Set(stored.Elem())
+
+	if err := editToManyIDs(jsonapi.Adapt(object.Interface()), name, IDs, add); err != nil {
+		return nil, err
+	}
+	r.objects[ID] = object
+
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+// editToManyIDs adds or deletes ids of a to-many relationship of target, the
+// types are only passed on if target implements EditToManyRelationsWithType
+func editToManyIDs(target interface{}, name string, IDs []jsonapi.ReferenceID, add bool) error {
+	var err error
+	switch editor := target.(type) {
+	case jsonapi.EditToManyRelationsWithType:
+		if add {
+			err = editor.AddToManyIDsWithType(name, IDs)
+		} else {
+			err = editor.DeleteToManyIDsWithType(name, IDs)
+		}
+	case jsonapi.EditToManyRelations:
+		plainIDs := make([]string, 0, len(IDs))
+		for _, ID := range IDs {
+			plainIDs = append(plainIDs, ID.ID)
+		}
+
+		if add {
+			err = editor.AddToManyIDs(name, plainIDs)
+		} else {
+			err = editor.DeleteToManyIDs(name, plainIDs)
+		}
+	default:
+		return fmt.Errorf("%T does not implement jsonapi.EditToManyRelations", target)
+	}
+
+	if err != nil {
+		return api2go.NewHTTPError(err, err.Error(), http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// query returns the filtered and sorted objects
+func (r *Repository) query(req api2go.Request) ([]reflect.Value, error) {
+	r.mutex.RLock()
+	objects := make([]reflect.Value, 0, len(r.order))
+	for _, ID := range r.order {
+		objects = append(objects, r.objects[ID])
+	}
+	r.mutex.RUnlock()
+
+	filters := getFilters(req.QueryParams)
+	sorting := req.QueryParams["sort"]
+	if len(filters) == 0 && len(sorting) == 0 {
+		return objects, nil
+	}
+
+	records := make([]record, 0, len(objects))
+	for _, object := range objects {
+		record, err := newRecord(object)
+		if err != nil {
+			return nil, err
+		}
+
+		matches, err := record.matches(filters)
+		if err != nil {
+			return nil, err
+		}
+
+		if matches {
+			records = append(records, record)
+		}
+	}
+
+	if err := sortRecords(records, sorting); err != nil {
+		return nil, err
+	}
+
+	objects = objects[:0]
+	for _, record := range records {
+		objects = append(objects, record.object)
+	}
+
+	return objects, nil
+}
+
+func (r *Repository) generateID() string {
+	if r.idGenerator != nil {
+		return r.idGenerator()
+	}
+
+	for {
+		ID := strconv.Itoa(r.nextID)
+		r.nextID++
+		if _, ok := r.objects[ID]; !ok {
+			return ID
+		}
+	}
+}
+
+// getFilters returns the values of all filter[name] query parameters by name
+func getFilters(queryParams map[string][]string) map[string][]string {
+	filters := map[string][]string{}
+	for key, values := range queryParams {
+		if strings.HasPrefix(key, "filter[") && strings.HasSuffix(key, "]") {
+			filters[key[len("filter["):len(key)-1]] = values
+		}
+	}
+
+	return filters
+}
+
+// record is an object together with its id and decoded attributes
+type record struct {
+	object     reflect.Value
+	ID         string
+	attributes map[string]interface{}
+}
+
+func newRecord(object reflect.Value) (record, error) {
+	document, err := jsonapi.MarshalToStruct(object.Interface(), nil)
+	if err != nil {
+		return record{}, err
+	}
+
+	data := document.Data.DataObject
+	attributes := map[string]interface{}{}
+	if len(data.Attributes) > 0 {
+		if err := json.Unmarshal(data.Attributes, &attributes); err != nil {
+			return record{}, err
+		}
+	}
+
+	return record{object: object, ID: data.ID, attributes: attributes}, nil
+}
+
+// value returns the id or an attribute
+func (r record) value(name string) (interface{}, bool) {
+	if name == "id" {
+		return r.ID, true
+	}
+
+	value, ok := r.attributes[name]
+	return value, ok
+}
+
+// matches checks if the record has one of the values of every filter
+func (r record) matches(filters map[string][]string) (bool, error) {
+	for name, values := range filters {
+		value, ok := r.value(name)
+		if !ok {
+			return false, api2go.NewHTTPError(nil, fmt.Sprintf("invalid filter %s", name), http.StatusBadRequest)
+		}
+
+		if !containsString(values, format(value)) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func sortRecords(records []record, sorting []string) error {
+	for _, field := range sorting {
+		name := strings.TrimPrefix(field, "-")
+		for _, record := range records {
+			if _, ok := record.value(name); !ok {
+				return api2go.NewHTTPError(nil, fmt.Sprintf("invalid sort field %s", name), http.StatusBadRequest)
+			}
+		}
+	}
+
+	sort.Stable(recordsByFields{records: records, fields: sorting})
+	return nil
+}
+
+// recordsByFields sorts records by multiple fields, a leading minus sorts in
+// descending order
+type recordsByFields struct {
+	records []record
+	fields  []string
+}
+
+func (r recordsByFields) Len() int {
+	return len(r.records)
+}
+
+func (r recordsByFields) Swap(i, j int) {
+	r.records[i], r.records[j] = r.records[j], r.records[i]
+}
+
+func (r recordsByFields) Less(i, j int) bool {
+	for _, field := range r.fields {
+		name := strings.TrimPrefix(field, "-")
+		left, _ := r.records[i].value(name)
+		right, _ := r.records[j].value(name)
+
+		result := compare(left, right)
+		if strings.HasPrefix(field, "-") {
+			result = -result
+		}
+
+		if result != 0 {
+			return result < 0
+		}
+	}
+
+	return false
+}
+
+// compare compares json values, null is lower than any other value and values
+// of different types are compared by their formatted values
+func compare(left, right interface{}) int {
+	switch {
+	case left == nil && right == nil:
+		return 0
+	case left == nil:
+		return -1
+	case right == nil:
+		return 1
+	}
+
+	switch leftValue := left.(type) {
+	case float64:
+		if rightValue, ok := right.(float64); ok {
+			switch {
+			case leftValue < rightValue:
+				return -1
+			case leftValue > rightValue:
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if rightValue, ok := right.(bool); ok {
+			switch {
+			case leftValue == rightValue:
+				return 0
+			case !leftValue:
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return strings.Compare(format(left), format(right))
+}
+
+// format returns the string representation of a json value
+func format(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(typed)
+	default:
+		encoded, _ := json.Marshal(typed)
+		return string(encoded)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,310 @@
+package memory
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/manyminds/api2go"
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+)
+
+type Robot struct {
+	ID      string   `jsonapi:"primary,robots"`
+	Name    string   `jsonapi:"attr,name"`
+	Age     int      `jsonapi:"attr,age"`
+	Active  bool     `jsonapi:"attr,active"`
+	PartIDs []string `jsonapi:"relation,parts,parts"`
+}
+
+// haveStatus matches api2go.HTTPErrors with the given status and message
+func haveStatus(status int, message string) types.GomegaMatcher {
+	return MatchError(HavePrefix(fmt.Sprintf("http error (%d) %s and", status, message)))
+}
+
+// identifiers returns the identifiers of parts with the given ids
+func identifiers(ids ...string) []jsonapi.ReferenceID {
+	result := []jsonapi.ReferenceID{}
+	for _, id := range ids {
+		result = append(result, jsonapi.ReferenceID{ID: id, Type: "parts", Relationship: jsonapi.ToManyRelationship})
+	}
+
+	return result
+}
+
+var _ = Describe("Repository", func() {
+	var (
+		repository *Repository
+		request    api2go.Request
+	)
+
+	names := func(response api2go.Responder) []string {
+		result := []string{}
+		for _, robot := range response.Result().([]Robot) {
+			result = append(result, robot.Name)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		repository = New(Robot{})
+		request = api2go.Request{QueryParams: map[string][]string{}, Pagination: map[string]string{}}
+
+		for _, robot := range []Robot{
+			{Name: "Marvin", Age: 42, Active: false},
+			{Name: "Bender", Age: 4, Active: true},
+			{Name: "Robbie", Age: 42, Active: true},
+		} {
+			_, err := repository.Create(robot, request)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("panics for invalid prototypes", func() {
+		Expect(func() { New("robot") }).To(Panic())
+		Expect(func() { New(nil) }).To(Panic())
+	})
+
+	Context("creating", func() {
+		It("generates ids", func() {
+			response, err := repository.Create(Robot{Name: "Wall-E"}, request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode()).To(Equal(http.StatusCreated))
+			Expect(response.Result()).To(Equal(Robot{ID: "4", Name: "Wall-E"}))
+		})
+
+		It("skips ids that are in use", func() {
+			_, err := repository.Create(Robot{ID: "5", Name: "Wall-E"}, request)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = repository.Create(Robot{Name: "Eve"}, request)
+			Expect(err).ToNot(HaveOccurred())
+			response, err := repository.Create(Robot{Name: "Hal"}, request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Result().(Robot).ID).To(Equal("6"))
+		})
+
+		It("uses a custom id generator", func() {
+			repository.SetIDGenerator(func() string { return "custom" })
+			response, err := repository.Create(Robot{Name: "Wall-E"}, request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Result().(Robot).ID).To(Equal("custom"))
+		})
+
+		It("rejects existing ids", func() {
+			_, err := repository.Create(Robot{ID: "1", Name: "Wall-E"}, request)
+			Expect(err).To(haveStatus(http.StatusConflict, "object with id 1 exists already"))
+		})
+
+		It("rejects objects of other types", func() {
+			_, err := repository.Create(&Robot{Name: "Wall-E"}, request)
+			Expect(err).To(haveStatus(http.StatusInternalServerError, "expected an object of type memory.Robot but got *memory.Robot"))
+			_, err = repository.Create(nil, request)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("stores copies", func() {
+			robot := &Robot{Name: "Wall-E"}
+			pointers := New(&Robot{})
+			_, err := pointers.Create(robot, request)
+			Expect(err).ToNot(HaveOccurred())
+			robot.Name = "Eve"
+
+			response, err := pointers.FindOne("1", request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Result()).To(Equal(&Robot{ID: "1", Name: "Wall-E"}))
+		})
+	})
+
+	Context("finding", func() {
+		It("finds one object", func() {
+			response, err := repository.FindOne("2", request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode()).To(Equal(http.StatusOK))
+			Expect(response.Result()).To(Equal(Robot{ID: "2", Name: "Bender", Age: 4, Active: true}))
+		})
+
+		It("returns 404 for unknown ids", func() {
+			_, err := repository.FindOne("23", request)
+			Expect(err).To(haveStatus(http.StatusNotFound, "object with id 23 not found"))
+		})
+
+		It("finds all objects in creation order", func() {
+			response, err := repository.FindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(response)).To(Equal([]string{"Marvin", "Bender", "Robbie"}))
+		})
+
+		It("finds many objects in the order of the ids", func() {
+			response, err := repository.FindMany([]string{"3", "23", "1"}, request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(response)).To(Equal([]string{"Robbie", "Marvin"}))
+		})
+
+		It("sorts by multiple fields", func() {
+			request.QueryParams["sort"] = []string{"-age", "name"}
+			response, err := repository.FindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(response)).To(Equal([]string{"Marvin", "Robbie", "Bender"}))
+		})
+
+		It("sorts by id and booleans", func() {
+			request.QueryParams["sort"] = []string{"active", "-id"}
+			response, err := repository.FindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(response)).To(Equal([]string{"Marvin", "Robbie", "Bender"}))
+		})
+
+		It("filters by attributes and ids", func() {
+			request.QueryParams["filter[age]"] = []string{"42", "4"}
+			request.QueryParams["filter[active]"] = []string{"true"}
+			response, err := repository.FindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(response)).To(Equal([]string{"Bender", "Robbie"}))
+
+			request.QueryParams["filter[id]"] = []string{"3"}
+			response, err = repository.FindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(response)).To(Equal([]string{"Robbie"}))
+		})
+
+		It("rejects unknown fields", func() {
+			request.QueryParams["sort"] = []string{"size"}
+			_, err := repository.FindAll(request)
+			Expect(err).To(haveStatus(http.StatusBadRequest, "invalid sort field size"))
+
+			request.QueryParams = map[string][]string{"filter[size]": {"1"}}
+			_, err = repository.FindAll(request)
+			Expect(err).To(haveStatus(http.StatusBadRequest, "invalid filter size"))
+		})
+
+		It("paginates with number and size", func() {
+			request.QueryParams["sort"] = []string{"name"}
+			request.Pagination = map[string]string{"number": "2", "size": "2"}
+			count, response, err := repository.PaginatedFindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(uint(3)))
+			Expect(names(response)).To(Equal([]string{"Robbie"}))
+		})
+
+		It("paginates with offset and limit after filtering", func() {
+			request.QueryParams["filter[age]"] = []string{"42"}
+			request.Pagination = map[string]string{"offset": "1", "limit": "5"}
+			count, response, err := repository.PaginatedFindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(uint(2)))
+			Expect(names(response)).To(Equal([]string{"Robbie"}))
+
+			request.Pagination = map[string]string{"offset": "5", "limit": "5"}
+			_, response, err = repository.PaginatedFindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(response)).To(BeEmpty())
+		})
+
+		It("rejects invalid pagination", func() {
+			request.Pagination = map[string]string{"number": "1", "size": "-1"}
+			_, _, err := repository.PaginatedFindAll(request)
+			Expect(err).To(haveStatus(http.StatusBadRequest, `invalid value "-1" for page[size]`))
+		})
+	})
+
+	Context("updating and deleting", func() {
+		It("updates objects", func() {
+			response, err := repository.Update(Robot{ID: "1", Name: "Marvin", Age: 43}, request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode()).To(Equal(http.StatusOK))
+
+			response, err = repository.FindOne("1", request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Result().(Robot).Age).To(Equal(43))
+		})
+
+		It("returns 404 for updates of unknown objects", func() {
+			_, err := repository.Update(Robot{ID: "23"}, request)
+			Expect(err).To(haveStatus(http.StatusNotFound, "object with id 23 not found"))
+		})
+
+		It("deletes objects", func() {
+			response, err := repository.Delete("2", request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode()).To(Equal(http.StatusNoContent))
+
+			response, err = repository.FindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names(response)).To(Equal([]string{"Marvin", "Robbie"}))
+
+			_, err = repository.Delete("2", request)
+			Expect(err).To(haveStatus(http.StatusNotFound, "object with id 2 not found"))
+		})
+
+		It("edits to-many relationships", func() {
+			_, err := repository.AddToRelationship("1", "parts", identifiers("1", "2"), request)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = repository.RemoveFromRelationship("1", "parts", identifiers("1"), request)
+			Expect(err).ToNot(HaveOccurred())
+
+			response, err := repository.FindOne("1", request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Result().(Robot).PartIDs).To(Equal([]string{"2"}))
+
+			_, err = repository.AddToRelationship("1", "arms", identifiers("1"), request)
+			Expect(err).To(haveStatus(http.StatusBadRequest, "There is no to-many relationship with the name arms"))
+			_, err = repository.AddToRelationship("23", "parts", identifiers("1"), request)
+			Expect(err).To(haveStatus(http.StatusNotFound, "object with id 23 not found"))
+		})
+	})
+
+	It("is safe for concurrent use", func() {
+		var wait sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wait.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wait.Done()
+
+				response, err := repository.Create(Robot{Name: strconv.Itoa(i)}, request)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = repository.AddToRelationship(response.Result().(Robot).ID, "parts", identifiers("1"), request)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = repository.FindAll(request)
+				Expect(err).ToNot(HaveOccurred())
+			}(i)
+		}
+		wait.Wait()
+
+		response, err := repository.FindAll(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Result()).To(HaveLen(23))
+	})
+
+	It("works as api2go resource", func() {
+		api := api2go.NewAPI("v1")
+		api.AddResource(Robot{}, repository)
+
+		rec := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/v1/robots", strings.NewReader(`{"data": {"type": "robots", "attributes": {"name": "Wall-E", "age": 700}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(rec.Header().Get("Location")).To(Equal("/v1/robots/4"))
+
+		rec = httptest.NewRecorder()
+		req, err = http.NewRequest("POST", "/v1/robots/4/relationships/parts", strings.NewReader(`{"data": [{"type": "parts", "id": "7"}]}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+		rec = httptest.NewRecorder()
+		req, err = http.NewRequest("GET", "/v1/robots?sort=-age&page[size]=1&filter[active]=false", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"name":"Wall-E"`))
+		Expect(rec.Body.String()).To(ContainSubstring(`"data":[{"type":"parts","id":"7"}]`))
+	})
+})
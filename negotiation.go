@@ -0,0 +1,221 @@
+package api2go
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/manyminds/api2go/jsonapi"
+	"github.com/manyminds/api2go/routing"
+)
+
+const (
+	codeUnsupportedMediaType = "API2GO_UNSUPPORTED_MEDIA_TYPE"
+	codeNotAcceptable        = "API2GO_NOT_ACCEPTABLE"
+)
+
+type negotiationContextKey struct{}
+
+// mediaTypeParams contains the extensions and profiles that were negotiated for a request
+type mediaTypeParams struct {
+	extensions []string
+	profiles   []string
+}
+
+// handle registers a route whose handler is only called if the content negotiation
+// with the client succeeded, otherwise the matching error is returned.
+func (api *API) handle(method, route string, handler routing.HandlerFunc) {
+	api.router.Handle(method, route, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		negotiated, err := api.negotiate(r)
+		if err != nil {
+			api.handleError(err, w, r)
+			return
+		}
+
+		if len(api.extensions) > 0 || len(api.profiles) > 0 {
+			w.Header().Add("Vary", "Accept")
+		}
+
+		ctx := context.WithValue(r.Context(), negotiationContextKey{}, negotiated)
+		handler(w, r.WithContext(ctx), params)
+	})
+}
+
+// negotiate checks the Content-Type and Accept headers of a request according to
+// http://jsonapi.org/format/#content-negotiation-servers
+func (api *API) negotiate(r *http.Request) (mediaTypeParams, error) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if err := api.checkContentType(r.Header.Get("Content-Type")); err != nil {
+			return mediaTypeParams{}, err
+		}
+	}
+
+	return api.checkAccept(r.Header.Get("Accept"))
+}
+
+func (api *API) checkContentType(header string) error {
+	// requests without a content type are accepted to stay compatible with lax clients
+	if header == "" {
+		return nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return newNegotiationError(http.StatusUnsupportedMediaType, codeUnsupportedMediaType, fmt.Sprintf("Invalid Content-Type header %s", header))
+	}
+
+	if mediaType != defaultContentTypHeader {
+		if customType, _, err := mime.ParseMediaType(api.ContentType); err == nil && customType == mediaType {
+			return nil
+		}
+
+		return newNegotiationError(http.StatusUnsupportedMediaType, codeUnsupportedMediaType, fmt.Sprintf("Media type %s is not supported, use %s", mediaType, defaultContentTypHeader))
+	}
+
+	for name := range params {
+		if name != "ext" && name != "profile" {
+			return newNegotiationError(http.StatusUnsupportedMediaType, codeUnsupportedMediaType, fmt.Sprintf("Media type parameter %s is not supported", name))
+		}
+	}
+
+	if unsupported := api.unsupportedExtensions(params["ext"]); len(unsupported) > 0 {
+		return newNegotiationError(http.StatusUnsupportedMediaType, codeUnsupportedMediaType, fmt.Sprintf("Extension %s is not supported", unsupported[0]))
+	}
+
+	return nil
+}
+
+func (api *API) checkAccept(header string) (mediaTypeParams, error) {
+	var (
+		found      bool
+		candidates []map[string]string
+	)
+
+	for _, accepted := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil || mediaType != defaultContentTypHeader {
+			continue
+		}
+		found = true
+
+		// the quality value is not a media type parameter
+		delete(params, "q")
+
+		onlyKnownParams := true
+		for name := range params {
+			if name != "ext" && name != "profile" {
+				onlyKnownParams = false
+				break
+			}
+		}
+
+		if onlyKnownParams {
+			candidates = append(candidates, params)
+		}
+	}
+
+	// the client accepts other media types, there is nothing to negotiate
+	if !found {
+		return mediaTypeParams{}, nil
+	}
+
+	if len(candidates) == 0 {
+		return mediaTypeParams{}, newNegotiationError(http.StatusNotAcceptable, codeNotAcceptable, "All JSON API media types in the Accept header contain unsupported media type parameters")
+	}
+
+	for _, params := range candidates {
+		if len(api.unsupportedExtensions(params["ext"])) > 0 {
+			continue
+		}
+
+		result := mediaTypeParams{extensions: splitMediaTypeParam(params["ext"])}
+		for _, profile := range splitMediaTypeParam(params["profile"]) {
+			// profiles that are not known must be ignored
+			if containsString(api.profiles, profile) {
+				result.profiles = append(result.profiles, profile)
+			}
+		}
+
+		return result, nil
+	}
+
+	return mediaTypeParams{}, newNegotiationError(http.StatusNotAcceptable, codeNotAcceptable, "All JSON API media types in the Accept header contain unsupported extensions")
+}
+
+// unsupportedExtensions returns all extensions of an ext media type parameter that were not registered
+func (api *API) unsupportedExtensions(param string) []string {
+	var unsupported []string
+	for _, extension := range splitMediaTypeParam(param) {
+		if !containsString(api.extensions, extension) {
+			unsupported = append(unsupported, extension)
+		}
+	}
+
+	return unsupported
+}
+
+// contentType returns the Content-Type header for a response, including all applied extensions
+// and profiles
+func (api *API) contentType(r *http.Request) string {
+	negotiated := getNegotiatedParams(r)
+	contentType := api.ContentType
+	if len(negotiated.extensions) > 0 {
+		contentType += fmt.Sprintf(`; ext="%s"`, strings.Join(negotiated.extensions, " "))
+	}
+
+	if len(negotiated.profiles) > 0 {
+		contentType += fmt.Sprintf(`; profile="%s"`, strings.Join(negotiated.profiles, " "))
+	}
+
+	return contentType
+}
+
+// profileLinks returns the links for the top-level links object of all applied profiles
+func profileLinks(r *http.Request) []jsonapi.Link {
+	var links []jsonapi.Link
+	for _, profile := range getNegotiatedParams(r).profiles {
+		links = append(links, jsonapi.Link{Href: profile})
+	}
+
+	return links
+}
+
+func getNegotiatedParams(r *http.Request) mediaTypeParams {
+	if r == nil {
+		return mediaTypeParams{}
+	}
+
+	negotiated, _ := r.Context().Value(negotiationContextKey{}).(mediaTypeParams)
+	return negotiated
+}
+
+// splitMediaTypeParam splits the space separated list of URIs of the ext and profile parameters
+func splitMediaTypeParam(param string) []string {
+	return strings.Fields(param)
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, value := range haystack {
+		if value == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newNegotiationError(status int, code, title string) HTTPError {
+	httpError := NewHTTPError(nil, title, status)
+	httpError.Errors = append(httpError.Errors, Error{
+		Status: strconv.Itoa(status),
+		Code:   code,
+		Title:  title,
+	})
+
+	return httpError
+}
@@ -0,0 +1,247 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type extensionResource struct {
+	SomeResource
+	atomic     bool
+	timestamps bool
+}
+
+func (s *extensionResource) FindOne(ID string, req Request) (Responder, error) {
+	s.atomic = req.HasExtension("https://jsonapi.org/ext/atomic")
+	s.timestamps = req.HasProfile("https://example.com/profiles/timestamps")
+	return s.SomeResource.FindOne(ID, req)
+}
+
+func (s *extensionResource) FindAll(req Request) (Responder, error) {
+	return &Response{Res: []SomeData{{ID: "12345", Data: "A Brezzn"}}}, nil
+}
+
+func (s *extensionResource) PaginatedFindAll(req Request) (uint, Responder, error) {
+	return 2, &Response{Res: []SomeData{{ID: "12345", Data: "A Brezzn"}}}, nil
+}
+
+var _ = Describe("Content negotiation", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *extensionResource
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		source = &extensionResource{}
+		api.AddResource(SomeData{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	post := func(contentType string) {
+		reqBody := strings.NewReader(`{"data": {"attributes": {"data": "A Brezzn"}, "type": "someDatas"}}`)
+		req, err := http.NewRequest("POST", "/v1/someDatas", reqBody)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Content-Type", contentType)
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	get := func(accept string) {
+		req, err := http.NewRequest("GET", "/v1/someDatas/12345", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Accept", accept)
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	Context("Content-Type", func() {
+		It("accepts the JSON API media type", func() {
+			post("application/vnd.api+json")
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("accepts requests without Content-Type", func() {
+			post("")
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("rejects other media types with 415", func() {
+			post("text/plain")
+			Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{
+				"status": "415",
+				"code": "API2GO_UNSUPPORTED_MEDIA_TYPE",
+				"title": "Media type text/plain is not supported, use application/vnd.api+json"
+			}]}`))
+		})
+
+		It("accepts the custom content type of the API", func() {
+			api.ContentType = "application/json"
+			post("application/json; charset=utf-8")
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		})
+
+		It("rejects media type parameters other than ext and profile with 415", func() {
+			post("application/vnd.api+json; charset=utf-8")
+			Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+		})
+
+		It("rejects unsupported extensions with 415", func() {
+			post(`application/vnd.api+json; ext="https://example.com/ext/unknown"`)
+			Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+		})
+
+		It("accepts registered extensions", func() {
+			api.RegisterExtension("https://jsonapi.org/ext/atomic")
+			post(`application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("accepts unknown profiles", func() {
+			post(`application/vnd.api+json; profile="https://example.com/profiles/unknown"`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("does not check the Content-Type of GET requests", func() {
+			req, err := http.NewRequest("GET", "/v1/someDatas/12345", nil)
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", "text/plain")
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("Accept", func() {
+		It("accepts requests without JSON API media type", func() {
+			get("application/json, */*")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("Content-Type")).To(Equal("application/vnd.api+json"))
+		})
+
+		It("returns 406 if all JSON API media types have unsupported parameters", func() {
+			get("application/vnd.api+json; charset=utf-8, application/vnd.api+json; version=1")
+			Expect(rec.Code).To(Equal(http.StatusNotAcceptable))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{
+				"status": "406",
+				"code": "API2GO_NOT_ACCEPTABLE",
+				"title": "All JSON API media types in the Accept header contain unsupported media type parameters"
+			}]}`))
+		})
+
+		It("ignores JSON API media types with unsupported parameters if there is another one", func() {
+			get("application/vnd.api+json; charset=utf-8, application/vnd.api+json")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("ignores the quality value", func() {
+			get("application/vnd.api+json; q=0.9")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("returns 406 if all JSON API media types contain unsupported extensions", func() {
+			get(`application/vnd.api+json; ext="https://example.com/ext/unknown"`)
+			Expect(rec.Code).To(Equal(http.StatusNotAcceptable))
+		})
+
+		It("applies registered extensions", func() {
+			api.RegisterExtension("https://jsonapi.org/ext/atomic")
+			get(`application/vnd.api+json; ext="https://example.com/ext/unknown", application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"`)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(source.atomic).To(BeTrue())
+			Expect(rec.Header().Get("Content-Type")).To(Equal(`application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"`))
+			Expect(rec.Header().Get("Vary")).To(Equal("Accept"))
+		})
+
+		It("does not apply extensions that were not requested", func() {
+			api.RegisterExtension("https://jsonapi.org/ext/atomic")
+			get("application/vnd.api+json")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(source.atomic).To(BeFalse())
+			Expect(rec.Header().Get("Content-Type")).To(Equal("application/vnd.api+json"))
+		})
+
+		It("ignores unknown profiles", func() {
+			get(`application/vnd.api+json; profile="https://example.com/profiles/unknown"`)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+	})
+})
+
+var _ = Describe("Profiles", func() {
+	const (
+		timestamps = "https://example.com/profiles/timestamps"
+		cursors    = "https://example.com/profiles/cursors"
+	)
+
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *extensionResource
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.RegisterProfile(timestamps, cursors)
+		source = &extensionResource{}
+		api.AddResource(SomeData{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	get := func(url, accept string) {
+		req, err := http.NewRequest("GET", url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Accept", accept)
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("applies requested profiles", func() {
+		get("/v1/someDatas/12345", `application/vnd.api+json; profile="https://example.com/profiles/timestamps https://example.com/profiles/unknown"`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.timestamps).To(BeTrue())
+		Expect(rec.Header().Get("Content-Type")).To(Equal(`application/vnd.api+json; profile="https://example.com/profiles/timestamps"`))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"links": {
+				"profile": ["https://example.com/profiles/timestamps"]
+			},
+			"data": {
+				"type": "someDatas",
+				"id": "12345",
+				"attributes": {
+					"data": "A Brezzn",
+					"customerId": ""
+				}
+			}
+		}`))
+	})
+
+	It("does not apply profiles that were not requested", func() {
+		get("/v1/someDatas/12345", "application/vnd.api+json")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.timestamps).To(BeFalse())
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/vnd.api+json"))
+		Expect(rec.Body.String()).ToNot(ContainSubstring("profile"))
+	})
+
+	It("lists multiple profiles", func() {
+		get("/v1/someDatas", `application/vnd.api+json; profile="https://example.com/profiles/timestamps https://example.com/profiles/cursors"`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(`application/vnd.api+json; profile="https://example.com/profiles/timestamps https://example.com/profiles/cursors"`))
+		Expect(rec.Header().Get("Vary")).To(Equal("Accept"))
+		Expect(rec.Body.String()).To(ContainSubstring(`"profile":["https://example.com/profiles/timestamps","https://example.com/profiles/cursors"]`))
+	})
+
+	It("adds profile links next to pagination links", func() {
+		get("/v1/someDatas?page[number]=1&page[size]=1", `application/vnd.api+json; profile="https://example.com/profiles/cursors"`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var document map[string]interface{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+		Expect(document["links"]).To(HaveKeyWithValue("profile", []interface{}{cursors}))
+		Expect(document["links"]).To(HaveKey("next"))
+	})
+})
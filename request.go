@@ -9,4 +9,33 @@ type Request struct {
 	Pagination   map[string]string
 	Header       http.Header
 	Context      APIContexter
+	// Relation is only set for requests to the related resource endpoint of a to-many
+	// relationship like /posts/1/comments
+	Relation   *Relation
+	extensions []string
+	profiles   []string
+}
+
+// Relation describes which relationship of which parent resource is requested on the
+// related resource endpoint
+type Relation struct {
+	// ParentType is the type of the parent resource, e.g. posts
+	ParentType string
+	// ParentID is the id of the parent resource
+	ParentID string
+	// Name is the name of the relationship, e.g. comments
+	Name string
+}
+
+// HasExtension returns true if the client applied the JSON API extension with the given URI.
+// Only extensions that were registered with RegisterExtension can be applied.
+func (r Request) HasExtension(uri string) bool {
+	return containsString(r.extensions, uri)
+}
+
+// HasProfile returns true if the client requested the JSON API profile with the given URI.
+// Only profiles that were registered with RegisterProfile can be requested, the applied
+// profiles are listed in the response.
+func (r Request) HasProfile(uri string) bool {
+	return containsString(r.profiles, uri)
 }
@@ -0,0 +1,779 @@
+// Package sqlresource implements the api2go resource interfaces for tables that
+// are accessed with database/sql. The tables are described with a Mapping, the
+// sql driver and the schema are left to the application.
+package sqlresource
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/manyminds/api2go"
+	"github.com/manyminds/api2go/internal/resourceutil"
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// Mapping describes the table of a resource. All names are inserted into the
+// statements verbatim, only values are passed as parameters.
+type Mapping struct {
+	// Table is the name of the table
+	Table string
+	// ID is the column of the resource id, "id" if empty. Objects that are
+	// created without id get the id of sql.Result.LastInsertId
+	ID string
+	// Columns maps the names of struct fields to their columns
+	Columns map[string]string
+	// Relations maps the names of to-many relationships to their join tables
+	Relations map[string]JoinTable
+	// Placeholder returns the placeholder of the n-th parameter of a statement,
+	// starting with 1. QuestionMark is used if it is nil
+	Placeholder func(n int) string
+	// MaxParameters limits the number of ids that are passed as parameters of
+	// one IN list, so that the parameter limit of the database is not exceeded.
+	// Longer lists are split into several queries. 500 is used if it is 0
+	MaxParameters int
+}
+
+// defaultMaxParameters is below the limit of 999 parameters of older sqlite versions
+const defaultMaxParameters = 500
+
+// JoinTable describes a table that stores the ids of a to-many relationship
+type JoinTable struct {
+	// Table is the name of the join table
+	Table string
+	// SourceColumn references the id of the resource
+	SourceColumn string
+	// TargetColumn references the id of the related resource
+	TargetColumn string
+}
+
+// QuestionMark returns the placeholder `?` used by drivers like sqlite and mysql
+func QuestionMark(n int) string {
+	return "?"
+}
+
+// Dollar returns the placeholders `$1`, `$2`, ... used by postgres drivers
+func Dollar(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// Resource implements the CRUD, FindAll, PaginatedFindAll, FindMany,
+// RelationshipAdder and RelationshipRemover interfaces of api2go for one table.
+// Use it together with the same prototype:
+//
+//	api.AddResource(model.User{}, sqlresource.New(db, model.User{}, mapping))
+//
+// FindAll and PaginatedFindAll support `filter[name]=value1,value2` and
+// `sort=-name,id` for the id and all mapped fields with an attribute name, i.e.
+// a jsonapi attr tag or a json tag. Writes and relationship edits run in
+// transactions that also update the join tables.
+type Resource struct {
+	db        *sql.DB
+	prototype resourceutil.Prototype
+	mapping   Mapping
+	// fields and columns contain the mapped struct fields and columns in the same order
+	fields  []string
+	columns []string
+	// attributes maps attribute names to columns
+	attributes map[string]string
+}
+
+// New creates a resource for objects of the type of prototype, which should be
+// an empty struct like `User{}` or a pointer like `&User{}`. It panics if the
+// mapping is invalid.
+func New(db *sql.DB, prototype interface{}, mapping Mapping) *Resource {
+	resourceType := reflect.TypeOf(prototype)
+	if resourceType == nil || (resourceType.Kind() != reflect.Struct &&
+		(resourceType.Kind() != reflect.Ptr || resourceType.Elem().Kind() != reflect.Struct)) {
+		panic("pass an empty resource struct or a struct pointer to sqlresource.New!")
+	}
+
+	if mapping.Table == "" {
+		panic("the mapping needs a table")
+	}
+
+	if mapping.ID == "" {
+		mapping.ID = "id"
+	}
+
+	if mapping.Placeholder == nil {
+		mapping.Placeholder = QuestionMark
+	}
+
+	if mapping.MaxParameters <= 0 {
+		mapping.MaxParameters = defaultMaxParameters
+	}
+
+	structType := resourceType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	r := &Resource{
+		db:         db,
+		prototype:  resourceutil.Prototype{Type: resourceType},
+		mapping:    mapping,
+		attributes: map[string]string{"id": mapping.ID},
+	}
+
+	for field := range mapping.Columns {
+		r.fields = append(r.fields, field)
+	}
+	sort.Strings(r.fields)
+
+	for _, name := range r.fields {
+		field, ok := structType.FieldByName(name)
+		if !ok || field.PkgPath != "" {
+			panic(fmt.Sprintf("%s has no exported field %s", structType, name))
+		}
+
+		column := mapping.Columns[name]
+		r.columns = append(r.columns, column)
+		if attribute := getAttributeName(field); attribute != "" {
+			r.attributes[attribute] = column
+		}
+	}
+
+	return r
+}
+
+// getAttributeName returns the name of the attribute of a struct field or an
+// empty string if the field is no attribute
+func getAttributeName(field reflect.StructField) string {
+	if tag := strings.Split(field.Tag.Get("jsonapi"), ","); tag[0] == "attr" {
+		if len(tag) > 1 && tag[1] != "" {
+			return tag[1]
+		}
+		return field.Name
+	}
+
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+// FindAll returns all rows that match the filter query parameters ordered by the
+// sort query parameter or the id
+func (r *Resource) FindAll(req api2go.Request) (api2go.Responder, error) {
+	where, args, err := r.where(req.QueryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	orderBy, err := r.orderBy(req.QueryParams["sort"])
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := r.query(where+orderBy, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api2go.Response{Res: r.prototype.ToSlice(objects), Code: http.StatusOK}, nil
+}
+
+// PaginatedFindAll returns a page of the rows of FindAll, using either the
+// page[number] and page[size] or the page[offset] and page[limit] parameters.
+// The total count is determined with a separate COUNT query.
+func (r *Resource) PaginatedFindAll(req api2go.Request) (uint, api2go.Responder, error) {
+	where, args, err := r.where(req.QueryParams)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	orderBy, err := r.orderBy(req.QueryParams["sort"])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	offset, limit, err := resourceutil.GetPage(req.Pagination)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var count uint
+	statement := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", r.mapping.Table, where)
+	if err := r.db.QueryRow(statement, args...).Scan(&count); err != nil {
+		return 0, nil, err
+	}
+
+	if limit >= 0 {
+		args = append(args, limit, offset)
+		orderBy += fmt.Sprintf(" LIMIT %s OFFSET %s",
+			r.mapping.Placeholder(len(args)-1), r.mapping.Placeholder(len(args)))
+	} else if offset > 0 {
+		// not all databases support an OFFSET without LIMIT
+		if uint(offset) >= count {
+			return count, &api2go.Response{Res: r.prototype.ToSlice(nil), Code: http.StatusOK}, nil
+		}
+		args = append(args, int(count)-offset, offset)
+		orderBy += fmt.Sprintf(" LIMIT %s OFFSET %s",
+			r.mapping.Placeholder(len(args)-1), r.mapping.Placeholder(len(args)))
+	}
+
+	objects, err := r.query(where+orderBy, args)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return count, &api2go.Response{Res: r.prototype.ToSlice(objects), Code: http.StatusOK}, nil
+}
+
+// FindOne returns the row with the given id or a 404 error
+func (r *Resource) FindOne(ID string, req api2go.Request) (api2go.Responder, error) {
+	object, err := r.findOne(ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api2go.Response{Res: r.prototype.Export(object), Code: http.StatusOK}, nil
+}
+
+// FindMany returns the rows with the given ids in the order of the ids, unknown
+// ids are skipped. The rows are selected with one query per MaxParameters ids.
+func (r *Resource) FindMany(IDs []string, req api2go.Request) (api2go.Responder, error) {
+	found := map[string]reflect.Value{}
+	for _, batch := range r.batches(IDs) {
+		conditions := fmt.Sprintf(" WHERE %s IN (%s)", r.mapping.ID, r.placeholders(1, len(batch)))
+		objects, err := r.query(conditions, toArgs(batch))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range objects {
+			found[resourceutil.GetID(object)] = object
+		}
+	}
+
+	objects := []reflect.Value{}
+	for _, ID := range IDs {
+		if object, ok := found[ID]; ok {
+			objects = append(objects, object)
+			delete(found, ID)
+		}
+	}
+
+	return &api2go.Response{Res: r.prototype.ToSlice(objects), Code: http.StatusOK}, nil
+}
+
+// Create inserts a new row and the rows of its to-many relationships. A 409 error
+// is returned if a row with the id of obj exists already.
+func (r *Resource) Create(obj interface{}, req api2go.Request) (api2go.Responder, error) {
+	object, err := r.prototype.Copy(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.transaction(func(tx *sql.Tx) error {
+		ID := resourceutil.GetID(object)
+		columns := r.columns
+		values := r.values(object)
+		if ID != "" {
+			exists, err := r.exists(tx, ID)
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				message := fmt.Sprintf("object with id %s exists already", ID)
+				return api2go.NewHTTPError(nil, message, http.StatusConflict)
+			}
+
+			columns = append([]string{r.mapping.ID}, columns...)
+			values = append([]interface{}{ID}, values...)
+		}
+
+		statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			r.mapping.Table, strings.Join(columns, ", "), r.placeholders(1, len(values)))
+		result, err := tx.Exec(statement, values...)
+		if err != nil {
+			return err
+		}
+
+		if ID == "" {
+			generated, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+
+			ID = strconv.FormatInt(generated, 10)
+			if err := resourceutil.SetID(object, ID); err != nil {
+				return err
+			}
+		}
+
+		return r.insertRelations(tx, ID, object)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api2go.Response{Res: r.prototype.Export(object), Code: http.StatusCreated}, nil
+}
+
+// Update updates the row with the id of obj and replaces the rows of its to-many
+// relationships, a 404 error is returned if the row does not exist
+func (r *Resource) Update(obj interface{}, req api2go.Request) (api2go.Responder, error) {
+	object, err := r.prototype.Copy(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	ID := resourceutil.GetID(object)
+	err = r.transaction(func(tx *sql.Tx) error {
+		if err := r.checkExistence(tx, ID); err != nil {
+			return err
+		}
+
+		if len(r.columns) > 0 {
+			assignments := make([]string, len(r.columns))
+			for i, column := range r.columns {
+				assignments[i] = column + " = " + r.mapping.Placeholder(i+1)
+			}
+
+			args := append(r.values(object), ID)
+			statement := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s", r.mapping.Table,
+				strings.Join(assignments, ", "), r.mapping.ID, r.mapping.Placeholder(len(args)))
+			if _, err := tx.Exec(statement, args...); err != nil {
+				return err
+			}
+		}
+
+		if err := r.deleteRelations(tx, ID); err != nil {
+			return err
+		}
+
+		return r.insertRelations(tx, ID, object)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api2go.Response{Res: r.prototype.Export(object), Code: http.StatusOK}, nil
+}
+
+// Delete deletes the row with the given id and the rows of its to-many
+// relationships, a 404 error is returned if the row does not exist
+func (r *Resource) Delete(ID string, req api2go.Request) (api2go.Responder, error) {
+	err := r.transaction(func(tx *sql.Tx) error {
+		if err := r.checkExistence(tx, ID); err != nil {
+			return err
+		}
+
+		if err := r.deleteRelations(tx, ID); err != nil {
+			return err
+		}
+
+		statement := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", r.mapping.Table, r.mapping.ID, r.mapping.Placeholder(1))
+		_, err := tx.Exec(statement, ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+// AddToRelationship inserts the ids that are not yet part of a to-many
+// relationship into its join table
+func (r *Resource) AddToRelationship(ID, name string, IDs []jsonapi.ReferenceID, req api2go.Request) (api2go.Responder, error) {
+	return r.editRelation(ID, name, func(tx *sql.Tx, join JoinTable, existing map[string]bool) error {
+		statement := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (%s)",
+			join.Table, join.SourceColumn, join.TargetColumn, r.placeholders(1, 2))
+		for _, target := range IDs {
+			if existing[target.ID] {
+				continue
+			}
+
+			if _, err := tx.Exec(statement, ID, target.ID); err != nil {
+				return err
+			}
+			existing[target.ID] = true
+		}
+
+		return nil
+	})
+}
+
+// RemoveFromRelationship deletes the ids of a to-many relationship from its join
+// table
+func (r *Resource) RemoveFromRelationship(ID, name string, IDs []jsonapi.ReferenceID, req api2go.Request) (api2go.Responder, error) {
+	return r.editRelation(ID, name, func(tx *sql.Tx, join JoinTable, existing map[string]bool) error {
+		statement := fmt.Sprintf("DELETE FROM %s WHERE %s = %s AND %s = %s", join.Table,
+			join.SourceColumn, r.mapping.Placeholder(1), join.TargetColumn, r.mapping.Placeholder(2))
+		for _, target := range IDs {
+			if _, err := tx.Exec(statement, ID, target.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *Resource) editRelation(ID, name string, edit func(*sql.Tx, JoinTable, map[string]bool) error) (api2go.Responder, error) {
+	join, ok := r.mapping.Relations[name]
+	if !ok {
+		message := fmt.Sprintf("There is no to-many relationship with the name %s", name)
+		return nil, api2go.NewHTTPError(nil, message, http.StatusBadRequest)
+	}
+
+	err := r.transaction(func(tx *sql.Tx) error {
+		if err := r.checkExistence(tx, ID); err != nil {
+			return err
+		}
+
+		related, err := r.relatedIDs(tx, join, []string{ID})
+		if err != nil {
+			return err
+		}
+
+		existing := map[string]bool{}
+		for _, target := range related[ID] {
+			existing[target] = true
+		}
+
+		return edit(tx, join, existing)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+// queryer is implemented by sql.DB and sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// query selects the rows of the table with the given conditions and loads their
+// to-many relationships
+func (r *Resource) query(conditions string, args []interface{}) ([]reflect.Value, error) {
+	columns := append([]string{r.mapping.ID}, r.columns...)
+	statement := fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(columns, ", "), r.mapping.Table, conditions)
+
+	rows, err := r.db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := []reflect.Value{}
+	IDs := []string{}
+	for rows.Next() {
+		object := reflect.New(r.prototype.StructType())
+		var ID string
+		targets := []interface{}{&ID}
+		for _, field := range r.fields {
+			targets = append(targets, object.Elem().FieldByName(field).Addr().Interface())
+		}
+
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+
+		if err := resourceutil.SetID(object, ID); err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, object)
+		IDs = append(IDs, ID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadRelations(r.db, objects, IDs); err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+func (r *Resource) findOne(ID string) (reflect.Value, error) {
+	condition := fmt.Sprintf(" WHERE %s = %s", r.mapping.ID, r.mapping.Placeholder(1))
+	objects, err := r.query(condition, []interface{}{ID})
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if len(objects) == 0 {
+		return reflect.Value{}, resourceutil.NotFound(ID)
+	}
+
+	return objects[0], nil
+}
+
+// loadRelations sets the ids of all to-many relationships with one query per
+// relationship and MaxParameters objects
+func (r *Resource) loadRelations(db queryer, objects []reflect.Value, IDs []string) error {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	for name, join := range r.mapping.Relations {
+		related, err := r.relatedIDs(db, join, IDs)
+		if err != nil {
+			return err
+		}
+
+		for i, object := range objects {
+			if err := setToManyIDs(object, name, related[IDs[i]]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// setToManyIDs sets the ids of a to-many relationship with either of the
+// unmarshalling interfaces for to-many relationships
+func setToManyIDs(object reflect.Value, name string, IDs []string) error {
+	if IDs == nil {
+		IDs = []string{}
+	}
+
+	switch target := jsonapi.Adapt(object.Interface()).(type) {
+	case jsonapi.UnmarshalToManyRelations:
+		return target.SetToManyReferenceIDs(name, IDs)
+	case jsonapi.UnmarshalToManyRelationsWithType:
+		references := make([]jsonapi.ReferenceID, len(IDs))
+		for i, ID := range IDs {
+			references[i] = jsonapi.ReferenceID{ID: ID, Name: name, Relationship: jsonapi.ToManyRelationship}
+		}
+		return target.SetToManyReferenceIDsWithType(name, references)
+	default:
+		return fmt.Errorf("%s does not implement jsonapi.UnmarshalToManyRelations", object.Type())
+	}
+}
+
+// relatedIDs returns the ids in the join table by source id
+func (r *Resource) relatedIDs(db queryer, join JoinTable, IDs []string) (map[string][]string, error) {
+	related := map[string][]string{}
+	for _, batch := range r.batches(IDs) {
+		statement := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s) ORDER BY %s", join.SourceColumn,
+			join.TargetColumn, join.Table, join.SourceColumn, r.placeholders(1, len(batch)), join.TargetColumn)
+		if err := r.scanRelatedIDs(db, statement, toArgs(batch), related); err != nil {
+			return nil, err
+		}
+	}
+
+	return related, nil
+}
+
+func (r *Resource) scanRelatedIDs(db queryer, statement string, args []interface{}, related map[string][]string) error {
+	rows, err := db.Query(statement, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var source, target string
+		if err := rows.Scan(&source, &target); err != nil {
+			return err
+		}
+		related[source] = append(related[source], target)
+	}
+
+	return rows.Err()
+}
+
+// batches splits ids into slices of at most MaxParameters ids
+func (r *Resource) batches(IDs []string) [][]string {
+	batches := [][]string{}
+	for len(IDs) > r.mapping.MaxParameters {
+		batches = append(batches, IDs[:r.mapping.MaxParameters])
+		IDs = IDs[r.mapping.MaxParameters:]
+	}
+
+	if len(IDs) > 0 {
+		batches = append(batches, IDs)
+	}
+
+	return batches
+}
+
+func toArgs(IDs []string) []interface{} {
+	args := make([]interface{}, len(IDs))
+	for i, ID := range IDs {
+		args[i] = ID
+	}
+
+	return args
+}
+
+func (r *Resource) insertRelations(tx *sql.Tx, ID string, object reflect.Value) error {
+	if len(r.mapping.Relations) == 0 {
+		return nil
+	}
+
+	source, ok := jsonapi.Adapt(object.Interface()).(jsonapi.MarshalLinkedRelations)
+	if !ok {
+		return fmt.Errorf("%s does not implement jsonapi.MarshalLinkedRelations", object.Type())
+	}
+
+	for _, reference := range source.GetReferencedIDs() {
+		join, ok := r.mapping.Relations[reference.Name]
+		if !ok {
+			continue
+		}
+
+		statement := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (%s)",
+			join.Table, join.SourceColumn, join.TargetColumn, r.placeholders(1, 2))
+		if _, err := tx.Exec(statement, ID, reference.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Resource) deleteRelations(tx *sql.Tx, ID string) error {
+	for _, join := range r.mapping.Relations {
+		statement := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", join.Table, join.SourceColumn, r.mapping.Placeholder(1))
+		if _, err := tx.Exec(statement, ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkExistence returns a 404 error if there is no row with the id
+func (r *Resource) checkExistence(tx *sql.Tx, ID string) error {
+	exists, err := r.exists(tx, ID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return resourceutil.NotFound(ID)
+	}
+
+	return nil
+}
+
+func (r *Resource) exists(tx *sql.Tx, ID string) (bool, error) {
+	var count int
+	statement := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = %s", r.mapping.Table, r.mapping.ID, r.mapping.Placeholder(1))
+	if err := tx.QueryRow(statement, ID).Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// transaction runs fn in a transaction that is rolled back if fn fails
+func (r *Resource) transaction(fn func(*sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// where translates the filter query parameters into a WHERE clause
+func (r *Resource) where(queryParams map[string][]string) (string, []interface{}, error) {
+	names := []string{}
+	for key := range queryParams {
+		if strings.HasPrefix(key, "filter[") && strings.HasSuffix(key, "]") {
+			names = append(names, key[len("filter["):len(key)-1])
+		}
+	}
+
+	if len(names) == 0 {
+		return "", nil, nil
+	}
+	sort.Strings(names)
+
+	conditions := []string{}
+	args := []interface{}{}
+	for _, name := range names {
+		column, ok := r.attributes[name]
+		if !ok {
+			return "", nil, api2go.NewHTTPError(nil, fmt.Sprintf("invalid filter %s", name), http.StatusBadRequest)
+		}
+
+		values := queryParams["filter["+name+"]"]
+		if len(values) == 0 {
+			continue
+		}
+
+		conditions = append(conditions, fmt.Sprintf("%s IN (%s)", column, r.placeholders(len(args)+1, len(values))))
+		for _, value := range values {
+			args = append(args, value)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// orderBy translates the sort query parameter into an ORDER BY clause, the id
+// is always used as last criteria to get a stable order
+func (r *Resource) orderBy(sorting []string) (string, error) {
+	criteria := []string{}
+	for _, field := range sorting {
+		direction := "ASC"
+		if strings.HasPrefix(field, "-") {
+			direction = "DESC"
+			field = field[1:]
+		}
+
+		column, ok := r.attributes[field]
+		if !ok {
+			return "", api2go.NewHTTPError(nil, fmt.Sprintf("invalid sort field %s", field), http.StatusBadRequest)
+		}
+
+		criteria = append(criteria, column+" "+direction)
+	}
+	criteria = append(criteria, r.mapping.ID+" ASC")
+
+	return " ORDER BY " + strings.Join(criteria, ", "), nil
+}
+
+// placeholders returns count comma separated placeholders starting with first
+func (r *Resource) placeholders(first, count int) string {
+	placeholders := make([]string, count)
+	for i := range placeholders {
+		placeholders[i] = r.mapping.Placeholder(first + i)
+	}
+
+	return strings.Join(placeholders, ", ")
+}
+
+// values returns the values of the mapped fields
+func (r *Resource) values(object reflect.Value) []interface{} {
+	values := make([]interface{}, len(r.fields))
+	for i, field := range r.fields {
+		values[i] = object.Elem().FieldByName(field).Interface()
+	}
+
+	return values
+}
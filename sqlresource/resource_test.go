@@ -0,0 +1,311 @@
+//go:build go1.20
+// +build go1.20
+
+package sqlresource
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/manyminds/api2go"
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
+	_ "modernc.org/sqlite"
+)
+
+type Book struct {
+	ID        string   `jsonapi:"primary,books"`
+	Title     string   `jsonapi:"attr,title"`
+	Pages     int      `jsonapi:"attr,pages"`
+	Secret    string   `json:"-"`
+	AuthorIDs []string `jsonapi:"relation,authors,authors"`
+}
+
+const schema = `
+CREATE TABLE books (id INTEGER PRIMARY KEY, title TEXT NOT NULL, pages INTEGER NOT NULL, secret TEXT NOT NULL DEFAULT '');
+CREATE TABLE book_authors (book_id INTEGER NOT NULL, author_id INTEGER NOT NULL, PRIMARY KEY (book_id, author_id));
+INSERT INTO books (id, title, pages) VALUES (1, 'Dirk Gently', 306), (2, 'Mostly Harmless', 240), (3, 'Good Omens', 306);
+INSERT INTO book_authors (book_id, author_id) VALUES (1, 1), (3, 1), (3, 2);
+`
+
+var mapping = Mapping{
+	Table:   "books",
+	Columns: map[string]string{"Title": "title", "Pages": "pages", "Secret": "secret"},
+	Relations: map[string]JoinTable{
+		"authors": {Table: "book_authors", SourceColumn: "book_id", TargetColumn: "author_id"},
+	},
+}
+
+// haveStatus matches api2go.HTTPErrors with the given status and message
+func haveStatus(status int, message string) types.GomegaMatcher {
+	return MatchError(HavePrefix(fmt.Sprintf("http error (%d) %s and", status, message)))
+}
+
+// identifiers returns the identifiers of authors with the given ids
+func identifiers(ids ...string) []jsonapi.ReferenceID {
+	result := []jsonapi.ReferenceID{}
+	for _, id := range ids {
+		result = append(result, jsonapi.ReferenceID{ID: id, Type: "authors", Relationship: jsonapi.ToManyRelationship})
+	}
+
+	return result
+}
+
+var _ = Describe("Resource", func() {
+	var (
+		directory string
+		db        *sql.DB
+		resource  *Resource
+		request   api2go.Request
+	)
+
+	titles := func(response api2go.Responder) []string {
+		result := []string{}
+		for _, book := range response.Result().([]Book) {
+			result = append(result, book.Title)
+		}
+		return result
+	}
+
+	authors := func(ID string) []string {
+		response, err := resource.FindOne(ID, request)
+		Expect(err).ToNot(HaveOccurred())
+		return response.Result().(Book).AuthorIDs
+	}
+
+	BeforeEach(func() {
+		var err error
+		directory, err = ioutil.TempDir("", "sqlresource")
+		Expect(err).ToNot(HaveOccurred())
+		db, err = sql.Open("sqlite", filepath.Join(directory, "books.db"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = db.Exec(schema)
+		Expect(err).ToNot(HaveOccurred())
+
+		resource = New(db, Book{}, mapping)
+		request = api2go.Request{QueryParams: map[string][]string{}, Pagination: map[string]string{}}
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+		Expect(os.RemoveAll(directory)).To(Succeed())
+	})
+
+	It("panics for invalid mappings", func() {
+		Expect(func() { New(db, "book", mapping) }).To(Panic())
+		Expect(func() { New(db, Book{}, Mapping{}) }).To(Panic())
+		Expect(func() { New(db, Book{}, Mapping{Table: "books", Columns: map[string]string{"Author": "author"}}) }).To(Panic())
+	})
+
+	It("uses dollar placeholders", func() {
+		Expect(Dollar(1)).To(Equal("$1"))
+		Expect(QuestionMark(2)).To(Equal("?"))
+	})
+
+	Context("finding", func() {
+		It("finds one row with its relationships", func() {
+			response, err := resource.FindOne("3", request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode()).To(Equal(http.StatusOK))
+			Expect(response.Result()).To(Equal(Book{ID: "3", Title: "Good Omens", Pages: 306, AuthorIDs: []string{"1", "2"}}))
+		})
+
+		It("returns 404 for unknown ids", func() {
+			_, err := resource.FindOne("23", request)
+			Expect(err).To(haveStatus(http.StatusNotFound, "object with id 23 not found"))
+		})
+
+		It("finds all rows ordered by id", func() {
+			response, err := resource.FindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(titles(response)).To(Equal([]string{"Dirk Gently", "Mostly Harmless", "Good Omens"}))
+			Expect(response.Result().([]Book)[1].AuthorIDs).To(Equal([]string{}))
+		})
+
+		It("finds many rows in the order of the ids", func() {
+			response, err := resource.FindMany([]string{"3", "23", "1"}, request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(titles(response)).To(Equal([]string{"Good Omens", "Dirk Gently"}))
+			Expect(response.Result().([]Book)[0].AuthorIDs).To(Equal([]string{"1", "2"}))
+
+			response, err = resource.FindMany([]string{}, request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(titles(response)).To(BeEmpty())
+		})
+
+		It("splits long id lists into several queries", func() {
+			limited := mapping
+			limited.MaxParameters = 1
+			resource = New(db, Book{}, limited)
+
+			response, err := resource.FindMany([]string{"3", "1", "2"}, request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(titles(response)).To(Equal([]string{"Good Omens", "Dirk Gently", "Mostly Harmless"}))
+			Expect(response.Result().([]Book)[0].AuthorIDs).To(Equal([]string{"1", "2"}))
+
+			response, err = resource.FindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Result().([]Book)[2].AuthorIDs).To(Equal([]string{"1", "2"}))
+		})
+
+		It("sorts by multiple fields", func() {
+			request.QueryParams["sort"] = []string{"-pages", "title"}
+			response, err := resource.FindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(titles(response)).To(Equal([]string{"Dirk Gently", "Good Omens", "Mostly Harmless"}))
+		})
+
+		It("filters with parameters", func() {
+			request.QueryParams["filter[pages]"] = []string{"306"}
+			request.QueryParams["filter[title]"] = []string{"Good Omens", "'; DROP TABLE books; --"}
+			response, err := resource.FindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(titles(response)).To(Equal([]string{"Good Omens"}))
+
+			request.QueryParams = map[string][]string{"filter[id]": {"1", "2"}}
+			response, err = resource.FindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(titles(response)).To(Equal([]string{"Dirk Gently", "Mostly Harmless"}))
+		})
+
+		It("rejects unknown and unmapped fields", func() {
+			request.QueryParams["sort"] = []string{"Secret"}
+			_, err := resource.FindAll(request)
+			Expect(err).To(haveStatus(http.StatusBadRequest, "invalid sort field Secret"))
+
+			request.QueryParams = map[string][]string{"filter[secret]": {"x"}}
+			_, err = resource.FindAll(request)
+			Expect(err).To(haveStatus(http.StatusBadRequest, "invalid filter secret"))
+		})
+
+		It("paginates with number and size", func() {
+			request.QueryParams["sort"] = []string{"title"}
+			request.Pagination = map[string]string{"number": "2", "size": "2"}
+			count, response, err := resource.PaginatedFindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(uint(3)))
+			Expect(titles(response)).To(Equal([]string{"Mostly Harmless"}))
+		})
+
+		It("paginates with offset and limit after filtering", func() {
+			request.QueryParams["filter[pages]"] = []string{"306"}
+			request.Pagination = map[string]string{"offset": "1", "limit": "5"}
+			count, response, err := resource.PaginatedFindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(uint(2)))
+			Expect(titles(response)).To(Equal([]string{"Good Omens"}))
+
+			request.Pagination = map[string]string{"offset": "1"}
+			_, response, err = resource.PaginatedFindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(titles(response)).To(Equal([]string{"Good Omens"}))
+
+			request.Pagination = map[string]string{"offset": "5"}
+			_, response, err = resource.PaginatedFindAll(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(titles(response)).To(BeEmpty())
+		})
+
+		It("rejects invalid pagination", func() {
+			request.Pagination = map[string]string{"offset": "x"}
+			_, _, err := resource.PaginatedFindAll(request)
+			Expect(err).To(haveStatus(http.StatusBadRequest, `invalid value "x" for page[offset]`))
+		})
+	})
+
+	Context("writing", func() {
+		It("creates rows with generated ids", func() {
+			response, err := resource.Create(Book{Title: "Neverwhere", Pages: 370, AuthorIDs: []string{"2"}}, request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode()).To(Equal(http.StatusCreated))
+			Expect(response.Result().(Book).ID).To(Equal("4"))
+			Expect(authors("4")).To(Equal([]string{"2"}))
+		})
+
+		It("creates rows with given ids", func() {
+			_, err := resource.Create(Book{ID: "10", Title: "Neverwhere"}, request)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = resource.FindOne("10", request)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = resource.Create(Book{ID: "10", Title: "Stardust"}, request)
+			Expect(err).To(haveStatus(http.StatusConflict, "object with id 10 exists already"))
+		})
+
+		It("rolls back failed creates", func() {
+			_, err := resource.Create(Book{ID: "10", Title: "Neverwhere", AuthorIDs: []string{"1", "1"}}, request)
+			Expect(err).To(HaveOccurred())
+			_, err = resource.FindOne("10", request)
+			Expect(err).To(haveStatus(http.StatusNotFound, "object with id 10 not found"))
+		})
+
+		It("updates rows and relationships", func() {
+			response, err := resource.Update(Book{ID: "3", Title: "Good Omens", Pages: 400, AuthorIDs: []string{"2"}}, request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode()).To(Equal(http.StatusOK))
+
+			response, err = resource.FindOne("3", request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Result()).To(Equal(Book{ID: "3", Title: "Good Omens", Pages: 400, AuthorIDs: []string{"2"}}))
+
+			_, err = resource.Update(Book{ID: "23"}, request)
+			Expect(err).To(haveStatus(http.StatusNotFound, "object with id 23 not found"))
+		})
+
+		It("deletes rows and relationships", func() {
+			response, err := resource.Delete("3", request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode()).To(Equal(http.StatusNoContent))
+
+			var count int
+			Expect(db.QueryRow("SELECT COUNT(*) FROM book_authors WHERE book_id = 3").Scan(&count)).To(Succeed())
+			Expect(count).To(Equal(0))
+
+			_, err = resource.Delete("3", request)
+			Expect(err).To(haveStatus(http.StatusNotFound, "object with id 3 not found"))
+		})
+
+		It("edits to-many relationships", func() {
+			_, err := resource.AddToRelationship("1", "authors", identifiers("1", "2", "3"), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(authors("1")).To(Equal([]string{"1", "2", "3"}))
+
+			_, err = resource.RemoveFromRelationship("1", "authors", identifiers("1", "3"), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(authors("1")).To(Equal([]string{"2"}))
+
+			_, err = resource.AddToRelationship("1", "editors", identifiers("1"), request)
+			Expect(err).To(haveStatus(http.StatusBadRequest, "There is no to-many relationship with the name editors"))
+			_, err = resource.AddToRelationship("23", "authors", identifiers("1"), request)
+			Expect(err).To(haveStatus(http.StatusNotFound, "object with id 23 not found"))
+		})
+	})
+
+	It("works as api2go resource", func() {
+		api := api2go.NewAPI("v1")
+		api.AddResource(Book{}, resource)
+
+		rec := httptest.NewRecorder()
+		req, err := http.NewRequest("PATCH", "/v1/books/2", strings.NewReader(`{"data": {"type": "books", "id": "2", "attributes": {"pages": 250}, "relationships": {"authors": {"data": [{"type": "authors", "id": "1"}]}}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(authors("2")).To(Equal([]string{"1"}))
+
+		rec = httptest.NewRecorder()
+		req, err = http.NewRequest("GET", "/v1/books?sort=-pages,title&page[number]=1&page[size]=1&filter[pages]=250", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"title":"Mostly Harmless"`))
+		Expect(rec.Body.String()).To(ContainSubstring(`"data":[{"type":"authors","id":"1"}]`))
+	})
+})
@@ -0,0 +1,16 @@
+//go:build go1.20
+// +build go1.20
+
+package sqlresource
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSqlresource(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sqlresource Suite")
+}
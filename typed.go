@@ -0,0 +1,193 @@
+//go:build go1.18
+// +build go1.18
+
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// The TypedResourceGetter interface is the typed counterpart of ResourceGetter.
+type TypedResourceGetter[T any] interface {
+	// FindOne returns an object by its ID
+	FindOne(ID string, req Request) (T, error)
+}
+
+// The TypedCRUD interface is the typed counterpart of CRUD, resources implementing it can be
+// registered with AddTypedResource. The returned objects are sent with the status codes 201 for
+// Create and 200 for FindOne and Update, Delete responds with 204 No Content.
+type TypedCRUD[T any] interface {
+	TypedResourceGetter[T]
+	// Create a new object and return it with its new ID
+	Create(obj T, req Request) (T, error)
+	// Delete an object
+	Delete(ID string, req Request) error
+	// Update an object and return it
+	Update(obj T, req Request) (T, error)
+}
+
+// The TypedFindAll interface can be optionally implemented to fetch all records at once.
+type TypedFindAll[T any] interface {
+	// FindAll returns all objects
+	FindAll(req Request) ([]T, error)
+}
+
+// The TypedPaginatedFindAll interface can be optionally implemented to fetch a subset of all
+// records. Pagination query parameters must be used to limit the result. Pagination URLs will
+// automatically be generated by the api. You can use a combination of the following 2 query
+// parameters: page[number] AND page[size] OR page[offset] AND page[limit]
+type TypedPaginatedFindAll[T any] interface {
+	PaginatedFindAll(req Request) (totalCount uint, objects []T, err error)
+}
+
+// AddTypedResource registers a typed data source for the struct type T, which must implement
+// jsonapi.MarshalIdentifier or use `jsonapi` struct tags like for AddResource. The source can
+// additionally implement TypedFindAll and TypedPaginatedFindAll, all other optional interfaces
+// of untyped resources are not supported.
+func AddTypedResource[T any](api *API, source TypedCRUD[T]) {
+	var prototype T
+	if reflect.TypeOf(prototype) == nil || reflect.TypeOf(prototype).Kind() != reflect.Struct {
+		panic("pass a struct type to AddTypedResource!")
+	}
+
+	api.addResource(prototype, newTypedSource[T](source), typedAllocator[T]{})
+}
+
+// newTypedSource returns an untyped resource that only implements the interfaces of the typed one
+func newTypedSource[T any](source TypedCRUD[T]) interface{} {
+	crud := typedCRUD[T]{source: source}
+	findAll, hasFindAll := source.(TypedFindAll[T])
+	paginated, hasPagination := source.(TypedPaginatedFindAll[T])
+
+	switch {
+	case hasFindAll && hasPagination:
+		return typedPaginatedFindAll[T]{typedFindAll: typedFindAll[T]{typedCRUD: crud, findAll: findAll}, paginated: paginated}
+	case hasFindAll:
+		return typedFindAll[T]{typedCRUD: crud, findAll: findAll}
+	case hasPagination:
+		return typedPaginatedOnly[T]{typedCRUD: crud, paginated: paginated}
+	default:
+		return crud
+	}
+}
+
+// typedCRUD implements CRUD for a TypedCRUD
+type typedCRUD[T any] struct {
+	source TypedCRUD[T]
+}
+
+func (s typedCRUD[T]) FindOne(ID string, req Request) (Responder, error) {
+	obj, err := s.source.FindOne(ID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{Res: obj, Code: http.StatusOK}, nil
+}
+
+func (s typedCRUD[T]) Create(obj interface{}, req Request) (Responder, error) {
+	typed, ok := obj.(T)
+	if !ok {
+		return nil, fmt.Errorf("expected an object of type %T but got %T", typed, obj)
+	}
+
+	created, err := s.source.Create(typed, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{Res: created, Code: http.StatusCreated}, nil
+}
+
+func (s typedCRUD[T]) Delete(ID string, req Request) (Responder, error) {
+	if err := s.source.Delete(ID, req); err != nil {
+		return nil, err
+	}
+
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s typedCRUD[T]) Update(obj interface{}, req Request) (Responder, error) {
+	typed, ok := obj.(T)
+	if !ok {
+		return nil, fmt.Errorf("expected an object of type %T but got %T", typed, obj)
+	}
+
+	updated, err := s.source.Update(typed, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{Res: updated, Code: http.StatusOK}, nil
+}
+
+// typedFindAll implements FindAll for a TypedFindAll
+type typedFindAll[T any] struct {
+	typedCRUD[T]
+	findAll TypedFindAll[T]
+}
+
+func (s typedFindAll[T]) FindAll(req Request) (Responder, error) {
+	objects, err := s.findAll.FindAll(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{Res: objects, Code: http.StatusOK}, nil
+}
+
+// typedPaginatedFindAll implements FindAll and PaginatedFindAll for typed resources with both
+type typedPaginatedFindAll[T any] struct {
+	typedFindAll[T]
+	paginated TypedPaginatedFindAll[T]
+}
+
+func (s typedPaginatedFindAll[T]) PaginatedFindAll(req Request) (uint, Responder, error) {
+	return paginatedFindAll(s.paginated, req)
+}
+
+// typedPaginatedOnly implements PaginatedFindAll for typed resources without FindAll
+type typedPaginatedOnly[T any] struct {
+	typedCRUD[T]
+	paginated TypedPaginatedFindAll[T]
+}
+
+func (s typedPaginatedOnly[T]) PaginatedFindAll(req Request) (uint, Responder, error) {
+	return paginatedFindAll(s.paginated, req)
+}
+
+func paginatedFindAll[T any](source TypedPaginatedFindAll[T], req Request) (uint, Responder, error) {
+	count, objects, err := source.PaginatedFindAll(req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return count, &Response{Res: objects, Code: http.StatusOK}, nil
+}
+
+// typedAllocator allocates the objects of typed resources without reflection
+type typedAllocator[T any] struct{}
+
+func (typedAllocator[T]) create(init func(ptr interface{}) error) (interface{}, error) {
+	var obj T
+	if err := init(&obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (typedAllocator[T]) edit(obj interface{}, change func(ptr interface{}) error) (interface{}, error) {
+	typed, ok := obj.(T)
+	if !ok {
+		return nil, fmt.Errorf("expected an object of type %T but got %T", typed, obj)
+	}
+
+	if err := change(&typed); err != nil {
+		return nil, err
+	}
+
+	return typed, nil
+}